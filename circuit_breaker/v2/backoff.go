@@ -0,0 +1,98 @@
+package circuit_breaker
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// BackoffPolicy lengthens the open-state timeout of a CircuitBreaker whose probes keep failing:
+//
+// Multiplier is the factor applied to the timeout each time the CircuitBreaker trips
+// from half-open back to open, so the open period at backoff level n is Timeout * Multiplier^n.
+// If Multiplier is less than or equal to 1, the CircuitBreaker uses 2.
+//
+// MaxTimeout caps the open period before jitter.
+// If MaxTimeout is less than or equal to 0, the open period is not capped.
+//
+// Jitter spreads each open period uniformly over ±Jitter of its value, so that breakers
+// tripped together do not probe together. At the cap it spreads it over
+// [MaxTimeout * (1 - Jitter), MaxTimeout] instead, so the cap still holds.
+// It is clamped to [0, 1].
+//
+// ResetAfter is how long the CircuitBreaker must stay closed for the level to drop back to 0.
+// If ResetAfter is less than or equal to 0, the level drops as soon as the CircuitBreaker closes.
+type BackoffPolicy struct {
+	Multiplier float64
+	MaxTimeout time.Duration
+	Jitter     float64
+	ResetAfter time.Duration
+}
+
+// BackoffLevel returns the number of consecutive trips from half-open back to open
+// that lengthen the current open timeout. It is always 0 without Settings.OpenBackoff.
+func (cb *CircuitBreaker[T]) BackoffLevel() uint32 {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.currentState(time.Now())
+	return cb.backoffLevel
+}
+
+// updateBackoff moves the backoff level on a transition from prev to state.
+func (cb *CircuitBreaker[T]) updateBackoff(prev State, state State, now time.Time) {
+	if cb.backoff == nil {
+		return
+	}
+
+	switch {
+	case state == StateOpen && prev == StateHalfOpen:
+		cb.backoffLevel++
+	case state == StateOpen && prev == StateClosed:
+		cb.resetBackoff(now)
+	case state == StateClosed:
+		cb.closedSince = now
+	}
+}
+
+// resetBackoff drops the backoff level once the CircuitBreaker has been closed for ResetAfter.
+func (cb *CircuitBreaker[T]) resetBackoff(now time.Time) {
+	if cb.backoff != nil && cb.backoffLevel > 0 && now.Sub(cb.closedSince) >= cb.backoff.ResetAfter {
+		cb.backoffLevel = 0
+	}
+}
+
+// openTimeout returns the period of the next open state.
+func (cb *CircuitBreaker[T]) openTimeout() time.Duration {
+	if cb.backoff == nil {
+		return cb.timeout
+	}
+
+	multiplier := cb.backoff.Multiplier
+	if multiplier <= 1 {
+		multiplier = 2
+	}
+	timeout := float64(cb.timeout) * math.Pow(multiplier, float64(cb.backoffLevel))
+	capped := cb.backoff.MaxTimeout > 0 && timeout >= float64(cb.backoff.MaxTimeout)
+	if capped {
+		timeout = float64(cb.backoff.MaxTimeout)
+	}
+
+	jitter := min(max(cb.backoff.Jitter, 0), 1)
+	if jitter > 0 {
+		if capped {
+			// Only shorten, so that breakers held at the cap still spread out.
+			timeout *= 1 - jitter*rand.Float64()
+		} else {
+			timeout *= 1 + jitter*(2*rand.Float64()-1)
+		}
+	}
+
+	if cb.backoff.MaxTimeout > 0 && timeout > float64(cb.backoff.MaxTimeout) {
+		return cb.backoff.MaxTimeout
+	}
+	if timeout >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(timeout)
+}
@@ -0,0 +1,114 @@
+package circuit_breaker
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestCircuitBreaker_OpenTimeout tests the open period at each backoff level
+func TestCircuitBreaker_OpenTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff *BackoffPolicy
+		level   uint32
+		want    time.Duration
+	}{
+		{name: "no backoff", level: 3, want: time.Second},
+		{name: "level 0", backoff: &BackoffPolicy{Multiplier: 3}, want: time.Second},
+		{name: "level 2", backoff: &BackoffPolicy{Multiplier: 3}, level: 2, want: 9 * time.Second},
+		{name: "default multiplier", backoff: &BackoffPolicy{}, level: 2, want: 4 * time.Second},
+		{name: "capped", backoff: &BackoffPolicy{MaxTimeout: 5 * time.Second}, level: 3, want: 5 * time.Second},
+		{name: "uncapped overflow", backoff: &BackoffPolicy{}, level: 200, want: math.MaxInt64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb := NewCircuitBreaker[int](Settings{Timeout: time.Second, OpenBackoff: tt.backoff})
+			cb.backoffLevel = tt.level
+			if got := cb.openTimeout(); got != tt.want {
+				t.Errorf("openTimeout() = %v; expected %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCircuitBreaker_OpenTimeoutJitter tests that jitter stays within its bounds and the cap
+func TestCircuitBreaker_OpenTimeoutJitter(t *testing.T) {
+	cb := NewCircuitBreaker[int](Settings{
+		Timeout:     time.Second,
+		OpenBackoff: &BackoffPolicy{Jitter: 0.5, MaxTimeout: 1200 * time.Millisecond},
+	})
+	spread := false
+	for range 200 {
+		got := cb.openTimeout()
+		if got < 500*time.Millisecond || got > 1200*time.Millisecond {
+			t.Fatalf("openTimeout() = %v; expected within [500ms, 1.2s]", got)
+		}
+		spread = spread || got != time.Second
+	}
+	if !spread {
+		t.Error("openTimeout() never moved away from Timeout")
+	}
+}
+
+// TestCircuitBreaker_OpenTimeoutJitterAtCap tests that breakers held at the cap
+// still get spread-out open periods below it
+func TestCircuitBreaker_OpenTimeoutJitterAtCap(t *testing.T) {
+	cb := NewCircuitBreaker[int](Settings{
+		Timeout:     time.Second,
+		OpenBackoff: &BackoffPolicy{Jitter: 0.2, MaxTimeout: 5 * time.Second},
+	})
+	cb.backoffLevel = 10
+	seen := map[time.Duration]bool{}
+	for range 200 {
+		got := cb.openTimeout()
+		if got < 4*time.Second || got > 5*time.Second {
+			t.Fatalf("openTimeout() = %v; expected within [4s, 5s]", got)
+		}
+		seen[got] = true
+	}
+	if len(seen) < 2 {
+		t.Error("openTimeout() at the cap never varied")
+	}
+}
+
+// TestCircuitBreaker_BackoffLevel tests that failed probes raise the level and
+// a sustained closed period resets it
+func TestCircuitBreaker_BackoffLevel(t *testing.T) {
+	cb := NewCircuitBreaker[int](Settings{
+		Timeout:     10 * time.Millisecond,
+		OpenBackoff: &BackoffPolicy{Multiplier: 2, ResetAfter: 50 * time.Millisecond},
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	})
+	fail := func() (int, error) { return 0, errTest }
+	succeed := func() (int, error) { return 1, nil }
+
+	_, _ = cb.Execute(fail)
+	if level := cb.BackoffLevel(); level != 0 {
+		t.Fatalf("BackoffLevel() after the first trip = %d; expected 0", level)
+	}
+	time.Sleep(20 * time.Millisecond)
+	_, _ = cb.Execute(fail)
+	if level := cb.BackoffLevel(); level != 1 {
+		t.Fatalf("BackoffLevel() after a failed probe = %d; expected 1", level)
+	}
+	cb.mutex.Lock()
+	period := cb.expiry.Sub(cb.start)
+	cb.mutex.Unlock()
+	if period != 20*time.Millisecond {
+		t.Errorf("open period at level 1 = %v; expected 20ms", period)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, err := cb.Execute(succeed); err != nil {
+		t.Fatalf("Execute() probe error = %v", err)
+	}
+	if state, level := cb.State(), cb.BackoffLevel(); state != StateClosed || level != 1 {
+		t.Fatalf("after a good probe State() = %v, BackoffLevel() = %d; expected closed at level 1", state, level)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if level := cb.BackoffLevel(); level != 0 {
+		t.Errorf("BackoffLevel() after ResetAfter = %d; expected 0", level)
+	}
+}
@@ -1,6 +1,7 @@
 package circuit_breaker
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
@@ -11,12 +12,24 @@ import (
 type State int
 
 // These constants are states of CircuitBreaker.
+// StateClosed, StateHalfOpen and StateOpen are driven by traffic; the manual states
+// StateForcedOpen, StateForcedClosed and StateDisabled are set by an operator and kept
+// until Release.
 const (
 	StateClosed State = iota
 	StateHalfOpen
 	StateOpen
+	// StateForcedOpen rejects every request with ErrOpenState.
+	StateForcedOpen
+	// StateForcedClosed lets every request through and counts it, but never trips.
+	StateForcedClosed
+	// StateDisabled lets every request through without counting it.
+	StateDisabled
 )
 
+// states lists every State in order.
+var states = []State{StateClosed, StateHalfOpen, StateOpen, StateForcedOpen, StateForcedClosed, StateDisabled}
+
 var (
 	// ErrTooManyRequests is returned when the CB state is half open and the requests count is over the cb maxRequests
 	ErrTooManyRequests = errors.New("too many requests")
@@ -33,11 +46,35 @@ func (s State) String() string {
 		return "half-open"
 	case StateOpen:
 		return "open"
+	case StateForcedOpen:
+		return "forced-open"
+	case StateForcedClosed:
+		return "forced-closed"
+	case StateDisabled:
+		return "disabled"
 	default:
 		return fmt.Sprintf("unknown state: %d", s)
 	}
 }
 
+// IsManual reports whether s is set by an operator rather than driven by traffic.
+func (s State) IsManual() bool {
+	return s == StateForcedOpen || s == StateForcedClosed || s == StateDisabled
+}
+
+// CancelPolicy decides how a cancelled request is counted.
+type CancelPolicy int
+
+// These constants are the CancelPolicy values.
+const (
+	// CancelIgnore counts the request as neither a success nor a failure.
+	CancelIgnore CancelPolicy = iota
+	// CancelAsFailure counts the request as a failure.
+	CancelAsFailure
+	// CancelAsSuccess counts the request as a success.
+	CancelAsSuccess
+)
+
 // Settings configures CircuitBreaker:
 //
 // Name is the name of the CircuitBreaker.
@@ -59,26 +96,46 @@ func (s State) String() string {
 // after which the state of the CircuitBreaker becomes half-open.
 // If Timeout is less than or equal to 0, the timeout value of the CircuitBreaker is set to 60 seconds.
 //
-// ReadyToTrip is called with a copy of Counts whenever a request fails in the closed state.
+// OpenBackoff, if not nil, lengthens Timeout each time a half-open probe fails; see BackoffPolicy.
+//
+// ReadyToTrip is called with a copy of Counts whenever a request finishes in the closed state,
+// successes included, so that a rate crosses its threshold on the request that brings the window
+// to its minimum size.
 // If ReadyToTrip returns true, the CircuitBreaker will be placed into the open state.
 // If ReadyToTrip is nil, default ReadyToTrip is used.
 // Default ReadyToTrip returns true when the number of consecutive failures is more than 5.
+// FailureRateTrip, SlowCallRateTrip and AnyTrip build rate-based ReadyToTrip functions.
+//
+// SlowCallDuration is the duration from which a finished request counts in Counts.SlowCalls.
+// If SlowCallDuration is less than or equal to 0, no request is slow.
 //
 // OnStateChange is called whenever the state of the CircuitBreaker changes.
 //
+// Listeners are told about every success, failure, rejection, ignored request and state change,
+// with the duration of the request; see Event.
+//
 // IsSuccessful is called with the error returned from a request.
 // If IsSuccessful returns true, the error is counted as a success.
 // Otherwise the error is counted as a failure.
 // If IsSuccessful is nil, default IsSuccessful is used, which returns false for all non-nil errors.
+//
+// CancelPolicy decides how ExecuteContext and AllowContext count a failed request whose error
+// wraps context.Canceled or whose context was cancelled, which usually means the caller gave up
+// rather than the dependency failed. IsSuccessful is not consulted for such errors. The zero value is CancelIgnore.
+// context.DeadlineExceeded is not affected and goes through IsSuccessful like any other error.
 type Settings struct {
-	Name          string
-	MaxRequests   uint32
-	Interval      time.Duration
-	BucketPeriod  time.Duration
-	Timeout       time.Duration
-	ReadyToTrip   func(counts Counts) bool
-	OnStateChange func(name string, from State, to State)
-	IsSuccessful  func(err error) bool
+	Name             string
+	MaxRequests      uint32
+	Interval         time.Duration
+	BucketPeriod     time.Duration
+	Timeout          time.Duration
+	OpenBackoff      *BackoffPolicy
+	ReadyToTrip      func(counts Counts) bool
+	SlowCallDuration time.Duration
+	OnStateChange    func(name string, from State, to State)
+	Listeners        []Listener
+	IsSuccessful     func(err error) bool
+	CancelPolicy     CancelPolicy
 }
 
 // CircuitBreaker is a state machine to prevent sending requests that are likely to fail.
@@ -88,16 +145,22 @@ type CircuitBreaker[T any] struct {
 	interval      time.Duration
 	bucketPeriod  time.Duration
 	timeout       time.Duration
+	backoff       *BackoffPolicy
 	readyToTrip   func(counts Counts) bool
+	slowCall      time.Duration
 	isSuccessful  func(err error) bool
 	onStateChange func(name string, from State, to State)
-
-	mutex      sync.Mutex
-	state      State
-	generation uint64
-	counts     *rollingCounts
-	start      time.Time
-	expiry     time.Time
+	cancelPolicy  CancelPolicy
+	listeners     []Listener
+
+	mutex        sync.Mutex
+	state        State
+	generation   uint64
+	counts       *rollingCounts
+	start        time.Time
+	expiry       time.Time
+	backoffLevel uint32
+	closedSince  time.Time
 }
 
 // NewCircuitBreaker returns a new CircuitBreaker configured with the given Settings.
@@ -106,6 +169,8 @@ func NewCircuitBreaker[T any](st Settings) *CircuitBreaker[T] {
 
 	cb.name = st.Name
 	cb.onStateChange = st.OnStateChange
+	cb.cancelPolicy = st.CancelPolicy
+	cb.listeners = append([]Listener(nil), st.Listeners...)
 
 	if st.MaxRequests == 0 {
 		cb.maxRequests = 1
@@ -136,19 +201,28 @@ func NewCircuitBreaker[T any](st Settings) *CircuitBreaker[T] {
 		cb.timeout = st.Timeout
 	}
 
+	if st.OpenBackoff != nil {
+		backoff := *st.OpenBackoff
+		cb.backoff = &backoff
+	}
+
 	if st.ReadyToTrip == nil {
 		cb.readyToTrip = defaultReadyToTrip
 	} else {
 		cb.readyToTrip = st.ReadyToTrip
 	}
 
+	cb.slowCall = st.SlowCallDuration
+
 	if st.IsSuccessful == nil {
 		cb.isSuccessful = defaultIsSuccessful
 	} else {
 		cb.isSuccessful = st.IsSuccessful
 	}
 
-	cb.toNewGeneration(time.Now())
+	now := time.Now()
+	cb.closedSince = now
+	cb.toNewGeneration(now)
 
 	return cb
 }
@@ -164,6 +238,35 @@ func defaultIsSuccessful(err error) bool {
 	return err == nil
 }
 
+// FailureRateTrip returns a ReadyToTrip that trips when at least minRequests requests
+// finished in the current window and more than percent of them failed.
+func FailureRateTrip(percent float64, minRequests uint32) func(counts Counts) bool {
+	return func(counts Counts) bool {
+		return counts.Finished() >= minRequests && counts.FailureRate() > percent
+	}
+}
+
+// SlowCallRateTrip returns a ReadyToTrip that trips when at least minRequests requests
+// finished in the current window and more than percent of them took at least
+// Settings.SlowCallDuration.
+func SlowCallRateTrip(percent float64, minRequests uint32) func(counts Counts) bool {
+	return func(counts Counts) bool {
+		return counts.Finished() >= minRequests && counts.SlowCallRate() > percent
+	}
+}
+
+// AnyTrip returns a ReadyToTrip that trips when any of the given functions does.
+func AnyTrip(readyToTrip ...func(counts Counts) bool) func(counts Counts) bool {
+	return func(counts Counts) bool {
+		for _, trip := range readyToTrip {
+			if trip(counts) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
 // Name returns the name of the CircuitBreaker.
 func (cb *CircuitBreaker[T]) Name() string {
 	return cb.name
@@ -199,19 +302,87 @@ func (cb *CircuitBreaker[T]) Execute(req func() (T, error)) (T, error) {
 		return defaultValue, err
 	}
 
+	start := time.Now()
 	defer func() {
 		e := recover()
 		if e != nil {
-			cb.afterRequest(generation, age, false)
+			cb.afterRequest(generation, age, false, time.Since(start), nil)
 			panic(e)
 		}
 	}()
 
 	result, err := req()
-	cb.afterRequest(generation, age, cb.isSuccessful(err))
+	cb.afterRequest(generation, age, cb.isSuccessful(err), time.Since(start), err)
 	return result, err
 }
 
+// ExecuteContext is like Execute but passes ctx to the request.
+// It returns ctx.Err() without running the request if ctx is already done.
+// The request runs in its own goroutine: if ctx is done before it returns, ExecuteContext
+// returns ctx.Err() immediately, counts the request with that error and drops its late result.
+// A cancelled request is counted according to Settings.CancelPolicy.
+// A panic in the request is raised again in the caller, or in the request's goroutine
+// if ExecuteContext has already returned.
+func (cb *CircuitBreaker[T]) ExecuteContext(ctx context.Context, req func(ctx context.Context) (T, error)) (T, error) {
+	var defaultValue T
+	if err := ctx.Err(); err != nil {
+		return defaultValue, err
+	}
+
+	generation, age, err := cb.beforeRequest()
+	if err != nil {
+		return defaultValue, err
+	}
+
+	type outcome struct {
+		result T
+		err    error
+		panic  any
+	}
+	start := time.Now()
+	// done is unbuffered, so a send succeeds only while ExecuteContext still waits for it;
+	// once it stopped waiting, abandoned is closed.
+	done := make(chan outcome)
+	abandoned := make(chan struct{})
+	go func() {
+		var out outcome
+		defer func() {
+			if e := recover(); e != nil {
+				select {
+				case <-abandoned:
+					panic(e)
+				default:
+				}
+				select {
+				case done <- outcome{panic: e}:
+				case <-abandoned:
+					panic(e)
+				}
+			}
+		}()
+		out.result, out.err = req(ctx)
+		select {
+		case done <- out:
+		case <-abandoned:
+		}
+	}()
+
+	select {
+	case out := <-done:
+		if out.panic != nil {
+			cb.afterRequest(generation, age, false, time.Since(start), nil)
+			panic(out.panic)
+		}
+		cb.afterResult(ctx, generation, age, out.err, time.Since(start))
+		return out.result, out.err
+	case <-ctx.Done():
+		close(abandoned)
+		err := ctx.Err()
+		cb.afterResult(ctx, generation, age, err, time.Since(start))
+		return defaultValue, err
+	}
+}
+
 func (cb *CircuitBreaker[T]) beforeRequest() (uint64, uint64, error) {
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
@@ -219,52 +390,103 @@ func (cb *CircuitBreaker[T]) beforeRequest() (uint64, uint64, error) {
 	now := time.Now()
 	state, generation, age := cb.currentState(now)
 
-	if state == StateOpen {
+	if state == StateOpen || state == StateForcedOpen {
+		cb.emit(Event{Kind: EventRejected, State: state, Err: ErrOpenState})
 		return generation, age, ErrOpenState
 	} else if state == StateHalfOpen && cb.counts.Requests >= cb.maxRequests {
+		cb.emit(Event{Kind: EventRejected, State: state, Err: ErrTooManyRequests})
 		return generation, age, ErrTooManyRequests
 	}
 
-	cb.counts.onRequest()
+	if state != StateDisabled {
+		cb.counts.onRequest()
+	}
 	return generation, age, nil
 }
 
-func (cb *CircuitBreaker[T]) afterRequest(previous uint64, age uint64, success bool) {
+func (cb *CircuitBreaker[T]) afterRequest(previous uint64, age uint64, success bool, duration time.Duration, err error) {
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
 
 	now := time.Now()
 	state, generation, _ := cb.currentState(now)
-	if generation != previous {
+	if success {
+		cb.emit(Event{Kind: EventSuccess, State: state, Err: err, Duration: duration})
+	} else {
+		cb.emit(Event{Kind: EventFailure, State: state, Err: err, Duration: duration})
+	}
+	if generation != previous || state == StateDisabled {
 		return
 	}
 
+	slow := cb.slowCall > 0 && duration >= cb.slowCall
 	if success {
-		cb.onSuccess(state, age, now)
+		cb.onSuccess(state, age, duration, slow, now)
 	} else {
-		cb.onFailure(state, age, now)
+		cb.onFailure(state, age, duration, slow, now)
 	}
 }
 
-func (cb *CircuitBreaker[T]) onSuccess(state State, age uint64, now time.Time) {
+// afterResult counts a request by its error, applying the cancel policy to a failed request
+// whose error wraps context.Canceled or whose ctx was cancelled, since clients often return
+// their own error when the caller cancels.
+func (cb *CircuitBreaker[T]) afterResult(ctx context.Context, previous uint64, age uint64, err error, duration time.Duration) {
+	if err != nil && (errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled)) {
+		switch cb.cancelPolicy {
+		case CancelAsFailure:
+			cb.afterRequest(previous, age, false, duration, err)
+		case CancelAsSuccess:
+			cb.afterRequest(previous, age, true, duration, err)
+		default:
+			cb.afterIgnored(previous, age, duration, err)
+		}
+		return
+	}
+	cb.afterRequest(previous, age, cb.isSuccessful(err), duration, err)
+}
+
+// afterIgnored takes back the request counted by beforeRequest,
+// so that a half-open breaker lets another request through in its place.
+func (cb *CircuitBreaker[T]) afterIgnored(previous uint64, age uint64, duration time.Duration, err error) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	now := time.Now()
+	state, generation, _ := cb.currentState(now)
+	cb.emit(Event{Kind: EventIgnored, State: state, Err: err, Duration: duration})
+	if generation != previous || state == StateDisabled {
+		return
+	}
+
+	cb.counts.onIgnore(age)
+}
+
+func (cb *CircuitBreaker[T]) onSuccess(state State, age uint64, duration time.Duration, slow bool, now time.Time) {
 	switch state {
 	case StateClosed:
-		cb.counts.onSuccess(age)
+		cb.counts.onSuccess(age, duration, slow)
+		if cb.readyToTrip(cb.counts.Counts) {
+			cb.setState(StateOpen, now)
+		}
+	case StateForcedClosed:
+		cb.counts.onSuccess(age, duration, slow)
 	case StateHalfOpen:
-		cb.counts.onSuccess(age)
+		cb.counts.onSuccess(age, duration, slow)
 		if cb.counts.ConsecutiveSuccesses >= cb.maxRequests {
 			cb.setState(StateClosed, now)
 		}
 	}
 }
 
-func (cb *CircuitBreaker[T]) onFailure(state State, age uint64, now time.Time) {
+func (cb *CircuitBreaker[T]) onFailure(state State, age uint64, duration time.Duration, slow bool, now time.Time) {
 	switch state {
 	case StateClosed:
-		cb.counts.onFailure(age)
+		cb.counts.onFailure(age, duration, slow)
 		if cb.readyToTrip(cb.counts.Counts) {
 			cb.setState(StateOpen, now)
 		}
+	case StateForcedClosed:
+		cb.counts.onFailure(age, duration, slow)
 	case StateHalfOpen:
 		cb.setState(StateOpen, now)
 	}
@@ -272,7 +494,8 @@ func (cb *CircuitBreaker[T]) onFailure(state State, age uint64, now time.Time) {
 
 func (cb *CircuitBreaker[T]) currentState(now time.Time) (State, uint64, uint64) {
 	switch cb.state {
-	case StateClosed:
+	case StateClosed, StateForcedClosed:
+		cb.resetBackoff(now)
 		if !cb.expiry.IsZero() && cb.expiry.Before(now) {
 			cb.toNewGeneration(now)
 		} else if len(cb.counts.buckets) >= 2 {
@@ -307,11 +530,46 @@ func (cb *CircuitBreaker[T]) setState(state State, now time.Time) {
 	prev := cb.state
 	cb.state = state
 
+	cb.updateBackoff(prev, state, now)
 	cb.toNewGeneration(now)
 
 	if cb.onStateChange != nil {
 		cb.onStateChange(cb.name, prev, state)
 	}
+	cb.emit(Event{Kind: EventStateChange, State: state, From: prev})
+}
+
+// ForceOpen places the CircuitBreaker into StateForcedOpen until Release.
+func (cb *CircuitBreaker[T]) ForceOpen() {
+	cb.setManualState(StateForcedOpen)
+}
+
+// ForceClosed places the CircuitBreaker into StateForcedClosed until Release.
+func (cb *CircuitBreaker[T]) ForceClosed() {
+	cb.setManualState(StateForcedClosed)
+}
+
+// Disable places the CircuitBreaker into StateDisabled until Release.
+func (cb *CircuitBreaker[T]) Disable() {
+	cb.setManualState(StateDisabled)
+}
+
+// Release returns the CircuitBreaker from a manual state to automatic mode,
+// starting closed with cleared Counts. It does nothing in an automatic state.
+func (cb *CircuitBreaker[T]) Release() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if cb.state.IsManual() {
+		cb.setState(StateClosed, time.Now())
+	}
+}
+
+func (cb *CircuitBreaker[T]) setManualState(state State) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.setState(state, time.Now())
 }
 
 func (cb *CircuitBreaker[T]) toNewGeneration(now time.Time) {
@@ -321,15 +579,15 @@ func (cb *CircuitBreaker[T]) toNewGeneration(now time.Time) {
 
 	var zero time.Time
 	switch cb.state {
-	case StateClosed:
+	case StateClosed, StateForcedClosed:
 		if cb.interval == 0 || len(cb.counts.buckets) >= 2 {
 			cb.expiry = zero
 		} else {
 			cb.expiry = now.Add(cb.interval)
 		}
 	case StateOpen:
-		cb.expiry = now.Add(cb.timeout)
-	default: // StateHalfOpen
+		cb.expiry = now.Add(cb.openTimeout())
+	default: // StateHalfOpen, StateForcedOpen, StateDisabled
 		cb.expiry = zero
 	}
 }
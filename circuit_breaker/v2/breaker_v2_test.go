@@ -0,0 +1,590 @@
+package circuit_breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+var errTest = errors.New("test error")
+
+// withoutDuration zeroes the measured time of c, which varies between runs.
+func withoutDuration(c Counts) Counts {
+	c.TotalDuration = 0
+	return c
+}
+
+// TestCircuitBreaker_ExecuteContext tests how ExecuteContext counts cancelled,
+// timed out and rejected requests under each CancelPolicy
+func TestCircuitBreaker_ExecuteContext(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name       string
+		policy     CancelPolicy
+		ctx        context.Context
+		reqErr     error
+		wantErr    error
+		wantRan    bool
+		wantCounts Counts
+	}{
+		{
+			name:       "success",
+			ctx:        context.Background(),
+			wantRan:    true,
+			wantCounts: Counts{Requests: 1, TotalSuccesses: 1, ConsecutiveSuccesses: 1},
+		},
+		{
+			name:       "canceled is ignored by default",
+			ctx:        context.Background(),
+			reqErr:     fmt.Errorf("call: %w", context.Canceled),
+			wantErr:    context.Canceled,
+			wantRan:    true,
+			wantCounts: Counts{},
+		},
+		{
+			name:       "canceled as failure",
+			policy:     CancelAsFailure,
+			ctx:        context.Background(),
+			reqErr:     context.Canceled,
+			wantErr:    context.Canceled,
+			wantRan:    true,
+			wantCounts: Counts{Requests: 1, TotalFailures: 1, ConsecutiveFailures: 1},
+		},
+		{
+			name:       "canceled as success",
+			policy:     CancelAsSuccess,
+			ctx:        context.Background(),
+			reqErr:     context.Canceled,
+			wantErr:    context.Canceled,
+			wantRan:    true,
+			wantCounts: Counts{Requests: 1, TotalSuccesses: 1, ConsecutiveSuccesses: 1},
+		},
+		{
+			name:       "deadline exceeded is a failure",
+			ctx:        context.Background(),
+			reqErr:     context.DeadlineExceeded,
+			wantErr:    context.DeadlineExceeded,
+			wantRan:    true,
+			wantCounts: Counts{Requests: 1, TotalFailures: 1, ConsecutiveFailures: 1},
+		},
+		{
+			name:       "done context is not run",
+			ctx:        cancelled,
+			wantErr:    context.Canceled,
+			wantCounts: Counts{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb := NewCircuitBreaker[int](Settings{CancelPolicy: tt.policy})
+			ran := false
+			_, err := cb.ExecuteContext(tt.ctx, func(ctx context.Context) (int, error) {
+				ran = true
+				return 1, tt.reqErr
+			})
+
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("ExecuteContext() error = %v; expected %v", err, tt.wantErr)
+			}
+			if ran != tt.wantRan {
+				t.Errorf("ExecuteContext() ran = %v; expected %v", ran, tt.wantRan)
+			}
+			if got := withoutDuration(cb.Counts()); got != tt.wantCounts {
+				t.Errorf("Counts() = %+v; expected %+v", got, tt.wantCounts)
+			}
+		})
+	}
+}
+
+// TestCircuitBreaker_ExecuteContextHalfOpen tests that an ignored cancellation
+// in the half-open state frees its slot for the next probe
+func TestCircuitBreaker_ExecuteContextHalfOpen(t *testing.T) {
+	cb := NewCircuitBreaker[int](Settings{
+		Timeout:     10 * time.Millisecond,
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	})
+	fail := func(ctx context.Context) (int, error) { return 0, errTest }
+	if _, err := cb.ExecuteContext(context.Background(), fail); !errors.Is(err, errTest) {
+		t.Fatalf("ExecuteContext() error = %v; expected %v", err, errTest)
+	}
+	if cb.State() != StateOpen {
+		t.Fatalf("State() = %v; expected open", cb.State())
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := cb.ExecuteContext(context.Background(), func(ctx context.Context) (int, error) {
+		return 0, context.Canceled
+	}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ExecuteContext() error = %v; expected context.Canceled", err)
+	}
+	if cb.State() != StateHalfOpen {
+		t.Fatalf("State() = %v; expected half-open after an ignored probe", cb.State())
+	}
+	if _, err := cb.ExecuteContext(context.Background(), func(ctx context.Context) (int, error) {
+		return 1, nil
+	}); err != nil {
+		t.Fatalf("ExecuteContext() error = %v; expected the next probe to run", err)
+	}
+	if cb.State() != StateClosed {
+		t.Errorf("State() = %v; expected closed", cb.State())
+	}
+}
+
+// TestCircuitBreaker_ExecuteContextPanic tests that a panic in the request is raised in the
+// caller while ExecuteContext waits for it, and in the request's goroutine once it returned
+func TestCircuitBreaker_ExecuteContextPanic(t *testing.T) {
+	const panicAfterReturn = "CIRCUIT_BREAKER_PANIC_AFTER_RETURN"
+
+	t.Run("before return", func(t *testing.T) {
+		cb := NewCircuitBreaker[int](Settings{})
+		defer func() {
+			if e := recover(); e != "boom" {
+				t.Errorf("recover() = %v; expected boom", e)
+			}
+			want := Counts{Requests: 1, TotalFailures: 1, ConsecutiveFailures: 1}
+			if got := withoutDuration(cb.Counts()); got != want {
+				t.Errorf("Counts() = %+v; expected %+v", got, want)
+			}
+		}()
+		_, _ = cb.ExecuteContext(context.Background(), func(ctx context.Context) (int, error) {
+			panic("boom")
+		})
+	})
+
+	t.Run("after return", func(t *testing.T) {
+		if os.Getenv(panicAfterReturn) == "1" {
+			cb := NewCircuitBreaker[int](Settings{})
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(10*time.Millisecond, cancel)
+			returned := make(chan struct{})
+			_, _ = cb.ExecuteContext(ctx, func(ctx context.Context) (int, error) {
+				<-returned
+				panic("late boom")
+			})
+			close(returned)
+			time.Sleep(time.Second)
+			return
+		}
+
+		// The panic crashes the process, so it runs in a child process.
+		cmd := exec.Command(os.Args[0], "-test.run=^TestCircuitBreaker_ExecuteContextPanic$/^after_return$")
+		cmd.Env = append(os.Environ(), panicAfterReturn+"=1")
+		out, err := cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(out), "panic: late boom") {
+			t.Errorf("child error = %v, output:\n%s\nexpected a late boom panic", err, out)
+		}
+	})
+}
+
+// TestCircuitBreaker_ExecuteContextInFlight tests that ExecuteContext returns as soon as
+// ctx is done while the request still runs, and counts cancellations by ctx
+func TestCircuitBreaker_ExecuteContextInFlight(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     CancelPolicy
+		timeout    time.Duration
+		wantErr    error
+		wantCounts Counts
+	}{
+		{
+			name:       "cancelled in flight is ignored",
+			wantErr:    context.Canceled,
+			wantCounts: Counts{},
+		},
+		{
+			name:       "cancelled in flight as failure",
+			policy:     CancelAsFailure,
+			wantErr:    context.Canceled,
+			wantCounts: Counts{Requests: 1, TotalFailures: 1, ConsecutiveFailures: 1},
+		},
+		{
+			name:       "deadline in flight is a failure",
+			timeout:    20 * time.Millisecond,
+			wantErr:    context.DeadlineExceeded,
+			wantCounts: Counts{Requests: 1, TotalFailures: 1, ConsecutiveFailures: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb := NewCircuitBreaker[int](Settings{CancelPolicy: tt.policy})
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.timeout > 0 {
+				ctx, cancel = context.WithTimeout(context.Background(), tt.timeout)
+			}
+			defer cancel()
+
+			release := make(chan struct{})
+			defer close(release)
+			req := func(ctx context.Context) (int, error) {
+				<-release
+				return 1, nil
+			}
+			if tt.timeout == 0 {
+				time.AfterFunc(20*time.Millisecond, cancel)
+			}
+
+			start := time.Now()
+			_, err := cb.ExecuteContext(ctx, req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ExecuteContext() error = %v; expected %v", err, tt.wantErr)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("ExecuteContext() returned after %v; expected right after ctx is done", elapsed)
+			}
+			if got := withoutDuration(cb.Counts()); got != tt.wantCounts {
+				t.Errorf("Counts() = %+v; expected %+v", got, tt.wantCounts)
+			}
+		})
+	}
+}
+
+// TestTwoStepCircuitBreaker_AllowContext tests that the done callback applies
+// IsSuccessful and the cancel policy, also to a client error returned after ctx was cancelled
+func TestTwoStepCircuitBreaker_AllowContext(t *testing.T) {
+	tscb := NewTwoStepCircuitBreaker[int](Settings{})
+
+	for _, err := range []error{nil, errTest, context.Canceled} {
+		done, allowErr := tscb.AllowContext(context.Background())
+		if allowErr != nil {
+			t.Fatalf("AllowContext() error = %v", allowErr)
+		}
+		done(err)
+	}
+	aborted, abort := context.WithCancel(context.Background())
+	done, allowErr := tscb.AllowContext(aborted)
+	if allowErr != nil {
+		t.Fatalf("AllowContext() error = %v", allowErr)
+	}
+	abort()
+	done(errors.New("client: request aborted"))
+	want := Counts{Requests: 2, TotalSuccesses: 1, TotalFailures: 1, ConsecutiveFailures: 1}
+	if got := withoutDuration(tscb.Counts()); got != want {
+		t.Errorf("Counts() = %+v; expected %+v", got, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := tscb.AllowContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("AllowContext() error = %v; expected context.Canceled", err)
+	}
+}
+
+// TestDistributedCircuitBreaker_ExecuteContext tests that counts of ExecuteContext
+// are shared through the store and that a done context stops waiting for the lock
+func TestDistributedCircuitBreaker_ExecuteContext(t *testing.T) {
+	store := NewMemoryStore()
+	dcb, err := NewDistributedCircuitBreaker[int](store, Settings{Name: "dcb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, reqErr := range []error{nil, context.Canceled, errTest} {
+		_, _ = dcb.ExecuteContext(context.Background(), func(ctx context.Context) (int, error) {
+			return 0, reqErr
+		})
+	}
+	shared, err := dcb.getSharedState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Counts{Requests: 2, TotalSuccesses: 1, TotalFailures: 1, ConsecutiveFailures: 1}
+	if withoutDuration(shared.Counts) != want {
+		t.Errorf("shared Counts = %+v; expected %+v", shared.Counts, want)
+	}
+
+	if err := store.Lock(dcb.mutexKey()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = dcb.ExecuteContext(ctx, func(ctx context.Context) (int, error) { return 0, nil })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ExecuteContext() error = %v; expected context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ExecuteContext() waited %v for a locked store", elapsed)
+	}
+}
+
+// TestTripPolicies tests the rate-based ReadyToTrip builders
+func TestTripPolicies(t *testing.T) {
+	tests := []struct {
+		name   string
+		trip   func(counts Counts) bool
+		counts Counts
+		want   bool
+	}{
+		{
+			name:   "failure rate above threshold",
+			trip:   FailureRateTrip(50, 4),
+			counts: Counts{TotalSuccesses: 1, TotalFailures: 3},
+			want:   true,
+		},
+		{
+			name:   "failure rate at threshold",
+			trip:   FailureRateTrip(50, 4),
+			counts: Counts{TotalSuccesses: 2, TotalFailures: 2},
+			want:   false,
+		},
+		{
+			name:   "too few finished requests",
+			trip:   FailureRateTrip(50, 4),
+			counts: Counts{Requests: 10, TotalFailures: 3},
+			want:   false,
+		},
+		{
+			name:   "slow call rate above threshold",
+			trip:   SlowCallRateTrip(40, 5),
+			counts: Counts{TotalSuccesses: 5, SlowCalls: 3},
+			want:   true,
+		},
+		{
+			name:   "any trips on the second policy",
+			trip:   AnyTrip(FailureRateTrip(50, 5), SlowCallRateTrip(40, 5)),
+			counts: Counts{TotalSuccesses: 5, SlowCalls: 3},
+			want:   true,
+		},
+		{
+			name:   "any without policies",
+			trip:   AnyTrip(),
+			counts: Counts{TotalFailures: 100},
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.trip(tt.counts); got != tt.want {
+				t.Errorf("trip(%+v) = %v; expected %v", tt.counts, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCircuitBreaker_FailureRateAtMinimum tests that a success bringing the window to its
+// minimum size trips the breaker when the failure rate is already above the threshold
+func TestCircuitBreaker_FailureRateAtMinimum(t *testing.T) {
+	cb := NewCircuitBreaker[int](Settings{ReadyToTrip: FailureRateTrip(50, 10)})
+	fail := func() (int, error) { return 0, errTest }
+	succeed := func() (int, error) { return 1, nil }
+
+	for range 9 {
+		_, _ = cb.Execute(fail)
+	}
+	if state := cb.State(); state != StateClosed {
+		t.Fatalf("State() below the minimum = %v; expected closed", state)
+	}
+	if _, err := cb.Execute(succeed); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if state := cb.State(); state != StateOpen {
+		t.Errorf("State() after 9 failures and 1 success = %v; expected open", state)
+	}
+}
+
+// TestCircuitBreaker_SlowCalls tests that slow successful calls are counted
+// and trip a breaker using SlowCallRateTrip
+func TestCircuitBreaker_SlowCalls(t *testing.T) {
+	cb := NewCircuitBreaker[int](Settings{
+		SlowCallDuration: 10 * time.Millisecond,
+		ReadyToTrip:      SlowCallRateTrip(50, 2),
+	})
+	fast := func() (int, error) { return 1, nil }
+	slow := func() (int, error) {
+		time.Sleep(15 * time.Millisecond)
+		return 1, nil
+	}
+
+	for _, req := range []func() (int, error){fast, slow} {
+		if _, err := cb.Execute(req); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+	}
+	counts := cb.Counts()
+	if counts.SlowCalls != 1 || counts.TotalDuration < 15*time.Millisecond || counts.AverageDuration() < 7*time.Millisecond {
+		t.Fatalf("Counts() = %+v; expected one slow call and its duration", counts)
+	}
+	if cb.State() != StateClosed {
+		t.Fatalf("State() = %v; expected closed at 50%% slow calls", cb.State())
+	}
+
+	if _, err := cb.Execute(slow); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if cb.State() != StateOpen {
+		t.Errorf("State() = %v; expected open above 50%% slow calls", cb.State())
+	}
+}
+
+// TestRollingCounts_Durations tests that durations and slow calls leave the
+// window with their bucket
+func TestRollingCounts_Durations(t *testing.T) {
+	rc := newRollingCounts(2)
+	rc.onRequest()
+	rc.onSuccess(0, 30*time.Millisecond, true)
+	rc.grow(1)
+	rc.onRequest()
+	rc.onFailure(1, 10*time.Millisecond, false)
+
+	want := Counts{Requests: 2, TotalSuccesses: 1, TotalFailures: 1, ConsecutiveFailures: 1, TotalDuration: 40 * time.Millisecond, SlowCalls: 1}
+	if rc.Counts != want {
+		t.Fatalf("Counts = %+v; expected %+v", rc.Counts, want)
+	}
+
+	rc.grow(2)
+	want = Counts{Requests: 1, TotalFailures: 1, ConsecutiveFailures: 1, TotalDuration: 10 * time.Millisecond}
+	if rc.Counts != want {
+		t.Errorf("Counts after roll = %+v; expected %+v", rc.Counts, want)
+	}
+}
+
+// TestCircuitBreaker_ManualStates tests that manual states ignore traffic
+// until Release returns the breaker to a fresh closed state
+func TestCircuitBreaker_ManualStates(t *testing.T) {
+	tests := []struct {
+		name       string
+		set        func(cb *CircuitBreaker[int])
+		wantState  State
+		wantErr    error
+		wantCounts Counts
+	}{
+		{
+			name:      "forced open",
+			set:       (*CircuitBreaker[int]).ForceOpen,
+			wantState: StateForcedOpen,
+			wantErr:   ErrOpenState,
+		},
+		{
+			name:       "forced closed",
+			set:        (*CircuitBreaker[int]).ForceClosed,
+			wantState:  StateForcedClosed,
+			wantErr:    errTest,
+			wantCounts: Counts{Requests: 3, TotalFailures: 3, ConsecutiveFailures: 3},
+		},
+		{
+			name:      "disabled",
+			set:       (*CircuitBreaker[int]).Disable,
+			wantState: StateDisabled,
+			wantErr:   errTest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var transitions []State
+			cb := NewCircuitBreaker[int](Settings{
+				ReadyToTrip:   func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 },
+				OnStateChange: func(_ string, _ State, to State) { transitions = append(transitions, to) },
+			})
+			tt.set(cb)
+			for range 3 {
+				if _, err := cb.Execute(func() (int, error) { return 0, errTest }); !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Execute() error = %v; expected %v", err, tt.wantErr)
+				}
+			}
+			if got := cb.State(); got != tt.wantState || !got.IsManual() {
+				t.Errorf("State() = %v; expected %v", got, tt.wantState)
+			}
+			if got := withoutDuration(cb.Counts()); got != tt.wantCounts {
+				t.Errorf("Counts() = %+v; expected %+v", got, tt.wantCounts)
+			}
+
+			cb.Release()
+			if got := cb.State(); got != StateClosed || cb.Counts() != (Counts{}) {
+				t.Errorf("after Release() State() = %v, Counts() = %+v; expected closed and cleared", got, cb.Counts())
+			}
+			cb.Release()
+			if want := []State{tt.wantState, StateClosed}; fmt.Sprint(transitions) != fmt.Sprint(want) {
+				t.Errorf("transitions = %v; expected %v", transitions, want)
+			}
+		})
+	}
+}
+
+// TestDistributedCircuitBreaker_ManualStates tests that a manual state set on one
+// instance applies to every instance sharing the store
+func TestDistributedCircuitBreaker_ManualStates(t *testing.T) {
+	store := NewMemoryStore()
+	a, err := NewDistributedCircuitBreaker[int](store, Settings{Name: "shared"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewDistributedCircuitBreaker[int](store, Settings{Name: "shared"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	succeed := func() (int, error) { return 1, nil }
+
+	if err := a.ForceOpen(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Execute(succeed); !errors.Is(err, ErrOpenState) {
+		t.Errorf("Execute() on the other instance error = %v; expected ErrOpenState", err)
+	}
+	if state, err := b.State(); err != nil || state != StateForcedOpen {
+		t.Errorf("State() on the other instance = %v, %v; expected forced-open", state, err)
+	}
+
+	if err := b.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Execute(succeed); err != nil {
+		t.Errorf("Execute() after Release() error = %v", err)
+	}
+	if state, err := a.State(); err != nil || state != StateClosed {
+		t.Errorf("State() after Release() = %v, %v; expected closed", state, err)
+	}
+}
+
+// callLogStore is a MemoryStore that logs the calls made to it.
+type callLogStore struct {
+	*MemoryStore
+	calls []string
+}
+
+func (cs *callLogStore) Lock(name string) error {
+	cs.calls = append(cs.calls, "Lock")
+	return cs.MemoryStore.Lock(name)
+}
+
+func (cs *callLogStore) Unlock(name string) error {
+	cs.calls = append(cs.calls, "Unlock")
+	return cs.MemoryStore.Unlock(name)
+}
+
+func (cs *callLogStore) GetData(name string) ([]byte, error) {
+	cs.calls = append(cs.calls, "GetData")
+	return cs.MemoryStore.GetData(name)
+}
+
+func (cs *callLogStore) SetData(name string, data []byte) error {
+	cs.calls = append(cs.calls, "SetData")
+	return cs.MemoryStore.SetData(name, data)
+}
+
+// TestDistributedCircuitBreaker_ManualStateUnderLock tests that a manual state is set
+// on the shared state read while holding the lock
+func TestDistributedCircuitBreaker_ManualStateUnderLock(t *testing.T) {
+	store := &callLogStore{MemoryStore: NewMemoryStore()}
+	dcb, err := NewDistributedCircuitBreaker[int](store, Settings{Name: "locked"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store.calls = nil
+	if err := dcb.ForceOpen(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Lock", "GetData", "SetData", "Unlock"}; fmt.Sprint(store.calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %v; expected %v", store.calls, want)
+	}
+}
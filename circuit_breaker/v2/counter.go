@@ -1,17 +1,60 @@
 package circuit_breaker
 
+import "time"
+
+// Counts holds the numbers of requests and their successes/failures.
+// TotalDuration sums the durations of the finished requests and SlowCalls counts those
+// that took at least Settings.SlowCallDuration, whether they succeeded or failed.
 type Counts struct {
 	Requests             uint32
 	TotalSuccesses       uint32
 	TotalFailures        uint32
 	ConsecutiveSuccesses uint32
 	ConsecutiveFailures  uint32
+	TotalDuration        time.Duration
+	SlowCalls            uint32
+}
+
+// Finished returns the number of requests with a counted outcome.
+func (c Counts) Finished() uint32 {
+	return c.TotalSuccesses + c.TotalFailures
+}
+
+// FailureRate returns the percentage of finished requests that failed, or 0 if none finished.
+func (c Counts) FailureRate() float64 {
+	if c.Finished() == 0 {
+		return 0
+	}
+	return float64(c.TotalFailures) * 100 / float64(c.Finished())
+}
+
+// SlowCallRate returns the percentage of finished requests that were slow, or 0 if none finished.
+func (c Counts) SlowCallRate() float64 {
+	if c.Finished() == 0 {
+		return 0
+	}
+	return float64(c.SlowCalls) * 100 / float64(c.Finished())
+}
+
+// AverageDuration returns the mean duration of the finished requests, or 0 if none finished.
+func (c Counts) AverageDuration() time.Duration {
+	if c.Finished() == 0 {
+		return 0
+	}
+	return c.TotalDuration / time.Duration(c.Finished())
 }
 
 func (c *Counts) onRequest() {
 	c.Requests++
 }
 
+func (c *Counts) onDuration(duration time.Duration, slow bool) {
+	c.TotalDuration += duration
+	if slow {
+		c.SlowCalls++
+	}
+}
+
 func (c *Counts) onSuccess() {
 	c.TotalSuccesses++
 	c.ConsecutiveSuccesses++
@@ -24,12 +67,20 @@ func (c *Counts) onFailure() {
 	c.ConsecutiveSuccesses = 0
 }
 
+func (c *Counts) onIgnore() {
+	if c.Requests > 0 {
+		c.Requests--
+	}
+}
+
 func (c *Counts) clear() {
 	c.Requests = 0
 	c.TotalSuccesses = 0
 	c.TotalFailures = 0
 	c.ConsecutiveSuccesses = 0
 	c.ConsecutiveFailures = 0
+	c.TotalDuration = 0
+	c.SlowCalls = 0
 }
 
 type rollingCounts struct {
@@ -64,25 +115,40 @@ func (rc *rollingCounts) onRequest() {
 	rc.buckets[rc.current()].onRequest()
 }
 
-func (rc *rollingCounts) onSuccess(age uint64) {
+func (rc *rollingCounts) onSuccess(age uint64, duration time.Duration, slow bool) {
 	if age > rc.age {
 		return
 	}
 
 	if rc.age-age < uint64(len(rc.buckets)) {
 		rc.Counts.onSuccess()
+		rc.Counts.onDuration(duration, slow)
 		rc.buckets[rc.index(age)].onSuccess()
+		rc.buckets[rc.index(age)].onDuration(duration, slow)
 	}
 }
 
-func (rc *rollingCounts) onFailure(age uint64) {
+func (rc *rollingCounts) onFailure(age uint64, duration time.Duration, slow bool) {
 	if age > rc.age {
 		return
 	}
 
 	if rc.age-age < uint64(len(rc.buckets)) {
 		rc.Counts.onFailure()
+		rc.Counts.onDuration(duration, slow)
 		rc.buckets[rc.index(age)].onFailure()
+		rc.buckets[rc.index(age)].onDuration(duration, slow)
+	}
+}
+
+func (rc *rollingCounts) onIgnore(age uint64) {
+	if age > rc.age {
+		return
+	}
+
+	if rc.age-age < uint64(len(rc.buckets)) {
+		rc.Counts.onIgnore()
+		rc.buckets[rc.index(age)].onIgnore()
 	}
 }
 
@@ -159,6 +225,18 @@ func (rc *rollingCounts) subtract(oldest uint64) {
 	} else {
 		rc.TotalFailures = 0
 	}
+
+	if rc.TotalDuration > bucket.TotalDuration {
+		rc.TotalDuration -= bucket.TotalDuration
+	} else {
+		rc.TotalDuration = 0
+	}
+
+	if rc.SlowCalls > bucket.SlowCalls {
+		rc.SlowCalls -= bucket.SlowCalls
+	} else {
+		rc.SlowCalls = 0
+	}
 }
 
 func (rc *rollingCounts) grow(age uint64) {
@@ -1,6 +1,7 @@
 package circuit_breaker
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"time"
@@ -15,16 +16,19 @@ var (
 
 // SharedState represents the shared state of DistributedCircuitBreaker.
 type SharedState struct {
-	State      State     `json:"state"`
-	Generation uint64    `json:"generation"`
-	Age        uint64    `json:"age"`
-	Counts     Counts    `json:"counts"`
-	Buckets    []Counts  `json:"buckets"`
-	Start      time.Time `json:"start"`
-	Expiry     time.Time `json:"expiry"`
+	State        State     `json:"state"`
+	Generation   uint64    `json:"generation"`
+	Age          uint64    `json:"age"`
+	Counts       Counts    `json:"counts"`
+	Buckets      []Counts  `json:"buckets"`
+	Start        time.Time `json:"start"`
+	Expiry       time.Time `json:"expiry"`
+	BackoffLevel uint32    `json:"backoff_level"`
+	ClosedSince  time.Time `json:"closed_since"`
 }
 
 // SharedDataStore stores the shared state of DistributedCircuitBreaker.
+// MemoryStore, FileStore and RedisStore implement it.
 type SharedDataStore interface {
 	Lock(name string) error
 	Unlock(name string) error
@@ -81,6 +85,11 @@ func (dcb *DistributedCircuitBreaker[T]) mutexKey() string {
 }
 
 func (dcb *DistributedCircuitBreaker[T]) lock() error {
+	return dcb.lockContext(context.Background())
+}
+
+// lockContext retries the shared lock until mutexTimeout passes or ctx is done.
+func (dcb *DistributedCircuitBreaker[T]) lockContext(ctx context.Context) error {
 	if dcb.store == nil {
 		return ErrNoSharedStore
 	}
@@ -93,7 +102,13 @@ func (dcb *DistributedCircuitBreaker[T]) lock() error {
 			return nil
 		}
 
-		time.Sleep(mutexWaitTime)
+		timer := time.NewTimer(mutexWaitTime)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
 	}
 	return err
 }
@@ -151,6 +166,8 @@ func (dcb *DistributedCircuitBreaker[T]) inject(shared SharedState) {
 	dcb.counts.buckets = copyBuckets(shared.Buckets)
 	dcb.start = shared.Start
 	dcb.expiry = shared.Expiry
+	dcb.backoffLevel = shared.BackoffLevel
+	dcb.closedSince = shared.ClosedSince
 }
 
 func copyBuckets(buckets []Counts) []Counts {
@@ -168,13 +185,15 @@ func (dcb *DistributedCircuitBreaker[T]) extract() SharedState {
 	defer dcb.mutex.Unlock()
 
 	state := SharedState{
-		State:      dcb.state,
-		Generation: dcb.generation,
-		Age:        dcb.counts.age,
-		Counts:     dcb.counts.Counts,
-		Buckets:    copyBuckets(dcb.counts.buckets),
-		Start:      dcb.start,
-		Expiry:     dcb.expiry,
+		State:        dcb.state,
+		Generation:   dcb.generation,
+		Age:          dcb.counts.age,
+		Counts:       dcb.counts.Counts,
+		Buckets:      copyBuckets(dcb.counts.buckets),
+		Start:        dcb.start,
+		Expiry:       dcb.expiry,
+		BackoffLevel: dcb.backoffLevel,
+		ClosedSince:  dcb.closedSince,
 	}
 
 	return state
@@ -235,3 +254,85 @@ func (dcb *DistributedCircuitBreaker[T]) Execute(req func() (T, error)) (t T, er
 
 	return t, err
 }
+
+// ExecuteContext runs the given request if the DistributedCircuitBreaker accepts it,
+// like CircuitBreaker.ExecuteContext. Waiting for the shared lock also stops when ctx is done.
+func (dcb *DistributedCircuitBreaker[T]) ExecuteContext(ctx context.Context, req func(ctx context.Context) (T, error)) (t T, err error) {
+	if err = ctx.Err(); err != nil {
+		return t, err
+	}
+
+	shared, err := dcb.getSharedState()
+	if err != nil {
+		return t, err
+	}
+
+	err = dcb.lockContext(ctx)
+	if err != nil {
+		return t, err
+	}
+	defer func() {
+		e := dcb.unlock()
+		if err == nil {
+			err = e
+		}
+	}()
+
+	dcb.inject(shared)
+	t, err = dcb.CircuitBreaker.ExecuteContext(ctx, req)
+	shared = dcb.extract()
+
+	e := dcb.setSharedState(shared)
+	if e != nil {
+		return t, e
+	}
+
+	return t, err
+}
+
+// ForceOpen places the DistributedCircuitBreaker into StateForcedOpen until Release,
+// for every instance sharing its state.
+func (dcb *DistributedCircuitBreaker[T]) ForceOpen() error {
+	return dcb.setSharedManualState(dcb.CircuitBreaker.ForceOpen)
+}
+
+// ForceClosed places the DistributedCircuitBreaker into StateForcedClosed until Release,
+// for every instance sharing its state.
+func (dcb *DistributedCircuitBreaker[T]) ForceClosed() error {
+	return dcb.setSharedManualState(dcb.CircuitBreaker.ForceClosed)
+}
+
+// Disable places the DistributedCircuitBreaker into StateDisabled until Release,
+// for every instance sharing its state.
+func (dcb *DistributedCircuitBreaker[T]) Disable() error {
+	return dcb.setSharedManualState(dcb.CircuitBreaker.Disable)
+}
+
+// Release returns the DistributedCircuitBreaker from a manual state to automatic mode,
+// for every instance sharing its state.
+func (dcb *DistributedCircuitBreaker[T]) Release() error {
+	return dcb.setSharedManualState(dcb.CircuitBreaker.Release)
+}
+
+func (dcb *DistributedCircuitBreaker[T]) setSharedManualState(set func()) (err error) {
+	err = dcb.lock()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		e := dcb.unlock()
+		if err == nil {
+			err = e
+		}
+	}()
+
+	// Read under the lock, so that a request finishing meanwhile is not overwritten.
+	shared, err := dcb.getSharedState()
+	if err != nil {
+		return err
+	}
+
+	dcb.inject(shared)
+	set()
+	return dcb.setSharedState(dcb.extract())
+}
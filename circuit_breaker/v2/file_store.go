@@ -0,0 +1,232 @@
+package circuit_breaker
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileStore is a SharedDataStore in a local directory, for breakers shared by
+// several processes on one host.
+//
+// A lock is a file created exclusively. Each Lock takes a fencing token from a counter next to
+// the lock file and writes it there with a suffix unique to the owner. A lock file older than
+// LockTTL is assumed to belong to a crashed process: it is taken over by renaming it aside,
+// which only one process can do, and checking that the renamed file is the stale one.
+// Locks are not refreshed, so LockTTL must exceed the longest request run under the lock.
+//
+// SetData writes only while the lock guarding the key still holds the owner's token and no
+// higher token has written the key, so an owner whose lock expired cannot overwrite the state
+// of the next owner. The lock guarding the state key of DistributedCircuitBreaker is its mutex
+// key; any other key is guarded by the lock of the same name. Data is written to a temporary
+// file renamed over the old one, so readers never see a partial write. Names are escaped into
+// file names.
+type FileStore struct {
+	dir     string
+	lockTTL time.Duration
+
+	mutex  sync.Mutex
+	tokens map[string]string
+}
+
+// NewFileStore returns a FileStore in dir, creating the directory if needed.
+// If lockTTL is less than or equal to 0, it is set to 10 seconds.
+func NewFileStore(dir string, lockTTL time.Duration) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if lockTTL <= 0 {
+		lockTTL = defaultLockTTL
+	}
+	return &FileStore{dir: dir, lockTTL: lockTTL, tokens: make(map[string]string)}, nil
+}
+
+const defaultLockTTL = 10 * time.Second
+
+func (fst *FileStore) path(name, ext string) string {
+	return filepath.Join(fst.dir, url.QueryEscape(name)+ext)
+}
+
+// uniqueSuffix names this process and moment, for tokens and temporary files.
+func uniqueSuffix() string {
+	return strconv.Itoa(os.Getpid()) + "-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// Lock takes the lock name, or returns ErrLocked if another owner holds it.
+func (fst *FileStore) Lock(name string) error {
+	fst.mutex.Lock()
+	defer fst.mutex.Unlock()
+
+	path := fst.path(name, ".lock")
+	for range 2 {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			token, err := fst.nextToken(name)
+			if err == nil {
+				_, err = f.WriteString(token)
+			}
+			if e := f.Close(); err == nil {
+				err = e
+			}
+			if err != nil {
+				os.Remove(path)
+				return err
+			}
+			fst.tokens[name] = token
+			return nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return err
+		}
+
+		stale, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue // released meanwhile
+		}
+		info, statErr := os.Stat(path)
+		if err != nil || statErr != nil || time.Since(info.ModTime()) < fst.lockTTL {
+			return ErrLocked
+		}
+		if !fst.takeOver(path, string(stale)) {
+			return ErrLocked
+		}
+	}
+	return ErrLocked
+}
+
+// nextToken increments the fencing counter of lock name and returns a token carrying it.
+// Only the holder of the lock calls it.
+func (fst *FileStore) nextToken(name string) (string, error) {
+	counter := fst.path(name, ".fence")
+	var fence uint64
+	if data, err := os.ReadFile(counter); err == nil {
+		if fence, err = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64); err != nil {
+			return "", fmt.Errorf("file store: bad fence in %s: %w", counter, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	fence++
+	if err := fst.writeFile(counter, []byte(strconv.FormatUint(fence, 10))); err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(fence, 10) + ":" + uniqueSuffix(), nil
+}
+
+// tokenFence returns the fencing number of a token written by nextToken.
+func tokenFence(token string) uint64 {
+	fence, _, _ := strings.Cut(token, ":")
+	n, _ := strconv.ParseUint(fence, 10, 64)
+	return n
+}
+
+// takeOver removes the lock file at path if it still holds token, and reports whether it did.
+// The file is first renamed aside, which only one process can do; a file that turns out to
+// hold another token is put back unless a new lock was created meanwhile.
+func (fst *FileStore) takeOver(path, token string) bool {
+	claim := path + ".claim-" + uniqueSuffix()
+	if err := os.Rename(path, claim); err != nil {
+		return false
+	}
+	defer os.Remove(claim)
+
+	current, err := os.ReadFile(claim)
+	if err == nil && string(current) == token {
+		return true
+	}
+	_ = os.Link(claim, path)
+	return false
+}
+
+// Unlock releases the lock name. It returns ErrNotLocked if this FileStore does not hold it,
+// and ErrLockLost if the lock file now belongs to another owner.
+func (fst *FileStore) Unlock(name string) error {
+	fst.mutex.Lock()
+	defer fst.mutex.Unlock()
+
+	token, ok := fst.tokens[name]
+	if !ok {
+		return ErrNotLocked
+	}
+	delete(fst.tokens, name)
+
+	// Renaming a lock file that belongs to another owner would release it for a moment,
+	// so the token is checked first.
+	path := fst.path(name, ".lock")
+	current, err := os.ReadFile(path)
+	if err != nil || string(current) != token || !fst.takeOver(path, token) {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// GetData returns the data stored under name, or nil if there is none.
+func (fst *FileStore) GetData(name string) ([]byte, error) {
+	data, err := os.ReadFile(fst.path(name, ".data"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, data, _ = bytes.Cut(data, []byte("\n"))
+	return data, nil
+}
+
+// SetData atomically replaces the data stored under name. The lock guarding name must be held
+// by this FileStore; otherwise SetData returns ErrNotLocked, or ErrLockLost if the lock was
+// taken over since or a newer owner already wrote name.
+func (fst *FileStore) SetData(name string, data []byte) error {
+	fst.mutex.Lock()
+	defer fst.mutex.Unlock()
+
+	lock := defaultLockKey(name)
+	token, ok := fst.tokens[lock]
+	if !ok {
+		return ErrNotLocked
+	}
+	current, err := os.ReadFile(fst.path(lock, ".lock"))
+	if err != nil || string(current) != token {
+		return ErrLockLost
+	}
+
+	path := fst.path(name, ".data")
+	fence := tokenFence(token)
+	if old, err := os.ReadFile(path); err == nil {
+		header, _, _ := bytes.Cut(old, []byte("\n"))
+		if last, _ := strconv.ParseUint(string(header), 10, 64); fence < last {
+			return ErrLockLost
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	content := append([]byte(strconv.FormatUint(fence, 10)+"\n"), data...)
+	return fst.writeFile(path, content)
+}
+
+// writeFile atomically replaces the file at path with data.
+func (fst *FileStore) writeFile(path string, data []byte) error {
+	f, err := os.CreateTemp(fst.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
@@ -0,0 +1,79 @@
+package circuit_breaker
+
+import (
+	"fmt"
+	"time"
+)
+
+// EventKind is a type that represents the kind of an Event.
+type EventKind int
+
+// These constants are kinds of Event.
+const (
+	// EventSuccess is a request counted as a success.
+	EventSuccess EventKind = iota
+	// EventFailure is a request counted as a failure.
+	EventFailure
+	// EventRejected is a request refused with ErrOpenState or ErrTooManyRequests.
+	EventRejected
+	// EventIgnored is a request counted as neither, by Settings.CancelPolicy.
+	EventIgnored
+	// EventStateChange is a transition between two states.
+	EventStateChange
+)
+
+// String implements stringer interface.
+func (k EventKind) String() string {
+	switch k {
+	case EventSuccess:
+		return "success"
+	case EventFailure:
+		return "failure"
+	case EventRejected:
+		return "rejected"
+	case EventIgnored:
+		return "ignored"
+	case EventStateChange:
+		return "state_change"
+	default:
+		return fmt.Sprintf("unknown event: %d", k)
+	}
+}
+
+// Event describes one request outcome or state change of a CircuitBreaker.
+//
+// State is the state the request was handled in, or the new state for EventStateChange,
+// in which case From is the previous state.
+// Err is the error of the request, ErrOpenState or ErrTooManyRequests for EventRejected;
+// it is nil for a panic and for TwoStepCircuitBreaker.Allow, which only reports a bool.
+// Duration is how long the request ran; it is zero for EventRejected and EventStateChange.
+type Event struct {
+	Name     string
+	Kind     EventKind
+	State    State
+	From     State
+	Err      error
+	Duration time.Duration
+}
+
+// Listener observes the events of CircuitBreaker.
+// OnEvent is called synchronously while the CircuitBreaker is locked,
+// so it must be fast and must not call methods of the CircuitBreaker.
+type Listener interface {
+	OnEvent(e Event)
+}
+
+// ListenerFunc adapts a function to Listener.
+type ListenerFunc func(e Event)
+
+// OnEvent calls f(e).
+func (f ListenerFunc) OnEvent(e Event) {
+	f(e)
+}
+
+func (cb *CircuitBreaker[T]) emit(e Event) {
+	e.Name = cb.name
+	for _, l := range cb.listeners {
+		l.OnEvent(e)
+	}
+}
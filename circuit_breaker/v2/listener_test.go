@@ -0,0 +1,165 @@
+package circuit_breaker
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// TestCircuitBreaker_Listeners tests the events fired while a breaker trips,
+// rejects, ignores a cancellation and recovers
+func TestCircuitBreaker_Listeners(t *testing.T) {
+	var events []Event
+	cb := NewCircuitBreaker[int](Settings{
+		Name:        "cb",
+		Timeout:     10 * time.Millisecond,
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 },
+		Listeners:   []Listener{ListenerFunc(func(e Event) { e.Duration = 0; events = append(events, e) })},
+	})
+
+	_, _ = cb.Execute(func() (int, error) { return 0, errTest })
+	_, _ = cb.Execute(func() (int, error) { return 1, nil })
+	time.Sleep(20 * time.Millisecond)
+	_, _ = cb.ExecuteContext(context.Background(), func(ctx context.Context) (int, error) { return 0, context.Canceled })
+	_, _ = cb.Execute(func() (int, error) { return 1, nil })
+
+	want := []Event{
+		{Name: "cb", Kind: EventFailure, State: StateClosed, Err: errTest},
+		{Name: "cb", Kind: EventStateChange, State: StateOpen, From: StateClosed},
+		{Name: "cb", Kind: EventRejected, State: StateOpen, Err: ErrOpenState},
+		{Name: "cb", Kind: EventStateChange, State: StateHalfOpen, From: StateOpen},
+		{Name: "cb", Kind: EventIgnored, State: StateHalfOpen, Err: context.Canceled},
+		{Name: "cb", Kind: EventSuccess, State: StateHalfOpen},
+		{Name: "cb", Kind: EventStateChange, State: StateClosed, From: StateHalfOpen},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %+v; expected %+v", events, want)
+	}
+}
+
+// TestPrometheusMetrics_WriteTo tests the text exposition of aggregated events
+func TestPrometheusMetrics_WriteTo(t *testing.T) {
+	pm := NewPrometheusMetrics()
+	for _, e := range []Event{
+		{Name: "b", Kind: EventSuccess, Duration: 500 * time.Millisecond},
+		{Name: `a"1`, Kind: EventFailure, Duration: 250 * time.Millisecond},
+		{Name: `a"1`, Kind: EventStateChange, From: StateClosed, State: StateOpen},
+		{Name: `a"1`, Kind: EventRejected, State: StateOpen, Err: ErrOpenState},
+	} {
+		pm.OnEvent(e)
+	}
+
+	var buf bytes.Buffer
+	n, err := pm.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo() = %d; expected %d bytes", n, buf.Len())
+	}
+	out := buf.String()
+
+	tests := []struct {
+		name string
+		line string
+	}{
+		{name: "state header", line: "# TYPE circuit_breaker_state gauge\n"},
+		{name: "escaped open state", line: `circuit_breaker_state{name="a\"1",state="open"} 1` + "\n"},
+		{name: "default closed state", line: `circuit_breaker_state{name="b",state="closed"} 1` + "\n"},
+		{name: "rejected", line: `circuit_breaker_calls_total{name="a\"1",outcome="rejected"} 1` + "\n"},
+		{name: "zero outcome", line: `circuit_breaker_calls_total{name="b",outcome="failure"} 0` + "\n"},
+		{name: "duration sum", line: `circuit_breaker_call_duration_seconds_sum{name="b"} 0.5` + "\n"},
+		{name: "duration count skips rejections", line: `circuit_breaker_call_duration_seconds_count{name="a\"1"} 1` + "\n"},
+		{name: "transition", line: `circuit_breaker_state_transitions_total{name="a\"1",from="closed",to="open"} 1` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(out, tt.line) {
+				t.Errorf("output lacks %q:\n%s", tt.line, out)
+			}
+		})
+	}
+	if strings.Index(out, `name="a\"1"`) > strings.Index(out, `name="b"`) {
+		t.Errorf("breakers are not ordered by name:\n%s", out)
+	}
+}
+
+// TestOTelMetrics tests the instruments recorded by OTelMetrics through a manual reader
+func TestOTelMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer provider.Shutdown(context.Background())
+
+	om, err := NewOTelMetrics(provider.Meter("circuit_breaker"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer om.Close()
+	cb := NewCircuitBreaker[int](Settings{
+		Name:        "cb",
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 2 },
+		Listeners:   []Listener{om},
+	})
+	for _, reqErr := range []error{nil, errTest, errTest, nil} {
+		_, _ = cb.Execute(func() (int, error) { return 0, reqErr })
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	sums := map[string]int64{}
+	var durations uint64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					sums[m.Name+"/"+attrString(dp.Attributes)] += dp.Value
+				}
+			case metricdata.Gauge[int64]:
+				for _, dp := range data.DataPoints {
+					sums[m.Name+"/"+attrString(dp.Attributes)] += dp.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range data.DataPoints {
+					durations += dp.Count
+				}
+			}
+		}
+	}
+
+	want := map[string]int64{
+		"circuit_breaker.calls/name=cb,outcome=success":                 1,
+		"circuit_breaker.calls/name=cb,outcome=failure":                 2,
+		"circuit_breaker.calls/name=cb,outcome=rejected":                1,
+		"circuit_breaker.state.transitions/from=closed,name=cb,to=open": 1,
+		"circuit_breaker.state/name=cb,state=closed":                    0,
+		"circuit_breaker.state/name=cb,state=half-open":                 0,
+		"circuit_breaker.state/name=cb,state=open":                      1,
+		"circuit_breaker.state/name=cb,state=forced-open":               0,
+		"circuit_breaker.state/name=cb,state=forced-closed":             0,
+		"circuit_breaker.state/name=cb,state=disabled":                  0,
+	}
+	if !reflect.DeepEqual(sums, want) {
+		t.Errorf("metrics = %v; expected %v", sums, want)
+	}
+	if durations != 3 {
+		t.Errorf("duration count = %d; expected 3", durations)
+	}
+}
+
+func attrString(set attribute.Set) string {
+	parts := make([]string, 0, set.Len())
+	for _, kv := range set.ToSlice() {
+		parts = append(parts, string(kv.Key)+"="+kv.Value.Emit())
+	}
+	return strings.Join(parts, ",")
+}
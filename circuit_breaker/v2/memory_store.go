@@ -0,0 +1,82 @@
+package circuit_breaker
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrLocked is returned by SharedDataStore.Lock when the lock is held by someone else.
+	ErrLocked = errors.New("shared lock is held")
+	// ErrNotLocked is returned by SharedDataStore.Unlock and SetData when the caller does not hold the lock.
+	ErrNotLocked = errors.New("shared lock is not held")
+	// ErrLockLost is returned when the lock expired and may have been taken over before Unlock or SetData.
+	ErrLockLost = errors.New("shared lock was lost")
+)
+
+// MemoryStore is a SharedDataStore kept in process memory, for tests and for breakers
+// shared between goroutines of one process. The zero value is ready to use.
+type MemoryStore struct {
+	mutex  sync.Mutex
+	locked map[string]bool
+	data   map[string][]byte
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Lock takes the lock name, or returns ErrLocked if it is held.
+func (ms *MemoryStore) Lock(name string) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if ms.locked[name] {
+		return ErrLocked
+	}
+	if ms.locked == nil {
+		ms.locked = make(map[string]bool)
+	}
+	ms.locked[name] = true
+	return nil
+}
+
+// Unlock releases the lock name, or returns ErrNotLocked if it is not held.
+func (ms *MemoryStore) Unlock(name string) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if !ms.locked[name] {
+		return ErrNotLocked
+	}
+	delete(ms.locked, name)
+	return nil
+}
+
+// GetData returns a copy of the data stored under name, or nil if there is none.
+func (ms *MemoryStore) GetData(name string) ([]byte, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	return cloneBytes(ms.data[name]), nil
+}
+
+// SetData stores a copy of data under name.
+func (ms *MemoryStore) SetData(name string, data []byte) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if ms.data == nil {
+		ms.data = make(map[string][]byte)
+	}
+	ms.data[name] = cloneBytes(data)
+	return nil
+}
+
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte(nil), b...)
+}
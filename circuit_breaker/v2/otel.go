@@ -0,0 +1,102 @@
+package circuit_breaker
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// OTelMetrics is a Listener that records the events of any number of breakers,
+// told apart by the "name" attribute, into OpenTelemetry instruments:
+//
+//	circuit_breaker.calls              counter by "outcome" (EventKind)
+//	circuit_breaker.call.duration      histogram in seconds of requests that ran
+//	circuit_breaker.state.transitions  counter by "from" and "to"
+//	circuit_breaker.state              observable gauge by "state", 1 for the current state
+//
+// A breaker is observed after its first event; its state is the one reported by its latest event.
+type OTelMetrics struct {
+	calls        metric.Int64Counter
+	duration     metric.Float64Histogram
+	transitions  metric.Int64Counter
+	registration metric.Registration
+
+	mutex  sync.Mutex
+	states map[string]State
+}
+
+// NewOTelMetrics creates the instruments on meter and returns an OTelMetrics recording into them.
+func NewOTelMetrics(meter metric.Meter) (*OTelMetrics, error) {
+	om := &OTelMetrics{states: make(map[string]State)}
+
+	var err error
+	om.calls, err = meter.Int64Counter("circuit_breaker.calls",
+		metric.WithDescription("Requests by outcome."), metric.WithUnit("{call}"))
+	if err != nil {
+		return nil, err
+	}
+	om.duration, err = meter.Float64Histogram("circuit_breaker.call.duration",
+		metric.WithDescription("Duration of the requests that ran."), metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	om.transitions, err = meter.Int64Counter("circuit_breaker.state.transitions",
+		metric.WithDescription("State transitions of the circuit breaker."), metric.WithUnit("{transition}"))
+	if err != nil {
+		return nil, err
+	}
+	state, err := meter.Int64ObservableGauge("circuit_breaker.state",
+		metric.WithDescription("Current state of the circuit breaker, 1 for the active state."))
+	if err != nil {
+		return nil, err
+	}
+	om.registration, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		om.mutex.Lock()
+		defer om.mutex.Unlock()
+
+		for name, current := range om.states {
+			for _, st := range states {
+				var v int64
+				if st == current {
+					v = 1
+				}
+				o.ObserveInt64(state, v, metric.WithAttributes(
+					attribute.String("name", name), attribute.String("state", st.String())))
+			}
+		}
+		return nil
+	}, state)
+	if err != nil {
+		return nil, err
+	}
+
+	return om, nil
+}
+
+// OnEvent implements Listener.
+func (om *OTelMetrics) OnEvent(e Event) {
+	ctx := context.Background()
+	name := attribute.String("name", e.Name)
+
+	om.mutex.Lock()
+	om.states[e.Name] = e.State
+	om.mutex.Unlock()
+
+	switch e.Kind {
+	case EventStateChange:
+		om.transitions.Add(ctx, 1, metric.WithAttributes(name,
+			attribute.String("from", e.From.String()), attribute.String("to", e.State.String())))
+	default:
+		om.calls.Add(ctx, 1, metric.WithAttributes(name, attribute.String("outcome", e.Kind.String())))
+		if e.Kind != EventRejected {
+			om.duration.Record(ctx, e.Duration.Seconds(), metric.WithAttributes(name))
+		}
+	}
+}
+
+// Close stops observing the state gauge.
+func (om *OTelMetrics) Close() error {
+	return om.registration.Unregister()
+}
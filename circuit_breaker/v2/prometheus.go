@@ -0,0 +1,160 @@
+package circuit_breaker
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PrometheusMetrics is a Listener that aggregates the events of any number of breakers,
+// told apart by name, and writes them in the Prometheus text exposition format:
+//
+//	circuit_breaker_state{name,state}                      gauge, 1 for the current state
+//	circuit_breaker_calls_total{name,outcome}              counter by EventKind
+//	circuit_breaker_call_duration_seconds{name}            summary of requests that ran
+//	circuit_breaker_state_transitions_total{name,from,to}  counter
+//
+// A breaker appears after its first event; its state is the one reported by its latest event.
+// Share one PrometheusMetrics through Settings.Listeners and serve it on /metrics.
+type PrometheusMetrics struct {
+	mutex    sync.Mutex
+	breakers map[string]*breakerMetrics
+}
+
+type breakerMetrics struct {
+	state         State
+	calls         map[EventKind]uint64
+	durationSum   time.Duration
+	durationCount uint64
+	transitions   map[[2]State]uint64
+}
+
+// NewPrometheusMetrics returns an empty PrometheusMetrics.
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{breakers: make(map[string]*breakerMetrics)}
+}
+
+// OnEvent implements Listener.
+func (pm *PrometheusMetrics) OnEvent(e Event) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	bm := pm.breakers[e.Name]
+	if bm == nil {
+		bm = &breakerMetrics{calls: make(map[EventKind]uint64), transitions: make(map[[2]State]uint64)}
+		pm.breakers[e.Name] = bm
+	}
+
+	bm.state = e.State
+	switch e.Kind {
+	case EventStateChange:
+		bm.transitions[[2]State{e.From, e.State}]++
+	default:
+		bm.calls[e.Kind]++
+		if e.Kind != EventRejected {
+			bm.durationSum += e.Duration
+			bm.durationCount++
+		}
+	}
+}
+
+// WriteTo writes every metric in the Prometheus text exposition format, breakers ordered by name.
+func (pm *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	names := make([]string, 0, len(pm.breakers))
+	for name := range pm.breakers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	header := func(metric, typ, help string) {
+		fmt.Fprintf(cw, "# HELP %s %s\n# TYPE %s %s\n", metric, help, metric, typ)
+	}
+
+	header("circuit_breaker_state", "gauge", "Current state of the circuit breaker, 1 for the active state.")
+	for _, name := range names {
+		for _, st := range states {
+			v := 0
+			if pm.breakers[name].state == st {
+				v = 1
+			}
+			fmt.Fprintf(cw, "circuit_breaker_state{name=%s,state=%s} %d\n", promLabel(name), promLabel(st.String()), v)
+		}
+	}
+
+	header("circuit_breaker_calls_total", "counter", "Requests by outcome.")
+	for _, name := range names {
+		for _, kind := range []EventKind{EventSuccess, EventFailure, EventRejected, EventIgnored} {
+			fmt.Fprintf(cw, "circuit_breaker_calls_total{name=%s,outcome=%s} %d\n", promLabel(name), promLabel(kind.String()), pm.breakers[name].calls[kind])
+		}
+	}
+
+	header("circuit_breaker_call_duration_seconds", "summary", "Duration of the requests that ran.")
+	for _, name := range names {
+		bm := pm.breakers[name]
+		fmt.Fprintf(cw, "circuit_breaker_call_duration_seconds_sum{name=%s} %s\n", promLabel(name), strconv.FormatFloat(bm.durationSum.Seconds(), 'g', -1, 64))
+		fmt.Fprintf(cw, "circuit_breaker_call_duration_seconds_count{name=%s} %d\n", promLabel(name), bm.durationCount)
+	}
+
+	header("circuit_breaker_state_transitions_total", "counter", "State transitions of the circuit breaker.")
+	for _, name := range names {
+		bm := pm.breakers[name]
+		transitions := make([][2]State, 0, len(bm.transitions))
+		for t := range bm.transitions {
+			transitions = append(transitions, t)
+		}
+		sort.Slice(transitions, func(i, j int) bool {
+			if transitions[i][0] != transitions[j][0] {
+				return transitions[i][0] < transitions[j][0]
+			}
+			return transitions[i][1] < transitions[j][1]
+		})
+		for _, t := range transitions {
+			fmt.Fprintf(cw, "circuit_breaker_state_transitions_total{name=%s,from=%s,to=%s} %d\n",
+				promLabel(name), promLabel(t[0].String()), promLabel(t[1].String()), bm.transitions[t])
+		}
+	}
+
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// ServeHTTP writes the metrics as a Prometheus scrape response.
+func (pm *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = pm.WriteTo(w)
+}
+
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func promLabel(v string) string {
+	return `"` + promEscaper.Replace(v) + `"`
+}
+
+// countingWriter keeps the first write error and the number of bytes written.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
@@ -0,0 +1,215 @@
+package circuit_breaker
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fakeRedis is an in-process server speaking enough of the Redis protocol for RedisStore:
+// AUTH, PING, GET, SET [NX] [PX], DEL, INCR, and EVAL of the RedisStore scripts.
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mutex  sync.Mutex
+	data   map[string]string
+	expiry map[string]time.Time
+}
+
+func newFakeRedis(password string) (*fakeRedis, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	fr := &fakeRedis{
+		listener: listener,
+		password: password,
+		data:     make(map[string]string),
+		expiry:   make(map[string]time.Time),
+	}
+	go fr.serve()
+	return fr, nil
+}
+
+func (fr *fakeRedis) Addr() string {
+	return fr.listener.Addr().String()
+}
+
+func (fr *fakeRedis) Close() error {
+	return fr.listener.Close()
+}
+
+func (fr *fakeRedis) serve() {
+	for {
+		conn, err := fr.listener.Accept()
+		if err != nil {
+			return
+		}
+		go fr.handle(conn)
+	}
+}
+
+func (fr *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	authed := fr.password == ""
+	for {
+		reply, err := readRESP(r)
+		if err != nil {
+			return
+		}
+		items, ok := reply.([]any)
+		if !ok || len(items) == 0 {
+			return
+		}
+		args := make([]string, len(items))
+		for i, item := range items {
+			b, _ := item.([]byte)
+			args[i] = string(b)
+		}
+
+		var out string
+		switch {
+		case strings.EqualFold(args[0], "AUTH"):
+			authed = len(args) == 2 && args[1] == fr.password
+			out = "+OK\r\n"
+			if !authed {
+				out = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			out = "-NOAUTH Authentication required.\r\n"
+		default:
+			out = fr.run(args)
+		}
+		if _, err := io.WriteString(conn, out); err != nil {
+			return
+		}
+	}
+}
+
+// run executes one command and returns its encoded reply.
+func (fr *fakeRedis) run(args []string) string {
+	fr.mutex.Lock()
+	defer fr.mutex.Unlock()
+
+	switch cmd := strings.ToUpper(args[0]); {
+	case cmd == "PING":
+		return "+PONG\r\n"
+	case cmd == "GET" && len(args) == 2:
+		value, ok := fr.get(args[1])
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(value)
+	case cmd == "SET" && len(args) >= 3:
+		nx, ttl := false, time.Duration(0)
+		for i := 3; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX":
+				nx = true
+			case "PX":
+				if i+1 == len(args) {
+					return "-ERR syntax error\r\n"
+				}
+				ms, err := strconv.Atoi(args[i+1])
+				if err != nil || ms <= 0 {
+					return "-ERR invalid expire time\r\n"
+				}
+				ttl = time.Duration(ms) * time.Millisecond
+				i++
+			default:
+				return "-ERR syntax error\r\n"
+			}
+		}
+		if _, ok := fr.get(args[1]); ok && nx {
+			return "$-1\r\n"
+		}
+		fr.set(args[1], args[2], ttl)
+		return "+OK\r\n"
+	case cmd == "DEL" && len(args) >= 2:
+		n := 0
+		for _, key := range args[1:] {
+			if _, ok := fr.get(key); ok {
+				delete(fr.data, key)
+				n++
+			}
+		}
+		return integer(n)
+	case cmd == "INCR" && len(args) == 2:
+		n := 0
+		if value, ok := fr.get(args[1]); ok {
+			var err error
+			if n, err = strconv.Atoi(value); err != nil {
+				return "-ERR value is not an integer or out of range\r\n"
+			}
+		}
+		n++
+		fr.data[args[1]] = strconv.Itoa(n)
+		return integer(n)
+	case cmd == "EVAL" && len(args) >= 3:
+		return fr.eval(args[1], args[3:])
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+// eval runs the RedisStore scripts natively; keysAndArgs holds KEYS followed by ARGV.
+func (fr *fakeRedis) eval(script string, keysAndArgs []string) string {
+	switch script {
+	case unlockScript:
+		if value, ok := fr.get(keysAndArgs[0]); !ok || value != keysAndArgs[1] {
+			return integer(0)
+		}
+		delete(fr.data, keysAndArgs[0])
+		return integer(1)
+	case setDataScript:
+		data, fence, lock, token, value := keysAndArgs[0], keysAndArgs[1], keysAndArgs[2], keysAndArgs[3], keysAndArgs[4]
+		if current, ok := fr.get(lock); !ok || current != token {
+			return integer(0)
+		}
+		last, _ := fr.get(fence)
+		n, _ := strconv.Atoi(token)
+		m, _ := strconv.Atoi(last)
+		if n < m {
+			return integer(0)
+		}
+		fr.set(fence, token, 0)
+		fr.set(data, value, 0)
+		return integer(1)
+	default:
+		return "-NOSCRIPT unknown script\r\n"
+	}
+}
+
+func (fr *fakeRedis) get(key string) (string, bool) {
+	if expiry, ok := fr.expiry[key]; ok && !time.Now().Before(expiry) {
+		delete(fr.data, key)
+		delete(fr.expiry, key)
+	}
+	value, ok := fr.data[key]
+	return value, ok
+}
+
+func (fr *fakeRedis) set(key, value string, ttl time.Duration) {
+	fr.data[key] = value
+	delete(fr.expiry, key)
+	if ttl > 0 {
+		fr.expiry[key] = time.Now().Add(ttl)
+	}
+}
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+func integer(n int) string {
+	return fmt.Sprintf(":%d\r\n", n)
+}
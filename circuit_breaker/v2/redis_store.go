@@ -0,0 +1,315 @@
+package circuit_breaker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// unlockScript deletes the lock KEYS[1] only if it still holds the token ARGV[1].
+const unlockScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) else return 0 end`
+
+// setDataScript writes ARGV[2] to KEYS[1] only if the lock KEYS[3] still holds the token ARGV[1]
+// and no write with a higher token reached KEYS[1], recording the token in KEYS[2].
+const setDataScript = `if redis.call("GET", KEYS[3]) ~= ARGV[1] then return 0 end
+if tonumber(ARGV[1]) < tonumber(redis.call("GET", KEYS[2]) or "0") then return 0 end
+redis.call("SET", KEYS[2], ARGV[1])
+redis.call("SET", KEYS[1], ARGV[2])
+return 1`
+
+// RedisStoreSettings configures RedisStore:
+//
+// Addr is the host:port of a server speaking the Redis protocol (RESP).
+//
+// Password, if not empty, is sent with AUTH after connecting.
+//
+// LockTTL is how long a lock lives if its owner never releases it.
+// It must exceed the longest request run under the lock.
+// If LockTTL is less than or equal to 0, it is set to 10 seconds.
+//
+// Timeout bounds each command, connecting included.
+// If Timeout is less than or equal to 0, it is set to 3 seconds.
+//
+// LockKey maps a data key to the lock that guards its writes.
+// If LockKey is nil, the state key of DistributedCircuitBreaker maps to its mutex key.
+type RedisStoreSettings struct {
+	Addr     string
+	Password string
+	LockTTL  time.Duration
+	Timeout  time.Duration
+	LockKey  func(dataKey string) string
+}
+
+// RedisStore is a SharedDataStore on a Redis-protocol server, for breakers shared across hosts.
+//
+// Each name has its own keys, so a lock and data of the same name never collide:
+// the data is in "<name>:data" and the token of its last write in "<name>:written",
+// the lock in "<name>:lock" and its fencing counter in "<name>:fence".
+//
+// Lock takes a fencing token from INCR on the counter and stores it in the lock with
+// SET NX PX, so the lock expires after LockTTL if its owner dies. SetData writes only while
+// the lock guarding the key still holds the owner's token and no higher token has written
+// the key, so an owner whose lock expired cannot overwrite the state of the next owner.
+// It uses one connection, reconnecting after an error, and is safe for concurrent use.
+type RedisStore struct {
+	addr     string
+	password string
+	lockTTL  time.Duration
+	timeout  time.Duration
+	lockKey  func(dataKey string) string
+
+	mutex  sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	tokens map[string]int64
+}
+
+// NewRedisStore returns a RedisStore configured with the given RedisStoreSettings.
+// It connects on first use.
+func NewRedisStore(st RedisStoreSettings) *RedisStore {
+	rs := &RedisStore{
+		addr:     st.Addr,
+		password: st.Password,
+		lockTTL:  st.LockTTL,
+		timeout:  st.Timeout,
+		lockKey:  st.LockKey,
+		tokens:   make(map[string]int64),
+	}
+	if rs.lockTTL <= 0 {
+		rs.lockTTL = defaultLockTTL
+	}
+	if rs.timeout <= 0 {
+		rs.timeout = defaultRedisTimeout
+	}
+	if rs.lockKey == nil {
+		rs.lockKey = defaultLockKey
+	}
+	return rs
+}
+
+const defaultRedisTimeout = 3 * time.Second
+
+func defaultLockKey(dataKey string) string {
+	return strings.Replace(dataKey, "gobreaker:state:", "gobreaker:mutex:", 1)
+}
+
+// These suffixes give the Redis keys of a name; none ends with another, so the keys of
+// different names and roles are distinct.
+const (
+	redisDataSuffix    = ":data"
+	redisWrittenSuffix = ":written"
+	redisLockSuffix    = ":lock"
+	redisFenceSuffix   = ":fence"
+)
+
+// Lock takes the lock name, or returns ErrLocked if another owner holds it.
+func (rs *RedisStore) Lock(name string) error {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	reply, err := rs.do("INCR", name+redisFenceSuffix)
+	if err != nil {
+		return err
+	}
+	token, ok := reply.(int64)
+	if !ok {
+		return fmt.Errorf("redis: unexpected INCR reply %v", reply)
+	}
+
+	reply, err = rs.do("SET", name+redisLockSuffix, strconv.FormatInt(token, 10), "NX", "PX", strconv.FormatInt(rs.lockTTL.Milliseconds(), 10))
+	if err != nil {
+		return err
+	}
+	if reply == nil {
+		return ErrLocked
+	}
+	rs.tokens[name] = token
+	return nil
+}
+
+// Unlock releases the lock name. It returns ErrNotLocked if this RedisStore does not hold it,
+// and ErrLockLost if the lock expired before.
+func (rs *RedisStore) Unlock(name string) error {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	token, ok := rs.tokens[name]
+	if !ok {
+		return ErrNotLocked
+	}
+	delete(rs.tokens, name)
+
+	reply, err := rs.do("EVAL", unlockScript, "1", name+redisLockSuffix, strconv.FormatInt(token, 10))
+	if err != nil {
+		return err
+	}
+	if reply != int64(1) {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// GetData returns the data stored under name, or nil if there is none.
+func (rs *RedisStore) GetData(name string) ([]byte, error) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	reply, err := rs.do("GET", name+redisDataSuffix)
+	if err != nil || reply == nil {
+		return nil, err
+	}
+	data, ok := reply.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected GET reply %v", reply)
+	}
+	return data, nil
+}
+
+// SetData stores data under name. The lock guarding name must be held by this RedisStore;
+// otherwise SetData returns ErrNotLocked, or ErrLockLost if the lock expired since.
+func (rs *RedisStore) SetData(name string, data []byte) error {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	lock := rs.lockKey(name)
+	token, ok := rs.tokens[lock]
+	if !ok {
+		return ErrNotLocked
+	}
+
+	reply, err := rs.do("EVAL", setDataScript, "3", name+redisDataSuffix, name+redisWrittenSuffix, lock+redisLockSuffix,
+		strconv.FormatInt(token, 10), string(data))
+	if err != nil {
+		return err
+	}
+	if reply != int64(1) {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Close closes the connection. The RedisStore reconnects if used again.
+func (rs *RedisStore) Close() error {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	return rs.disconnect()
+}
+
+func (rs *RedisStore) disconnect() error {
+	if rs.conn == nil {
+		return nil
+	}
+	err := rs.conn.Close()
+	rs.conn, rs.reader = nil, nil
+	return err
+}
+
+func (rs *RedisStore) connect() error {
+	conn, err := net.DialTimeout("tcp", rs.addr, rs.timeout)
+	if err != nil {
+		return err
+	}
+	rs.conn, rs.reader = conn, bufio.NewReader(conn)
+	if rs.password != "" {
+		if _, err := rs.roundTrip("AUTH", rs.password); err != nil {
+			rs.disconnect()
+			return err
+		}
+	}
+	return nil
+}
+
+// do runs one command, connecting first if needed. A server error reply is returned as
+// an error and keeps the connection; any other error drops it.
+func (rs *RedisStore) do(args ...string) (any, error) {
+	if rs.conn == nil {
+		if err := rs.connect(); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := rs.roundTrip(args...)
+	var serverErr redisError
+	if err != nil && !errors.As(err, &serverErr) {
+		rs.disconnect()
+	}
+	return reply, err
+}
+
+func (rs *RedisStore) roundTrip(args ...string) (any, error) {
+	if err := rs.conn.SetDeadline(time.Now().Add(rs.timeout)); err != nil {
+		return nil, err
+	}
+	if _, err := rs.conn.Write(encodeRESPCommand(args)); err != nil {
+		return nil, err
+	}
+	return readRESP(rs.reader)
+}
+
+// redisError is an error reply of the server.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+func encodeRESPCommand(args []string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return []byte(b.String())
+}
+
+// readRESP reads one reply: a simple string as string, an integer as int64, a bulk string
+// as []byte, an array as []any, and a null bulk string or array as nil.
+func readRESP(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readRESP(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+}
@@ -0,0 +1,319 @@
+package circuit_breaker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrUnknownAction is returned by Registry.Apply for an action it does not know.
+var ErrUnknownAction = errors.New("unknown action")
+
+// These constants are the actions of Registry.Apply.
+const (
+	ActionForceOpen   = "force-open"
+	ActionForceClosed = "force-closed"
+	ActionDisable     = "disable"
+	ActionRelease     = "release"
+)
+
+// RegistrySettings configures Registry:
+//
+// Template is the Settings of every CircuitBreaker the Registry creates;
+// the Name of each one is its key.
+//
+// IdleTimeout is how long a CircuitBreaker may go without Get, Execute or a manual action before
+// the Registry drops it. Breakers in a manual state are never dropped.
+// If IdleTimeout is less than or equal to 0, breakers are kept until Remove.
+type RegistrySettings struct {
+	Template    Settings
+	IdleTimeout time.Duration
+}
+
+// Registry holds one CircuitBreaker per key, typically a downstream host or endpoint,
+// creating each lazily on first use. It is safe for concurrent use.
+//
+// Callers should go through Get or Execute for every request instead of keeping
+// the returned CircuitBreaker, so that the Registry sees it used.
+type Registry[T any] struct {
+	template    Settings
+	idleTimeout time.Duration
+
+	mutex     sync.Mutex
+	breakers  map[string]*registryEntry[T]
+	lastSweep time.Time
+}
+
+type registryEntry[T any] struct {
+	cb       *CircuitBreaker[T]
+	lastUsed time.Time
+}
+
+// BreakerSnapshot is the state of one CircuitBreaker of a Registry.
+// In JSON the state is its name, e.g. "half-open", in StateName.
+type BreakerSnapshot struct {
+	Key          string    `json:"key"`
+	State        State     `json:"-"`
+	StateName    string    `json:"state"`
+	Forced       bool      `json:"forced"`
+	Counts       Counts    `json:"counts"`
+	BackoffLevel uint32    `json:"backoff_level"`
+	LastUsed     time.Time `json:"last_used"`
+}
+
+// NewRegistry returns an empty Registry configured with the given RegistrySettings.
+func NewRegistry[T any](st RegistrySettings) *Registry[T] {
+	return &Registry[T]{
+		template:    st.Template,
+		idleTimeout: st.IdleTimeout,
+		breakers:    make(map[string]*registryEntry[T]),
+		lastSweep:   time.Now(),
+	}
+}
+
+// Get returns the CircuitBreaker for key, creating it from the template if needed.
+// It also drops idle breakers, at most once per IdleTimeout.
+func (r *Registry[T]) Get(key string) *CircuitBreaker[T] {
+	r.mutex.Lock()
+	now := time.Now()
+	var idle []idleEntry[T]
+	if r.idleTimeout > 0 && now.Sub(r.lastSweep) >= r.idleTimeout {
+		idle = r.idleEntries(now)
+	}
+	cb := r.entry(key, now).cb
+	r.mutex.Unlock()
+
+	r.evict(idle)
+	return cb
+}
+
+// Execute runs req through the CircuitBreaker for key.
+func (r *Registry[T]) Execute(key string, req func() (T, error)) (T, error) {
+	return r.Get(key).Execute(req)
+}
+
+// ExecuteContext runs req through the CircuitBreaker for key, like CircuitBreaker.ExecuteContext.
+func (r *Registry[T]) ExecuteContext(ctx context.Context, key string, req func(ctx context.Context) (T, error)) (T, error) {
+	return r.Get(key).ExecuteContext(ctx, req)
+}
+
+// entry returns the entry for key, creating it if needed, and marks it used at now.
+// r.mutex must be held.
+func (r *Registry[T]) entry(key string, now time.Time) *registryEntry[T] {
+	e := r.breakers[key]
+	if e == nil {
+		st := r.template
+		st.Name = key
+		e = &registryEntry[T]{cb: NewCircuitBreaker[T](st)}
+		r.breakers[key] = e
+	}
+	e.lastUsed = now
+	return e
+}
+
+// Keys returns the keys of all breakers in order.
+func (r *Registry[T]) Keys() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	keys := make([]string, 0, len(r.breakers))
+	for key := range r.breakers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Snapshot returns the state and counts of all breakers, ordered by key.
+func (r *Registry[T]) Snapshot() []BreakerSnapshot {
+	type item struct {
+		key      string
+		cb       *CircuitBreaker[T]
+		lastUsed time.Time
+	}
+	r.mutex.Lock()
+	items := make([]item, 0, len(r.breakers))
+	for key, e := range r.breakers {
+		items = append(items, item{key: key, cb: e.cb, lastUsed: e.lastUsed})
+	}
+	r.mutex.Unlock()
+
+	// Reading the state may change it and call OnStateChange and listeners,
+	// which must be free to use the Registry.
+	snapshots := make([]BreakerSnapshot, 0, len(items))
+	for _, it := range items {
+		state := it.cb.State()
+		snapshots = append(snapshots, BreakerSnapshot{
+			Key:          it.key,
+			State:        state,
+			StateName:    state.String(),
+			Forced:       state.IsManual(),
+			Counts:       it.cb.Counts(),
+			BackoffLevel: it.cb.BackoffLevel(),
+			LastUsed:     it.lastUsed,
+		})
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Key < snapshots[j].Key
+	})
+	return snapshots
+}
+
+// ForceOpen places the CircuitBreaker for key, creating it if needed, into StateForcedOpen.
+func (r *Registry[T]) ForceOpen(key string) {
+	r.setManualState(key, (*CircuitBreaker[T]).ForceOpen)
+}
+
+// ForceClosed places the CircuitBreaker for key, creating it if needed, into StateForcedClosed.
+func (r *Registry[T]) ForceClosed(key string) {
+	r.setManualState(key, (*CircuitBreaker[T]).ForceClosed)
+}
+
+// Disable places the CircuitBreaker for key, creating it if needed, into StateDisabled.
+func (r *Registry[T]) Disable(key string) {
+	r.setManualState(key, (*CircuitBreaker[T]).Disable)
+}
+
+// Release returns the CircuitBreaker for key to automatic mode; see CircuitBreaker.Release.
+// It returns false if there is no such breaker.
+func (r *Registry[T]) Release(key string) bool {
+	r.mutex.Lock()
+	e := r.breakers[key]
+	r.mutex.Unlock()
+
+	if e == nil {
+		return false
+	}
+	e.cb.Release()
+	return true
+}
+
+// setManualState applies set to the CircuitBreaker for key outside r.mutex, so that
+// OnStateChange and listeners may use the Registry. If the breaker was evicted before
+// set took effect, set is applied again to the breaker that replaces it.
+func (r *Registry[T]) setManualState(key string, set func(cb *CircuitBreaker[T])) {
+	for {
+		r.mutex.Lock()
+		cb := r.entry(key, time.Now()).cb
+		r.mutex.Unlock()
+
+		set(cb)
+
+		r.mutex.Lock()
+		e := r.breakers[key]
+		r.mutex.Unlock()
+		if e != nil && e.cb == cb {
+			return
+		}
+	}
+}
+
+// Apply runs one of the actions ActionForceOpen, ActionForceClosed, ActionDisable and
+// ActionRelease on key.
+func (r *Registry[T]) Apply(key, action string) error {
+	switch action {
+	case ActionForceOpen:
+		r.ForceOpen(key)
+	case ActionForceClosed:
+		r.ForceClosed(key)
+	case ActionDisable:
+		r.Disable(key)
+	case ActionRelease:
+		r.Release(key)
+	default:
+		return ErrUnknownAction
+	}
+	return nil
+}
+
+// Remove drops the CircuitBreaker for key. It returns false if there is no such breaker.
+func (r *Registry[T]) Remove(key string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	_, ok := r.breakers[key]
+	delete(r.breakers, key)
+	return ok
+}
+
+// EvictIdle drops the breakers unused for IdleTimeout that are not in a manual state,
+// and returns their keys in order.
+func (r *Registry[T]) EvictIdle() []string {
+	r.mutex.Lock()
+	idle := r.idleEntries(time.Now())
+	r.mutex.Unlock()
+
+	return r.evict(idle)
+}
+
+// idleEntry is an eviction candidate, as last used when it was picked.
+type idleEntry[T any] struct {
+	key      string
+	entry    *registryEntry[T]
+	lastUsed time.Time
+}
+
+// idleEntries returns the entries unused for IdleTimeout. r.mutex must be held.
+func (r *Registry[T]) idleEntries(now time.Time) []idleEntry[T] {
+	r.lastSweep = now
+	if r.idleTimeout <= 0 {
+		return nil
+	}
+
+	var idle []idleEntry[T]
+	for key, e := range r.breakers {
+		if now.Sub(e.lastUsed) >= r.idleTimeout {
+			idle = append(idle, idleEntry[T]{key: key, entry: e, lastUsed: e.lastUsed})
+		}
+	}
+	return idle
+}
+
+// evict drops the idle entries that are not in a manual state and were not used since
+// idleEntries picked them, and returns their keys in order. The states are read outside
+// r.mutex, since reading may call OnStateChange and listeners.
+func (r *Registry[T]) evict(idle []idleEntry[T]) []string {
+	var evicted []string
+	for _, it := range idle {
+		if it.entry.cb.State().IsManual() {
+			continue
+		}
+		r.mutex.Lock()
+		if r.breakers[it.key] == it.entry && it.entry.lastUsed.Equal(it.lastUsed) {
+			delete(r.breakers, it.key)
+			evicted = append(evicted, it.key)
+		}
+		r.mutex.Unlock()
+	}
+	sort.Strings(evicted)
+	return evicted
+}
+
+// ServeHTTP is an admin endpoint. GET writes Snapshot as JSON.
+// POST with the query parameters key and action runs Apply, then writes the Snapshot.
+func (r *Registry[T]) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		key, action := req.URL.Query().Get("key"), req.URL.Query().Get("action")
+		if key == "" {
+			http.Error(w, "missing key", http.StatusBadRequest)
+			return
+		}
+		if err := r.Apply(key, action); err != nil {
+			http.Error(w, err.Error()+": "+action, http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(r.Snapshot())
+}
@@ -0,0 +1,204 @@
+package circuit_breaker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRegistry_Get tests lazy creation from the template and snapshots
+func TestRegistry_Get(t *testing.T) {
+	r := NewRegistry[int](RegistrySettings{Template: Settings{
+		Name:        "ignored",
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	}})
+
+	a := r.Get("host-a")
+	if r.Get("host-a") != a {
+		t.Fatal("Get() created a second breaker for the same key")
+	}
+	if a.Name() != "host-a" {
+		t.Errorf("Name() = %q; expected the key", a.Name())
+	}
+	_, _ = r.Execute("host-b", func() (int, error) { return 0, errTest })
+	_, _ = r.ExecuteContext(context.Background(), "host-a", func(context.Context) (int, error) { return 1, nil })
+
+	if got, want := r.Keys(), []string{"host-a", "host-b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys() = %v; expected %v", got, want)
+	}
+	var states []State
+	var successes []uint32
+	for _, s := range r.Snapshot() {
+		states = append(states, s.State)
+		successes = append(successes, s.Counts.TotalSuccesses)
+	}
+	if want := []State{StateClosed, StateOpen}; !reflect.DeepEqual(states, want) {
+		t.Errorf("snapshot states = %v; expected %v", states, want)
+	}
+	if want := []uint32{1, 0}; !reflect.DeepEqual(successes, want) {
+		t.Errorf("snapshot successes = %v; expected %v", successes, want)
+	}
+	if !r.Remove("host-b") || r.Remove("host-b") {
+		t.Error("Remove() should report the breaker only once")
+	}
+}
+
+// TestRegistry_Force tests the manual states set through the registry and their release
+func TestRegistry_Force(t *testing.T) {
+	r := NewRegistry[int](RegistrySettings{Template: Settings{
+		Timeout:     time.Hour,
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 },
+	}})
+	fail := func() (int, error) { return 0, errTest }
+	succeed := func() (int, error) { return 1, nil }
+
+	r.ForceOpen("a")
+	if _, err := r.Execute("a", succeed); !errors.Is(err, ErrOpenState) {
+		t.Errorf("Execute() on a forced-open breaker error = %v; expected ErrOpenState", err)
+	}
+
+	r.ForceClosed("a")
+	for range 3 {
+		_, _ = r.Execute("a", fail)
+	}
+	if s := r.Snapshot()[0]; s.State != StateForcedClosed || !s.Forced || s.Counts.TotalFailures != 3 {
+		t.Errorf("forced-closed snapshot = %+v; expected forced-closed with 3 failures", s)
+	}
+
+	if !r.Release("a") || r.Release("missing") {
+		t.Fatal("Release() should report only existing breakers")
+	}
+	_, _ = r.Execute("a", fail)
+	if s := r.Snapshot()[0]; s.State != StateOpen || s.Forced {
+		t.Errorf("released snapshot = %+v; expected open and not forced", s)
+	}
+	if err := r.Apply("a", ActionDisable); err != nil || r.Snapshot()[0].State != StateDisabled {
+		t.Errorf("Apply(disable) error = %v, snapshot = %+v; expected disabled", err, r.Snapshot()[0])
+	}
+	if err := r.Apply("a", "explode"); !errors.Is(err, ErrUnknownAction) {
+		t.Errorf("Apply() error = %v; expected ErrUnknownAction", err)
+	}
+}
+
+// TestRegistry_EvictIdle tests that idle breakers are dropped unless forced
+func TestRegistry_EvictIdle(t *testing.T) {
+	r := NewRegistry[int](RegistrySettings{IdleTimeout: 20 * time.Millisecond})
+	r.Get("idle")
+	r.ForceOpen("pinned")
+	time.Sleep(30 * time.Millisecond)
+	r.Get("busy")
+
+	if got := r.Keys(); !reflect.DeepEqual(got, []string{"busy", "pinned"}) {
+		t.Errorf("Keys() after the sweep in Get = %v; expected [busy pinned]", got)
+	}
+	if got := r.EvictIdle(); got != nil {
+		t.Errorf("EvictIdle() = %v; expected nothing before the timeout", got)
+	}
+}
+
+// TestRegistry_ForceKeepsBreakerUsed tests that a manual action counts as a use,
+// so a breaker idle before it is not dropped right after
+func TestRegistry_ForceKeepsBreakerUsed(t *testing.T) {
+	r := NewRegistry[int](RegistrySettings{IdleTimeout: 20 * time.Millisecond})
+	r.Get("a")
+	time.Sleep(30 * time.Millisecond)
+	r.ForceOpen("a")
+	r.Release("a")
+
+	if got := r.EvictIdle(); got != nil {
+		t.Errorf("EvictIdle() = %v; expected nothing right after ForceOpen", got)
+	}
+}
+
+// TestRegistry_StateChangeCallback tests that OnStateChange may call into the registry
+// while Snapshot and the idle sweep read the states
+func TestRegistry_StateChangeCallback(t *testing.T) {
+	var r *Registry[int]
+	r = NewRegistry[int](RegistrySettings{
+		Template: Settings{
+			Timeout:       10 * time.Millisecond,
+			ReadyToTrip:   func(counts Counts) bool { return counts.ConsecutiveFailures >= 1 },
+			OnStateChange: func(name string, from State, to State) { r.Keys() },
+		},
+		IdleTimeout: 20 * time.Millisecond,
+	})
+	fail := func() (int, error) { return 0, errTest }
+
+	// Each call finds the breaker open past its timeout, so reading the state
+	// moves it to half-open and calls OnStateChange.
+	for _, read := range []func(){
+		func() { r.Snapshot() },
+		func() { r.EvictIdle() },
+	} {
+		_, _ = r.Execute("a", fail)
+		time.Sleep(30 * time.Millisecond)
+
+		done := make(chan struct{})
+		go func() {
+			read()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("reading the registry deadlocked on OnStateChange")
+		}
+	}
+}
+
+// TestRegistry_ServeHTTP tests the admin endpoint
+func TestRegistry_ServeHTTP(t *testing.T) {
+	r := NewRegistry[int](RegistrySettings{})
+	r.Get("b")
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		wantCode int
+		wantKeys []string
+		wantJSON string
+	}{
+		{name: "list", method: http.MethodGet, target: "/", wantCode: http.StatusOK, wantKeys: []string{"b"}, wantJSON: `"state":"closed"`},
+		{name: "force open", method: http.MethodPost, target: "/?key=a&action=force-open", wantCode: http.StatusOK, wantKeys: []string{"a", "b"}, wantJSON: `"state":"forced-open"`},
+		{name: "missing key", method: http.MethodPost, target: "/?action=release", wantCode: http.StatusBadRequest},
+		{name: "unknown action", method: http.MethodPost, target: "/?key=a&action=x", wantCode: http.StatusBadRequest},
+		{name: "bad method", method: http.MethodDelete, target: "/", wantCode: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d; expected %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantJSON) {
+				t.Errorf("body = %s; expected it to contain %s", rec.Body.String(), tt.wantJSON)
+			}
+			var snapshots []BreakerSnapshot
+			if err := json.Unmarshal(rec.Body.Bytes(), &snapshots); err != nil {
+				t.Fatal(err)
+			}
+			var keys []string
+			for _, s := range snapshots {
+				keys = append(keys, s.Key)
+			}
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("keys = %v; expected %v", keys, tt.wantKeys)
+			}
+		})
+	}
+	if s := r.Snapshot()[0]; s.Key != "a" || s.State != StateForcedOpen || !s.Forced {
+		t.Errorf("snapshot = %+v; expected a forced open", s)
+	}
+}
@@ -0,0 +1,303 @@
+package circuit_breaker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestStores returns a pair of stores per implementation; the two stores of a pair
+// share data as two processes would.
+func newTestStores(t *testing.T) map[string][2]SharedDataStore {
+	t.Helper()
+
+	memory := NewMemoryStore()
+
+	dir := t.TempDir()
+	file1, err := NewFileStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file2, err := NewFileStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := newFakeRedis("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+	redis1 := NewRedisStore(RedisStoreSettings{Addr: server.Addr(), Password: "secret"})
+	redis2 := NewRedisStore(RedisStoreSettings{Addr: server.Addr(), Password: "secret"})
+	t.Cleanup(func() {
+		redis1.Close()
+		redis2.Close()
+	})
+
+	return map[string][2]SharedDataStore{
+		"memory": {memory, memory},
+		"file":   {file1, file2},
+		"redis":  {redis1, redis2},
+	}
+}
+
+// TestSharedDataStore_Contract tests locking and data storage of every store
+func TestSharedDataStore_Contract(t *testing.T) {
+	const lock, key = "gobreaker:mutex:contract", "gobreaker:state:contract"
+
+	for name, stores := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			a, b := stores[0], stores[1]
+
+			if data, err := a.GetData(key); err != nil || data != nil {
+				t.Errorf("GetData() of a missing key = %q, %v; expected nil, nil", data, err)
+			}
+			if err := a.Unlock(lock); !errors.Is(err, ErrNotLocked) {
+				t.Errorf("Unlock() of a free lock error = %v; expected ErrNotLocked", err)
+			}
+			if err := a.Lock(lock); err != nil {
+				t.Fatalf("Lock() error = %v", err)
+			}
+			if err := b.Lock(lock); !errors.Is(err, ErrLocked) {
+				t.Errorf("Lock() of a held lock error = %v; expected ErrLocked", err)
+			}
+			if err := a.SetData(key, []byte("state")); err != nil {
+				t.Fatalf("SetData() error = %v", err)
+			}
+			if data, err := b.GetData(key); err != nil || string(data) != "state" {
+				t.Errorf("GetData() = %q, %v; expected \"state\", nil", data, err)
+			}
+			if err := a.Unlock(lock); err != nil {
+				t.Fatalf("Unlock() error = %v", err)
+			}
+			if err := b.Lock(lock); err != nil {
+				t.Fatalf("Lock() after Unlock() error = %v", err)
+			}
+			if err := b.Unlock(lock); err != nil {
+				t.Fatalf("Unlock() error = %v", err)
+			}
+		})
+	}
+}
+
+// TestSharedDataStore_SameName tests every store with data stored under the name
+// of the lock guarding it
+func TestSharedDataStore_SameName(t *testing.T) {
+	const name = "foo"
+
+	for store, stores := range newTestStores(t) {
+		t.Run(store, func(t *testing.T) {
+			a, b := stores[0], stores[1]
+
+			if err := a.Lock(name); err != nil {
+				t.Fatalf("Lock() error = %v", err)
+			}
+			if err := a.SetData(name, []byte("state")); err != nil {
+				t.Fatalf("SetData() error = %v", err)
+			}
+			if data, err := b.GetData(name); err != nil || string(data) != "state" {
+				t.Errorf("GetData() = %q, %v; expected \"state\", nil", data, err)
+			}
+			if err := b.Lock(name); !errors.Is(err, ErrLocked) {
+				t.Errorf("Lock() of a held lock error = %v; expected ErrLocked", err)
+			}
+			if err := a.Unlock(name); err != nil {
+				t.Fatalf("Unlock() error = %v", err)
+			}
+			if err := b.Lock(name); err != nil {
+				t.Fatalf("Lock() after Unlock() error = %v", err)
+			}
+			if err := b.SetData(name, []byte("next")); err != nil {
+				t.Errorf("SetData() error = %v", err)
+			}
+			if err := b.Unlock(name); err != nil {
+				t.Errorf("Unlock() error = %v", err)
+			}
+		})
+	}
+}
+
+// TestSharedDataStore_DistributedCircuitBreaker tests that two breakers on separate
+// stores share their state through every store
+func TestSharedDataStore_DistributedCircuitBreaker(t *testing.T) {
+	fail := func() (int, error) { return 0, errTest }
+
+	for name, stores := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			st := Settings{
+				Name:        "stores",
+				ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 2 },
+			}
+			a, err := NewDistributedCircuitBreaker[int](stores[0], st)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := NewDistributedCircuitBreaker[int](stores[1], st)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, _ = a.Execute(fail)
+			_, _ = b.Execute(fail)
+			if state, err := a.State(); err != nil || state != StateOpen {
+				t.Errorf("State() = %v, %v; expected open", state, err)
+			}
+			if _, err := a.Execute(fail); !errors.Is(err, ErrOpenState) {
+				t.Errorf("Execute() error = %v; expected ErrOpenState", err)
+			}
+		})
+	}
+}
+
+// TestRedisStore_Fencing tests that an owner whose lock expired can neither write
+// nor release the lock of the next owner
+func TestRedisStore_Fencing(t *testing.T) {
+	const lock, key = "gobreaker:mutex:fencing", "gobreaker:state:fencing"
+
+	server, err := newFakeRedis("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	stale := NewRedisStore(RedisStoreSettings{Addr: server.Addr(), LockTTL: 20 * time.Millisecond})
+	defer stale.Close()
+	next := NewRedisStore(RedisStoreSettings{Addr: server.Addr()})
+	defer next.Close()
+
+	if err := stale.Lock(lock); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if err := next.Lock(lock); err != nil {
+		t.Fatalf("Lock() after expiry error = %v", err)
+	}
+	if err := next.SetData(key, []byte("next")); err != nil {
+		t.Fatalf("SetData() error = %v", err)
+	}
+
+	if err := stale.SetData(key, []byte("stale")); !errors.Is(err, ErrLockLost) {
+		t.Errorf("SetData() with an expired lock error = %v; expected ErrLockLost", err)
+	}
+	if err := stale.Unlock(lock); !errors.Is(err, ErrLockLost) {
+		t.Errorf("Unlock() of an expired lock error = %v; expected ErrLockLost", err)
+	}
+	if data, err := next.GetData(key); err != nil || string(data) != "next" {
+		t.Errorf("GetData() = %q, %v; expected \"next\", nil", data, err)
+	}
+	if err := next.SetData("gobreaker:state:other", nil); !errors.Is(err, ErrNotLocked) {
+		t.Errorf("SetData() without the lock error = %v; expected ErrNotLocked", err)
+	}
+	if err := next.Unlock(lock); err != nil {
+		t.Errorf("Unlock() error = %v", err)
+	}
+}
+
+// TestRedisStore_Auth tests that a wrong password surfaces the server error
+func TestRedisStore_Auth(t *testing.T) {
+	server, err := newFakeRedis("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	rs := NewRedisStore(RedisStoreSettings{Addr: server.Addr(), Password: "wrong"})
+	defer rs.Close()
+	var serverErr redisError
+	if _, err := rs.GetData("key"); !errors.As(err, &serverErr) {
+		t.Errorf("GetData() error = %v; expected a server error", err)
+	}
+}
+
+// TestFileStore_StaleLock tests that a lock file older than the TTL is taken over
+// and that its former owner can neither write nor release it
+func TestFileStore_StaleLock(t *testing.T) {
+	dir := t.TempDir()
+	crashed, err := NewFileStore(dir, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := NewFileStore(dir, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := crashed.Lock("lock"); err != nil {
+		t.Fatal(err)
+	}
+	if err := next.Lock("lock"); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Lock() of a fresh lock error = %v; expected ErrLocked", err)
+	}
+	old := time.Now().Add(-2 * time.Minute)
+	if err := os.Chtimes(filepath.Join(dir, "lock.lock"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := next.Lock("lock"); err != nil {
+		t.Fatalf("Lock() of a stale lock error = %v", err)
+	}
+	if err := next.SetData("lock", []byte("next")); err != nil {
+		t.Fatalf("SetData() error = %v", err)
+	}
+
+	if err := crashed.SetData("lock", []byte("stale")); !errors.Is(err, ErrLockLost) {
+		t.Errorf("SetData() with a taken-over lock error = %v; expected ErrLockLost", err)
+	}
+	if err := crashed.Unlock("lock"); !errors.Is(err, ErrLockLost) {
+		t.Errorf("Unlock() of a taken-over lock error = %v; expected ErrLockLost", err)
+	}
+	if err := crashed.Lock("lock"); !errors.Is(err, ErrLocked) {
+		t.Errorf("Lock() after Unlock() of a taken-over lock error = %v; expected ErrLocked", err)
+	}
+	if data, err := next.GetData("lock"); err != nil || string(data) != "next" {
+		t.Errorf("GetData() = %q, %v; expected \"next\", nil", data, err)
+	}
+	if err := next.Unlock("lock"); err != nil {
+		t.Errorf("Unlock() error = %v", err)
+	}
+}
+
+// TestFileStore_StaleLockRace tests that of several stores racing to take over
+// the same stale lock, exactly one gets it
+func TestFileStore_StaleLockRace(t *testing.T) {
+	dir := t.TempDir()
+	crashed, err := NewFileStore(dir, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := crashed.Lock("lock"); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Minute)
+	if err := os.Chtimes(filepath.Join(dir, "lock.lock"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	const racers = 16
+	results := make(chan error, racers)
+	start := make(chan struct{})
+	for range racers {
+		fst, err := NewFileStore(dir, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			<-start
+			results <- fst.Lock("lock")
+		}()
+	}
+	close(start)
+
+	won := 0
+	for range racers {
+		if err := <-results; err == nil {
+			won++
+		} else if !errors.Is(err, ErrLocked) {
+			t.Errorf("Lock() error = %v; expected nil or ErrLocked", err)
+		}
+	}
+	if won != 1 {
+		t.Errorf("%d stores took over the stale lock; expected 1", won)
+	}
+}
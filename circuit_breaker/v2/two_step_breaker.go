@@ -1,5 +1,10 @@
 package circuit_breaker
 
+import (
+	"context"
+	"time"
+)
+
 type TwoStepCircuitBreaker[T any] struct {
 	cb *CircuitBreaker[T]
 }
@@ -35,7 +40,27 @@ func (tscb *TwoStepCircuitBreaker[T]) Allow() (done func(success bool), err erro
 		return nil, err
 	}
 
+	start := time.Now()
 	return func(success bool) {
-		tscb.cb.afterRequest(generation, age, success)
+		tscb.cb.afterRequest(generation, age, success, time.Since(start), nil)
+	}, nil
+}
+
+// AllowContext is like Allow but returns ctx.Err() if ctx is already done.
+// The returned callback takes the error of the request and counts it like
+// CircuitBreaker.ExecuteContext does, applying Settings.IsSuccessful and Settings.CancelPolicy.
+func (tscb *TwoStepCircuitBreaker[T]) AllowContext(ctx context.Context) (done func(err error), err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	generation, age, err := tscb.cb.beforeRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	return func(err error) {
+		tscb.cb.afterResult(ctx, generation, age, err, time.Since(start))
 	}, nil
 }
@@ -0,0 +1,596 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/tqhuy-dev/xgen/codebase"
+)
+
+// errUsage marks bad arguments; run prints the command's usage after it.
+var errUsage = errors.New("bad usage")
+
+// lineCodeMax bounds line_code in compact find output, like find_code_destination_in_json.sh.
+const lineCodeMax = 120
+
+// queryFlags are shared by every command that reads an existing cb.json or cb.db.
+type queryFlags struct {
+	db     string
+	format string
+}
+
+func (q *queryFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&q.db, "db", "cb.json", "call graph JSON or SQLite written by 'xgen-codebase index'")
+	fs.StringVar(&q.format, "format", "table", "output format: table or json")
+}
+
+func (q *queryFlags) checkFormat() error {
+	if q.format != "table" && q.format != "json" {
+		return fmt.Errorf("%w: -format must be table or json, got %q", errUsage, q.format)
+	}
+	return nil
+}
+
+func (q *queryFlags) load() (*codebase.CodeBase, error) {
+	if err := q.checkFormat(); err != nil {
+		return nil, err
+	}
+	return codebase.LoadCodeBase(q.db)
+}
+
+// openSQLite opens -db for point queries when it is a SQLite database; it returns nil for JSON,
+// which callers load whole instead.
+func (q *queryFlags) openSQLite() (*codebase.SQLiteCodeBase, error) {
+	if err := q.checkFormat(); err != nil {
+		return nil, err
+	}
+	if !codebase.IsSQLiteFile(q.db) {
+		return nil, nil
+	}
+	return codebase.OpenSQLiteCodeBase(q.db)
+}
+
+// isSQLitePath reports whether an output path asks for SQLite rather than JSON.
+func isSQLitePath(p string) bool {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".db", ".sqlite", ".sqlite3":
+		return true
+	}
+	return false
+}
+
+// parseArgs parses fs allowing flags after positional arguments, and checks the positional count.
+func parseArgs(fs *flag.FlagSet, args []string, want int) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		pos = append(pos, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if want >= 0 && len(pos) != want {
+		return nil, fmt.Errorf("%w: want %d argument(s), got %d", errUsage, want, len(pos))
+	}
+	return pos, nil
+}
+
+type multiFlag []string
+
+func (m *multiFlag) String() string     { return strings.Join(*m, ",") }
+func (m *multiFlag) Set(v string) error { *m = append(*m, v); return nil }
+
+func runIndex(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	var (
+		opt    codebase.BuildOptions
+		out    string
+		ignore multiFlag
+		langs  multiFlag
+		usages bool
+	)
+	fs.StringVar(&opt.RepoRoot, "root", "", "module root (default: nearest directory with go.mod)")
+	fs.StringVar(&out, "o", "cb.json", "output file; .db, .sqlite or .sqlite3 writes SQLite")
+	fs.BoolVar(&usages, "usages", false, "also record identifier usages (SQLite output only)")
+	fs.StringVar(&opt.IndexPath, "index", "", "incremental index sidecar, e.g. cb.index.json (default: full rebuild)")
+	fs.Var(&ignore, "ignore", "file or directory to skip (repeatable)")
+	fs.IntVar(&opt.Concurrency, "j", 0, "files parsed at once (default: GOMAXPROCS)")
+	fs.BoolVar(&opt.ModuleCache, "modcache", false, "resolve calls into required modules from the module cache")
+	fs.Var(&langs, "lang", "also index this language: python, typescript or all (repeatable, comma-separated)")
+	fs.StringVar(&opt.Revision, "rev", "", "index the sources of this git revision, e.g. HEAD~20 or a tag (default: working tree)")
+	paths, err := parseArgs(fs, args, -1)
+	if err != nil {
+		return err
+	}
+	opt.Ignore = ignore
+	if usages && !isSQLitePath(out) {
+		return fmt.Errorf("%w: -usages needs a SQLite -o file", errUsage)
+	}
+	if opt.Frontends, err = frontendsByName(langs); err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		paths = []string{"."}
+		if opt.RepoRoot != "" {
+			paths = []string{opt.RepoRoot}
+		}
+	}
+	cb, err := codebase.BuildCodebaseForFiles(paths, opt)
+	if err != nil {
+		return err
+	}
+	if isSQLitePath(out) {
+		var sites []codebase.NameUsageSite
+		if usages {
+			rep, err := codebase.BuildUsageReport(paths, opt)
+			if err != nil {
+				return err
+			}
+			sites = rep.Usages
+		}
+		err = codebase.SaveToSQLiteFileCodeBase(cb, sites, out)
+	} else {
+		err = codebase.SaveToJSONFileCodeBase(cb, out)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "wrote %s: %d symbols, %d calls, %d implements\n", out, len(cb.Symbols), len(cb.Calls), len(cb.Implements))
+	return nil
+}
+
+func runSymbols(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	var (
+		q    queryFlags
+		kind string
+		glob string
+	)
+	q.register(fs)
+	fs.StringVar(&kind, "kind", "", "only this kind: function, method, class or constant")
+	fs.StringVar(&glob, "file", "", "only files matching this glob, e.g. 'utilities/*.go'")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if glob != "" {
+		if _, err := path.Match(glob, ""); err != nil {
+			return fmt.Errorf("%w: -file: %v", errUsage, err)
+		}
+	}
+	syms := []codebase.CodeSymbol{}
+	keep := func(s codebase.CodeSymbol) error {
+		if kind != "" && s.Kind != kind {
+			return nil
+		}
+		if glob != "" {
+			if ok, _ := path.Match(glob, s.FilePath); !ok {
+				return nil
+			}
+		}
+		syms = append(syms, s)
+		return nil
+	}
+	store, err := q.openSQLite()
+	if err != nil {
+		return err
+	}
+	if store != nil {
+		defer store.Close()
+		if err := store.EachSymbol(keep); err != nil {
+			return err
+		}
+	} else {
+		cb, err := q.load()
+		if err != nil {
+			return err
+		}
+		for _, s := range cb.Symbols {
+			keep(s)
+		}
+	}
+	if q.format == "json" {
+		return writeJSON(stdout, syms)
+	}
+	t := newTable(stdout, "ADDRESS", "KIND", "LINES")
+	for _, s := range syms {
+		t.row(s.Address, s.Kind, lineRange(s))
+	}
+	return t.flush()
+}
+
+func runCallers(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	return runEdges(fs, args, stdout, (*codebase.CallGraph).Callers, (*codebase.SQLiteCodeBase).Callers)
+}
+
+func runCallees(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	return runEdges(fs, args, stdout, (*codebase.CallGraph).Callees, (*codebase.SQLiteCodeBase).Callees)
+}
+
+func runEdges(
+	fs *flag.FlagSet, args []string, stdout io.Writer,
+	edges func(g *codebase.CallGraph, addr string) []codebase.CallEdge,
+	stored func(s *codebase.SQLiteCodeBase, addr string) ([]codebase.CallEdge, error),
+) error {
+	var q queryFlags
+	q.register(fs)
+	pos, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	store, err := q.openSQLite()
+	if err != nil {
+		return err
+	}
+	if store != nil {
+		defer store.Close()
+		sym, err := store.Symbol(pos[0])
+		if err != nil {
+			return err
+		}
+		if sym == nil {
+			return fmt.Errorf("%w: no symbol at %s", errNotFound, pos[0])
+		}
+		es, err := stored(store, pos[0])
+		if err != nil {
+			return err
+		}
+		return writeEdges(stdout, q.format, es, false)
+	}
+	cb, err := q.load()
+	if err != nil {
+		return err
+	}
+	g := codebase.NewCallGraph(cb)
+	if g.Symbol(pos[0]) == nil {
+		return fmt.Errorf("%w: no symbol at %s", errNotFound, pos[0])
+	}
+	return writeEdges(stdout, q.format, edges(g, pos[0]), false)
+}
+
+func runPath(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	var q queryFlags
+	q.register(fs)
+	pos, err := parseArgs(fs, args, 2)
+	if err != nil {
+		return err
+	}
+	cb, err := q.load()
+	if err != nil {
+		return err
+	}
+	g := codebase.NewCallGraph(cb)
+	for _, addr := range pos {
+		if g.Symbol(addr) == nil {
+			return fmt.Errorf("%w: no symbol at %s", errNotFound, addr)
+		}
+	}
+	edges := g.ShortestPath(pos[0], pos[1])
+	if edges == nil {
+		return fmt.Errorf("%w: no call path from %s to %s", errNotFound, pos[0], pos[1])
+	}
+	return writeEdges(stdout, q.format, edges, true)
+}
+
+// foundSymbol is the compact find result, the same fields the jq script printed by default.
+type foundSymbol struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	FilePath  string `json:"file_path"`
+	LineStart int    `json:"line_start"`
+	LineEnd   int    `json:"line_end"`
+	Address   string `json:"address"`
+	LineCode  string `json:"line_code"`
+}
+
+func runFind(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	var (
+		q    queryFlags
+		full bool
+	)
+	q.register(fs)
+	fs.BoolVar(&full, "full", false, "print whole symbols (json only), not the compact fields")
+	pos, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	name := pos[0]
+	syms := []codebase.CodeSymbol{}
+	store, err := q.openSQLite()
+	if err != nil {
+		return err
+	}
+	if store != nil {
+		defer store.Close()
+		if syms, err = store.FindSymbols(name); err != nil {
+			return err
+		}
+	} else {
+		cb, err := q.load()
+		if err != nil {
+			return err
+		}
+		for _, s := range cb.Symbols {
+			_, qual, _ := strings.Cut(s.Address, "::")
+			if s.Name == name || qual == name || s.Address == name {
+				syms = append(syms, s)
+			}
+		}
+	}
+	switch {
+	case q.format == "json" && full:
+		err = writeJSON(stdout, syms)
+	case q.format == "json":
+		found := make([]foundSymbol, 0, len(syms))
+		for _, s := range syms {
+			found = append(found, foundSymbol{
+				Name: s.Name, Kind: s.Kind, FilePath: s.FilePath,
+				LineStart: s.LineStart, LineEnd: s.LineEnd, Address: s.Address,
+				LineCode: truncate(s.LineCode, lineCodeMax),
+			})
+		}
+		err = writeJSON(stdout, found)
+	default:
+		t := newTable(stdout, "ADDRESS", "KIND", "LINES", "CODE")
+		for _, s := range syms {
+			t.row(s.Address, s.Kind, lineRange(s), truncate(firstLine(s.LineCode), lineCodeMax))
+		}
+		err = t.flush()
+	}
+	if err != nil {
+		return err
+	}
+	if len(syms) == 0 {
+		return fmt.Errorf("%w: no symbol named %s", errNotFound, name)
+	}
+	return nil
+}
+
+func runExport(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	var (
+		db, format, out string
+		pkgs            multiFlag
+		opt             codebase.ExportOptions
+	)
+	fs.StringVar(&db, "db", "cb.json", "call graph JSON or SQLite written by 'xgen-codebase index'")
+	fs.StringVar(&format, "format", "dot", "output format: dot, mermaid or graphml")
+	fs.StringVar(&out, "o", "", "output file (default: stdout)")
+	fs.Var(&pkgs, "pkg", "only this package directory, e.g. utilities (repeatable)")
+	fs.StringVar(&opt.FileGlob, "file", "", "only files matching this glob, e.g. 'codebase/*.go'")
+	fs.BoolVar(&opt.ClusterByPackage, "cluster", false, "group nodes by package")
+	fs.StringVar(&opt.Root, "root", "", "only symbols around this address")
+	fs.IntVar(&opt.Depth, "depth", 0, "with -root, the maximum number of calls away (default: unbounded)")
+	fs.StringVar(&opt.Direction, "direction", codebase.ExportBoth, "with -root, follow callees, callers or both")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	opt.Packages = pkgs
+	write := map[string]func(io.Writer, *codebase.CodeBase, codebase.ExportOptions) error{
+		"dot":     codebase.WriteDOT,
+		"mermaid": codebase.WriteMermaid,
+		"graphml": codebase.WriteGraphML,
+	}[format]
+	if write == nil {
+		return fmt.Errorf("%w: -format must be dot, mermaid or graphml, got %q", errUsage, format)
+	}
+	cb, err := codebase.LoadCodeBase(db)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		return write(stdout, cb, opt)
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	if err := write(f, cb, opt); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// frontendsByName picks frontends from codebase.DefaultFrontends by Name; "all" selects every one.
+func frontendsByName(names []string) ([]codebase.Frontend, error) {
+	var out []codebase.Frontend
+	for _, arg := range names {
+		for _, name := range strings.Split(arg, ",") {
+			found := false
+			for _, fe := range codebase.DefaultFrontends() {
+				if name == "all" || fe.Name() == name {
+					out = append(out, fe)
+					found = true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("%w: unknown -lang %q", errUsage, name)
+			}
+		}
+	}
+	return out, nil
+}
+
+func runDeadCode(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	var (
+		q          queryFlags
+		src        string
+		roots      multiFlag
+		keep       multiFlag
+		failOnDead bool
+	)
+	opt := codebase.DefaultDeadCodeOptions()
+	q.register(fs)
+	fs.StringVar(&src, "src", "", "source directory to resolve type and constant references (default: calls only)")
+	fs.BoolVar(&opt.Main, "main", opt.Main, "treat func main as a root")
+	fs.BoolVar(&opt.Init, "init", opt.Init, "treat func init as a root")
+	fs.BoolVar(&opt.Exported, "exported", opt.Exported, "treat exported API as roots")
+	fs.BoolVar(&opt.Tests, "tests", opt.Tests, "treat Test/Benchmark/Example/Fuzz functions as roots")
+	fs.Var(&roots, "root", "extra root address (repeatable)")
+	fs.Var(&keep, "keep", "method name kept when its type is reachable, e.g. String (repeatable)")
+	fs.BoolVar(&failOnDead, "fail", false, "exit 1 if anything is unreachable")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	opt.Roots, opt.MethodNames = roots, keep
+	cb, err := q.load()
+	if err != nil {
+		return err
+	}
+	if src != "" {
+		if opt.Usages, err = codebase.BuildUsageReport([]string{src}, codebase.BuildOptions{}); err != nil {
+			return err
+		}
+	}
+	rep, err := codebase.FindDeadCode(cb, opt)
+	if err != nil {
+		return err
+	}
+	if q.format == "json" {
+		err = writeJSON(stdout, rep)
+	} else {
+		t := newTable(stdout, "ADDRESS", "KIND", "LINES")
+		for _, d := range rep.Unreachable {
+			t.row(d.Address, d.Kind, lineRange(codebase.CodeSymbol{LineStart: d.LineStart, LineEnd: d.LineEnd}))
+		}
+		err = t.flush()
+	}
+	if err != nil {
+		return err
+	}
+	if failOnDead && len(rep.Unreachable) > 0 {
+		return fmt.Errorf("%d unreachable symbol(s)", len(rep.Unreachable))
+	}
+	return nil
+}
+
+func runMetrics(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	var (
+		q        queryFlags
+		opt      codebase.MetricsOptions
+		byFile   bool
+		failOver bool
+	)
+	q.register(fs)
+	fs.StringVar(&opt.SortBy, "sort", codebase.MetricCyclomatic, "rank by cyclomatic, nesting, statements, parameters, returns or lines")
+	fs.IntVar(&opt.Top, "top", 0, "only the first n symbols or files (default: all)")
+	fs.BoolVar(&byFile, "files", false, "rank files by the total of the metric instead of symbols (table only)")
+	fs.IntVar(&opt.Limits.Cyclomatic, "max-cyclomatic", 0, "limit on cyclomatic complexity (0: none)")
+	fs.IntVar(&opt.Limits.MaxNesting, "max-nesting", 0, "limit on nesting depth (0: none)")
+	fs.IntVar(&opt.Limits.Statements, "max-statements", 0, "limit on statements (0: none)")
+	fs.IntVar(&opt.Limits.Parameters, "max-params", 0, "limit on parameters (0: none)")
+	fs.IntVar(&opt.Limits.ReturnPaths, "max-returns", 0, "limit on return paths (0: none)")
+	fs.IntVar(&opt.Limits.Lines, "max-lines", 0, "limit on lines (0: none)")
+	fs.BoolVar(&failOver, "fail", false, "exit 1 if any limit is exceeded")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	cb, err := q.load()
+	if err != nil {
+		return err
+	}
+	rep, err := codebase.BuildMetricsReport(cb, opt)
+	if err != nil {
+		return fmt.Errorf("%w: -sort: %v", errUsage, err)
+	}
+	if q.format == "json" {
+		err = writeJSON(stdout, rep)
+	} else {
+		err = writeMetrics(stdout, rep, byFile)
+	}
+	if err != nil {
+		return err
+	}
+	if failOver && len(rep.Violations) > 0 {
+		return fmt.Errorf("%d metric limit(s) exceeded", len(rep.Violations))
+	}
+	return nil
+}
+
+func runDiff(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	var (
+		db, format, announceFile string
+		announce                 multiFlag
+		failOnBreaking           bool
+	)
+	fs.StringVar(&db, "db", "cb.json", "call graph JSON or SQLite of the new revision")
+	fs.StringVar(&format, "format", "table", "output format: table, json or markdown")
+	fs.Var(&announce, "announce", "address whose breaking change is expected (repeatable)")
+	fs.StringVar(&announceFile, "announce-file", "", "file listing expected breaking changes, one address per line, # comments")
+	fs.BoolVar(&failOnBreaking, "fail", false, "exit 1 on unannounced breaking changes")
+	pos, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if format != "table" && format != "json" && format != "markdown" {
+		return fmt.Errorf("%w: -format must be table, json or markdown, got %q", errUsage, format)
+	}
+	if announceFile != "" {
+		b, err := os.ReadFile(announceFile)
+		if err != nil {
+			return err
+		}
+		for _, line := range strings.Split(string(b), "\n") {
+			if line, _, _ = strings.Cut(line, "#"); strings.TrimSpace(line) != "" {
+				announce = append(announce, strings.TrimSpace(line))
+			}
+		}
+	}
+	base, err := codebase.LoadCodeBase(pos[0])
+	if err != nil {
+		return err
+	}
+	cur, err := codebase.LoadCodeBase(db)
+	if err != nil {
+		return err
+	}
+	d := codebase.DiffCodeBases(base, cur, codebase.DiffOptions{Announced: announce})
+	switch format {
+	case "json":
+		err = writeJSON(stdout, d)
+	case "markdown":
+		err = codebase.WriteDiffMarkdown(stdout, d)
+	default:
+		t := newTable(stdout, "CHANGE", "ADDRESS", "DETAIL")
+		for _, b := range d.Breaking {
+			change := "breaking " + b.Change
+			if b.Announced {
+				change += " (announced)"
+			}
+			t.row(change, b.Address, b.Detail)
+		}
+		for _, c := range d.SignatureChanges {
+			if !c.Breaking {
+				t.row("signature", c.Address, c.Old+" -> "+c.New)
+			}
+		}
+		for _, s := range d.Added {
+			t.row("added", s.Address, s.Kind)
+		}
+		for _, s := range d.Removed {
+			t.row("removed", s.Address, s.Kind)
+		}
+		for _, m := range d.Moved {
+			t.row("moved", m.From, "-> "+m.To)
+		}
+		for _, p := range d.AddedCalls {
+			t.row("call added", p.Caller, "-> "+p.Callee)
+		}
+		for _, p := range d.RemovedCalls {
+			t.row("call removed", p.Caller, "-> "+p.Callee)
+		}
+		err = t.flush()
+	}
+	if err != nil {
+		return err
+	}
+	if n := len(d.Unannounced()); failOnBreaking && n > 0 {
+		return fmt.Errorf("%d unannounced breaking change(s)", n)
+	}
+	return nil
+}
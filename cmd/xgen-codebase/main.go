@@ -0,0 +1,114 @@
+// Command xgen-codebase builds and queries the cb.json call graph produced by package codebase.
+// With -o cb.db (or .sqlite, .sqlite3) index writes a SQLite database instead; every -db flag
+// accepts either format, and symbols, callers, callees and find answer from SQLite without
+// loading the whole graph. With -rev, index reads the sources of a git revision without checking
+// it out, so indexing a release tag into base.json and diffing against it shows how the graph
+// changed since.
+//
+// Usage:
+//
+//	xgen-codebase index   [-root dir] [-o cb.json|cb.db] [-usages] [-index cb.index.json] [-ignore path]...
+//	                      [-j n] [-modcache] [-lang python,typescript|all] [-rev revision] [path...]
+//	xgen-codebase symbols [-db cb.json] [-format table|json] [-kind kind] [-file glob]
+//	xgen-codebase callers [-db cb.json] [-format table|json] <address>
+//	xgen-codebase callees [-db cb.json] [-format table|json] <address>
+//	xgen-codebase path    [-db cb.json] [-format table|json] <from> <to>
+//	xgen-codebase find    [-db cb.json] [-format table|json] [-full] <name>
+//	xgen-codebase export  [-db cb.json] [-format dot|mermaid|graphml] [-o file] [-pkg dir]... [-file glob]
+//	                      [-cluster] [-root address [-depth n] [-direction both|callees|callers]]
+//	xgen-codebase deadcode [-db cb.json] [-format table|json] [-src dir] [-exported=false] [-root address]...
+//	                      [-keep method]... [-fail]
+//	xgen-codebase metrics [-db cb.json] [-format table|json] [-sort metric] [-top n] [-files]
+//	                      [-max-cyclomatic n] [-max-nesting n] [-max-statements n] [-max-params n]
+//	                      [-max-returns n] [-max-lines n] [-fail]
+//	xgen-codebase diff    [-db cb.json] [-format table|json|markdown] [-announce address]...
+//	                      [-announce-file file] [-fail] <base.json>
+//
+// Addresses have the form relPosixPath::QualifiedName, e.g. utilities/slice.go::Map.
+// Exit status is 0 on success, 1 when the query fails or finds nothing, and 2 on bad usage.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// errNotFound marks queries that ran fine but matched nothing; it maps to exit status 1
+// without printing usage.
+var errNotFound = errors.New("not found")
+
+type command struct {
+	name  string
+	args  string
+	short string
+	run   func(fs *flag.FlagSet, args []string, stdout io.Writer) error
+}
+
+var commands = []command{
+	{"index", "[path...]", "parse .go (and -lang) files and write the call graph JSON or SQLite", runIndex},
+	{"symbols", "", "list symbols, optionally filtered by kind and file glob", runSymbols},
+	{"callers", "<address>", "list call edges into a symbol", runCallers},
+	{"callees", "<address>", "list call edges out of a symbol", runCallees},
+	{"path", "<from> <to>", "print the shortest call path between two symbols", runPath},
+	{"find", "<name>", "find symbols by name or qualified name", runFind},
+	{"export", "", "write the call graph as Graphviz DOT, Mermaid or GraphML", runExport},
+	{"deadcode", "", "list symbols unreachable from main, init, exported API, tests and -root", runDeadCode},
+	{"metrics", "", "rank functions and files by complexity and size, and check limits", runMetrics},
+	{"diff", "<base.json>", "report API and call changes from a base call graph to -db", runDiff},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage(stderr)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+		fs.SetOutput(stderr)
+		fs.Usage = func() {
+			fmt.Fprintf(stderr, "usage: xgen-codebase %s [flags] %s\n", c.name, c.args)
+			fs.PrintDefaults()
+		}
+		err := c.run(fs, args[1:], stdout)
+		switch {
+		case err == nil:
+			return 0
+		case errors.Is(err, flag.ErrHelp):
+			return 0
+		case errors.Is(err, errUsage):
+			fmt.Fprintf(stderr, "xgen-codebase %s: %v\n", c.name, err)
+			fs.Usage()
+			return 2
+		default:
+			fmt.Fprintf(stderr, "xgen-codebase %s: %v\n", c.name, err)
+			return 1
+		}
+	}
+	fmt.Fprintf(stderr, "xgen-codebase: unknown command %q\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: xgen-codebase <command> [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", c.name, c.short)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "run 'xgen-codebase <command> -h' for the flags of one command.")
+}
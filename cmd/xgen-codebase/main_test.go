@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tqhuy-dev/xgen/codebase"
+)
+
+func writeTestDB(t *testing.T) string {
+	t.Helper()
+	iface := "s.go::Store"
+	cb := &codebase.CodeBase{
+		Symbols: []codebase.CodeSymbol{
+			{Name: "A", Kind: "function", Address: "a.go::A", FilePath: "a.go", LineStart: 1, LineEnd: 4, LineCode: "func A() {\n\tB()\n}",
+				Metrics: &codebase.SymbolMetrics{Cyclomatic: 1, Statements: 1, ReturnPaths: 1, Lines: 4}},
+			{Name: "B", Kind: "function", Address: "a.go::B", FilePath: "a.go", LineStart: 6, LineEnd: 9},
+			{Name: "Get", Kind: "method", Address: "m.go::Mem.Get", FilePath: "m.go", LineStart: 3, LineEnd: 3},
+			{Name: "LIMIT", Kind: "constant", Address: "m.go::LIMIT", FilePath: "m.go", LineStart: 1, LineEnd: 1},
+		},
+		Calls: []codebase.CallEdge{
+			{CallerAddress: "a.go::A", CalleeAddress: "a.go::B", CallLine: 2},
+			{CallerAddress: "a.go::B", CalleeAddress: "m.go::Mem.Get", CallLine: 7, ViaInterface: &iface},
+		},
+	}
+	p := filepath.Join(t.TempDir(), "cb.json")
+	if err := codebase.SaveToJSONFileCodeBase(cb, p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// TestRun covers each query subcommand's output and exit status against a small cb.json and
+// the same graph stored as SQLite.
+func TestRun(t *testing.T) {
+	db := writeTestDB(t)
+	sqliteDB := filepath.Join(t.TempDir(), "cb.db")
+	dbCB, err := codebase.LoadFromJSONFileCodeBase(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := codebase.SaveToSQLiteFileCodeBase(dbCB, nil, sqliteDB); err != nil {
+		t.Fatal(err)
+	}
+	base := filepath.Join(t.TempDir(), "base.json")
+	baseCB, err := codebase.LoadFromJSONFileCodeBase(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseCB.Symbols = append(baseCB.Symbols, codebase.CodeSymbol{Name: "Old", Kind: "function", Address: "a.go::Old", FilePath: "a.go"})
+	if err := codebase.SaveToJSONFileCodeBase(baseCB, base); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		want     []string // substrings of stdout
+		wantJSON int      // if >= 0 with -format json, expected array length
+	}{
+		{name: "no command", args: nil, wantCode: 2, wantJSON: -1},
+		{name: "unknown command", args: []string{"nope"}, wantCode: 2, wantJSON: -1},
+		{name: "symbols by kind", args: []string{"symbols", "-kind", "method"}, want: []string{"m.go::Mem.Get"}, wantJSON: -1},
+		{name: "symbols by glob json", args: []string{"symbols", "-file", "a.*", "-format", "json"}, wantJSON: 2},
+		{name: "callers", args: []string{"callers", "a.go::B"}, want: []string{"a.go::A", "2"}, wantJSON: -1},
+		{name: "callees with trailing flag", args: []string{"callees", "a.go::B", "-format", "json"}, want: []string{`"via_interface": "s.go::Store"`}, wantJSON: 1},
+		{name: "callers of unknown", args: []string{"callers", "a.go::Z"}, wantCode: 1, wantJSON: -1},
+		{name: "callers missing address", args: []string{"callers"}, wantCode: 2, wantJSON: -1},
+		{name: "path", args: []string{"path", "a.go::A", "m.go::Mem.Get"}, want: []string{"STEP", "a.go::B", "s.go::Store"}, wantJSON: -1},
+		{name: "path json", args: []string{"path", "-format", "json", "a.go::A", "m.go::Mem.Get"}, wantJSON: 2},
+		{name: "no path", args: []string{"path", "m.go::Mem.Get", "a.go::A"}, wantCode: 1, wantJSON: -1},
+		{name: "find qualified", args: []string{"find", "Mem.Get"}, want: []string{"m.go::Mem.Get"}, wantJSON: -1},
+		{name: "find compact code", args: []string{"find", "A"}, want: []string{"func A() {"}, wantJSON: -1},
+		{name: "find missing", args: []string{"find", "-format", "json", "Nope"}, wantCode: 1, wantJSON: 0},
+		{name: "bad format", args: []string{"symbols", "-format", "xml"}, wantCode: 2, wantJSON: -1},
+		{name: "export mermaid", args: []string{"export", "-format", "mermaid", "-root", "a.go::B", "-direction", "callees"}, want: []string{"flowchart LR", "n0 -.-> n1"}, wantJSON: -1},
+		{name: "deadcode defaults", args: []string{"deadcode", "-fail"}, want: []string{"ADDRESS"}, wantJSON: -1},
+		{name: "deadcode from a root", args: []string{"deadcode", "-exported=false", "-root", "a.go::A", "-fail"}, wantCode: 1, want: []string{"m.go::LIMIT"}, wantJSON: -1},
+		{name: "diff unchanged", args: []string{"diff", "-fail", db}, want: []string{"CHANGE"}, wantJSON: -1},
+		{name: "diff unannounced removal", args: []string{"diff", "-fail", base}, wantCode: 1, want: []string{"breaking removed", "a.go::Old"}, wantJSON: -1},
+		{name: "diff announced markdown", args: []string{"diff", "-fail", "-announce", "a.go::Old", "-format", "markdown", base}, want: []string{"1 breaking (0 unannounced)"}, wantJSON: -1},
+		{name: "metrics", args: []string{"metrics", "-max-lines", "5", "-fail"}, want: []string{"a.go::A", "CYCLO"}, wantJSON: -1},
+		{name: "metrics over limit", args: []string{"metrics", "-files", "-max-lines", "3", "-fail"}, wantCode: 1, want: []string{"OVER LIMIT", "lines"}, wantJSON: -1},
+		{name: "metrics bad sort", args: []string{"metrics", "-sort", "size"}, wantCode: 2, wantJSON: -1},
+		{name: "export bad root", args: []string{"export", "-root", "a.go::Z"}, wantCode: 1, wantJSON: -1},
+	}
+
+	for _, store := range []string{db, sqliteDB} {
+		for _, tt := range tests {
+			t.Run(filepath.Base(store)+"/"+tt.name, func(t *testing.T) {
+				args := tt.args
+				if len(args) > 0 && args[0] != "nope" {
+					args = append([]string{args[0], "-db", store}, args[1:]...)
+				}
+				var stdout, stderr bytes.Buffer
+
+				code := run(args, &stdout, &stderr)
+
+				if code != tt.wantCode {
+					t.Fatalf("exit %d, want %d; stderr=%s", code, tt.wantCode, stderr.String())
+				}
+				for _, w := range tt.want {
+					if !strings.Contains(stdout.String(), w) {
+						t.Errorf("stdout missing %q:\n%s", w, stdout.String())
+					}
+				}
+				if tt.wantJSON >= 0 {
+					var got []json.RawMessage
+					if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+						t.Fatalf("stdout is not a JSON array: %v\n%s", err, stdout.String())
+					}
+					if len(got) != tt.wantJSON {
+						t.Errorf("got %d JSON items, want %d:\n%s", len(got), tt.wantJSON, stdout.String())
+					}
+				}
+			})
+		}
+	}
+}
+
+// TestRunIndex checks that index writes a cb.json or cb.db the query commands can read back.
+func TestRunIndex(t *testing.T) {
+	tests := []struct {
+		name     string
+		out      string
+		flags    []string
+		wantCode int
+	}{
+		{name: "json", out: "cb.json"},
+		{name: "sqlite with usages", out: "cb.db", flags: []string{"-usages"}},
+		{name: "usages need sqlite", out: "cb.json", flags: []string{"-usages"}, wantCode: 2},
+		{name: "git revision", out: "cb.json", flags: []string{"-rev", "HEAD"}},
+		{name: "unknown git revision", out: "cb.json", flags: []string{"-rev", "no-such-rev"}, wantCode: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), tt.out)
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"index", "-root", "../..", "-o", out}, tt.flags...)
+
+			code := run(append(args, "../../codebase/testdata/iface"), &stdout, &stderr)
+
+			if code != tt.wantCode {
+				t.Fatalf("index exit %d, want %d; stderr=%s", code, tt.wantCode, stderr.String())
+			}
+			if code != 0 {
+				return
+			}
+			stdout.Reset()
+			if code := run([]string{"callers", "-db", out, "codebase/testdata/iface/mem.go::MemStore.Get"}, &stdout, &stderr); code != 0 {
+				t.Fatalf("callers exit %d; stderr=%s", code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), "codebase/testdata/iface/store.go::Lookup") {
+				t.Errorf("Lookup does not dispatch to MemStore.Get:\n%s", stdout.String())
+			}
+		})
+	}
+}
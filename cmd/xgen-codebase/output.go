@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/tqhuy-dev/xgen/codebase"
+)
+
+func writeJSON(w io.Writer, v any) error {
+	b, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+// table writes tab-aligned columns with a header row.
+type table struct {
+	tw *tabwriter.Writer
+}
+
+func newTable(w io.Writer, header ...string) *table {
+	t := &table{tw: tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)}
+	t.row(header...)
+	return t
+}
+
+func (t *table) row(cols ...string) {
+	fmt.Fprintln(t.tw, strings.Join(cols, "\t"))
+}
+
+func (t *table) flush() error {
+	return t.tw.Flush()
+}
+
+// writeEdges prints call edges; steps adds a 1-based STEP column for call paths.
+func writeEdges(w io.Writer, format string, edges []codebase.CallEdge, steps bool) error {
+	if format == "json" {
+		return writeJSON(w, edges)
+	}
+	header := []string{"CALLER", "CALLEE", "LINE", "VIA"}
+	if steps {
+		header = append([]string{"STEP"}, header...)
+	}
+	t := newTable(w, header...)
+	for i, e := range edges {
+		via := "-"
+		if e.ViaInterface != nil {
+			via = *e.ViaInterface
+		}
+		cols := []string{e.CallerAddress, e.CalleeAddress, strconv.Itoa(e.CallLine), via}
+		if steps {
+			cols = append([]string{strconv.Itoa(i + 1)}, cols...)
+		}
+		t.row(cols...)
+	}
+	return t.flush()
+}
+
+// writeMetrics prints the ranked symbols (or files) and then any limit violations.
+func writeMetrics(w io.Writer, rep *codebase.MetricsReport, byFile bool) error {
+	cols := func(m codebase.SymbolMetrics) []string {
+		return []string{strconv.Itoa(m.Cyclomatic), strconv.Itoa(m.MaxNesting), strconv.Itoa(m.Statements),
+			strconv.Itoa(m.Parameters), strconv.Itoa(m.ReturnPaths), strconv.Itoa(m.Lines)}
+	}
+	metrics := []string{"CYCLO", "NEST", "STMTS", "PARAMS", "RETURNS", "LINES"}
+	var t *table
+	if byFile {
+		t = newTable(w, append([]string{"FILE", "SYMBOLS"}, metrics...)...)
+		for _, f := range rep.Files {
+			t.row(append([]string{f.FilePath, strconv.Itoa(f.Symbols)}, cols(f.Total)...)...)
+		}
+	} else {
+		t = newTable(w, append([]string{"ADDRESS"}, metrics...)...)
+		for _, s := range rep.Symbols {
+			t.row(append([]string{s.Address}, cols(s.SymbolMetrics)...)...)
+		}
+	}
+	if err := t.flush(); err != nil {
+		return err
+	}
+	if len(rep.Violations) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w)
+	t = newTable(w, "OVER LIMIT", "METRIC", "VALUE", "LIMIT")
+	for _, v := range rep.Violations {
+		t.row(v.Address, v.Metric, strconv.Itoa(v.Value), strconv.Itoa(v.Limit))
+	}
+	return t.flush()
+}
+
+func lineRange(s codebase.CodeSymbol) string {
+	if s.LineEnd <= s.LineStart {
+		return strconv.Itoa(s.LineStart)
+	}
+	return strconv.Itoa(s.LineStart) + "-" + strconv.Itoa(s.LineEnd)
+}
+
+// truncate shortens s to at most max runes, marking the cut with an ellipsis.
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max]) + "…"
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(line)
+}
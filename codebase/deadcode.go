@@ -0,0 +1,202 @@
+package codebase
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DeadCodeOptions chooses the roots of a dead-code analysis.
+//
+// Reachability follows call edges, from a method to its receiver "class", and, when Usages is
+// set, from the symbol enclosing each usage site to the symbol it resolves to. Without Usages,
+// types and constants are only reached through methods or as roots.
+type DeadCodeOptions struct {
+	// Main makes every func main a root.
+	Main bool
+	// Init makes every func init a root.
+	Init bool
+	// Exported makes exported functions, classes and constants, and exported methods of exported
+	// types, roots. Leave it off for a library to find API that nothing (not even tests) exercises.
+	Exported bool
+	// Tests makes Test*, Benchmark*, Example* and Fuzz* functions in _test.go files roots.
+	Tests bool
+	// Roots are extra root addresses; an address not in the CodeBase is an error.
+	Roots []string
+	// MethodNames keeps methods with these names once their receiver "class" is reachable, for
+	// methods only called through interfaces outside the repo (e.g. String, Error, Len).
+	MethodNames []string
+	// Usages, if set, adds reference edges from BuildUsageReport for the same files.
+	Usages *RepoUsageReport
+}
+
+// DefaultDeadCodeOptions returns options with main, init, exported API and tests as roots.
+func DefaultDeadCodeOptions() DeadCodeOptions {
+	return DeadCodeOptions{Main: true, Init: true, Exported: true, Tests: true}
+}
+
+// DeadSymbol is one symbol no root reaches.
+type DeadSymbol struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	FilePath  string `json:"file_path"`
+	LineStart int    `json:"line_start"`
+	LineEnd   int    `json:"line_end"`
+	Address   string `json:"address"`
+}
+
+// DeadCodeReport lists the roots used and every unreachable symbol, ordered by address.
+type DeadCodeReport struct {
+	Roots       []string     `json:"roots"`
+	Reachable   int          `json:"reachable"`
+	Unreachable []DeadSymbol `json:"unreachable"`
+}
+
+// FindDeadCode reports the symbols of cb that cannot be reached from the roots in opt.
+func FindDeadCode(cb *CodeBase, opt DeadCodeOptions) (*DeadCodeReport, error) {
+	syms := map[string]*CodeSymbol{}
+	methodsOf := map[string][]string{} // class address -> addresses of methods named in MethodNames
+	adj := map[string][]string{}
+	for i := range cb.Symbols {
+		s := &cb.Symbols[i]
+		syms[s.Address] = s
+		if s.Kind == "method" && s.ReceiverAddress != nil {
+			adj[s.Address] = append(adj[s.Address], *s.ReceiverAddress)
+			if containsStr(opt.MethodNames, s.Name) {
+				methodsOf[*s.ReceiverAddress] = append(methodsOf[*s.ReceiverAddress], s.Address)
+			}
+		}
+	}
+	for _, e := range cb.Calls {
+		adj[e.CallerAddress] = append(adj[e.CallerAddress], e.CalleeAddress)
+	}
+
+	roots := map[string]bool{}
+	for _, addr := range opt.Roots {
+		if syms[addr] == nil {
+			return nil, fmt.Errorf("dead code root %s is not a symbol", addr)
+		}
+		roots[addr] = true
+	}
+	for _, s := range cb.Symbols {
+		if isDeadCodeRoot(s, opt) {
+			roots[s.Address] = true
+		}
+	}
+	if opt.Usages != nil {
+		for _, addr := range addReferenceEdges(cb.Symbols, opt.Usages.Usages, adj) {
+			if syms[addr] != nil {
+				roots[addr] = true
+			}
+		}
+	}
+
+	rep := &DeadCodeReport{Roots: []string{}, Unreachable: []DeadSymbol{}}
+	seen := map[string]bool{}
+	var queue []string
+	for addr := range roots {
+		rep.Roots = append(rep.Roots, addr)
+		seen[addr] = true
+		queue = append(queue, addr)
+	}
+	sort.Strings(rep.Roots)
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, nexts := range [][]string{adj[cur], methodsOf[cur]} {
+			for _, next := range nexts {
+				if !seen[next] {
+					seen[next] = true
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+
+	for _, s := range cb.Symbols {
+		if seen[s.Address] {
+			rep.Reachable++
+			continue
+		}
+		rep.Unreachable = append(rep.Unreachable, DeadSymbol{
+			Name: s.Name, Kind: s.Kind, FilePath: s.FilePath,
+			LineStart: s.LineStart, LineEnd: s.LineEnd, Address: s.Address,
+		})
+	}
+	sort.Slice(rep.Unreachable, func(i, j int) bool { return rep.Unreachable[i].Address < rep.Unreachable[j].Address })
+	return rep, nil
+}
+
+func isDeadCodeRoot(s CodeSymbol, opt DeadCodeOptions) bool {
+	isFunc := s.Kind == "function"
+	switch {
+	case opt.Main && isFunc && s.Name == "main":
+		return true
+	case opt.Init && isFunc && s.Name == "init":
+		return true
+	case opt.Tests && isFunc && strings.HasSuffix(s.FilePath, "_test.go") && isTestFuncName(s.Name):
+		return true
+	case opt.Exported && isExportedName(s.Name):
+		return s.Kind != "method" || s.ReceiverType == nil || isExportedName(*s.ReceiverType)
+	}
+	return false
+}
+
+// isTestFuncName matches the names go test runs: TestX, BenchmarkX, ExampleX, FuzzX, where the
+// suffix is empty or does not start with a lower-case letter.
+func isTestFuncName(name string) bool {
+	for _, prefix := range []string{"Test", "Benchmark", "Example", "Fuzz"} {
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		if rest == "" {
+			return true
+		}
+		if r := []rune(rest)[0]; !unicode.IsLower(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func isExportedName(name string) bool {
+	for _, r := range name {
+		return unicode.IsUpper(r)
+	}
+	return false
+}
+
+// addReferenceEdges adds an edge from the innermost symbol whose lines enclose each resolved
+// usage to the symbol it names. Usages outside every symbol (package-level var initializers)
+// run at package init, so their targets are returned as extra roots.
+func addReferenceEdges(syms []CodeSymbol, usages []NameUsageSite, adj map[string][]string) []string {
+	byFile := map[string][]*CodeSymbol{}
+	for i := range syms {
+		byFile[syms[i].FilePath] = append(byFile[syms[i].FilePath], &syms[i])
+	}
+	var roots []string
+	for _, u := range usages {
+		if u.ResolvedAddress == nil || u.Kind == UsageImport {
+			continue
+		}
+		var enclosing *CodeSymbol
+		for _, s := range byFile[u.FilePath] {
+			if u.Line < s.LineStart || u.Line > s.LineEnd {
+				continue
+			}
+			if enclosing == nil || s.LineEnd-s.LineStart < enclosing.LineEnd-enclosing.LineStart {
+				enclosing = s
+			}
+		}
+		if enclosing == nil {
+			roots = append(roots, *u.ResolvedAddress)
+			continue
+		}
+		if enclosing.Address != *u.ResolvedAddress {
+			adj[enclosing.Address] = append(adj[enclosing.Address], *u.ResolvedAddress)
+		}
+	}
+	return roots
+}
@@ -0,0 +1,156 @@
+package codebase
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestFindDeadCode checks each kind of root, reference edges from usages, and kept method names
+// against a small module built from source.
+func TestFindDeadCode(t *testing.T) {
+	root := t.TempDir()
+	writeTestModule(t, root, map[string]string{
+		"go.mod": "module example.com/d\n\ngo 1.25\n",
+		"main.go": `package main
+
+const MAX_N = 3
+const MIN_N = 1
+
+type T struct{}
+
+func (t T) String() string { return "t" }
+
+func (t T) unusedMethod() {}
+
+func main() {
+	used(T{})
+}
+
+func used(t T) int { return MAX_N }
+
+func unused() {}
+
+var table = helperForVar()
+
+func helperForVar() int { return 0 }
+`,
+		"lib/lib.go":      "package lib\n\nfunc Exported() {}\n\nfunc internalHelper() {}\n",
+		"lib/lib_test.go": "package lib\n\nimport \"testing\"\n\nfunc TestExported(t *testing.T) { Exported() }\n\nfunc testHelper() {}\n",
+	})
+	opt := BuildOptions{RepoRoot: root}
+	cb, err := BuildCodebaseForFiles([]string{root}, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usages, err := BuildUsageReport([]string{root}, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opt  DeadCodeOptions
+		want []string
+	}{
+		{
+			name: "main and tests, calls only",
+			opt:  DeadCodeOptions{Main: true, Tests: true},
+			want: []string{
+				"lib/lib.go::internalHelper", "lib/lib_test.go::testHelper",
+				"main.go::MAX_N", "main.go::MIN_N", "main.go::T", "main.go::T.String", "main.go::T.unusedMethod",
+				"main.go::helperForVar", "main.go::unused",
+			},
+		},
+		{
+			name: "main and tests with usages and kept methods",
+			opt:  DeadCodeOptions{Main: true, Tests: true, Usages: usages, MethodNames: []string{"String"}},
+			want: []string{
+				"lib/lib.go::internalHelper", "lib/lib_test.go::testHelper",
+				"main.go::MIN_N", "main.go::T.unusedMethod", "main.go::unused",
+			},
+		},
+		{
+			name: "defaults with usages and an extra root",
+			opt: func() DeadCodeOptions {
+				o := DefaultDeadCodeOptions()
+				o.Usages, o.Roots = usages, []string{"main.go::unused"}
+				return o
+			}(),
+			want: []string{"lib/lib.go::internalHelper", "lib/lib_test.go::testHelper", "main.go::T.unusedMethod"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rep, err := FindDeadCode(cb, tt.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, d := range rep.Unreachable {
+				got = append(got, d.Address)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unreachable = %v\nwant          %v", got, tt.want)
+			}
+			if rep.Reachable+len(rep.Unreachable) != len(cb.Symbols) {
+				t.Errorf("reachable %d + unreachable %d != %d symbols", rep.Reachable, len(rep.Unreachable), len(cb.Symbols))
+			}
+		})
+	}
+
+	if _, err := FindDeadCode(cb, DeadCodeOptions{Roots: []string{"main.go::nope"}}); err == nil {
+		t.Error("expected an error for an unknown root")
+	}
+}
+
+// TestFindDeadCodeClosures checks that calls made only inside function literals keep their
+// callees reachable.
+func TestFindDeadCodeClosures(t *testing.T) {
+	root := t.TempDir()
+	writeTestModule(t, root, map[string]string{
+		"go.mod": "module example.com/d\n\ngo 1.25\n",
+		"main.go": `package main
+
+type Counter struct{}
+
+func (c *Counter) Done() {}
+
+func main() {
+	c := &Counter{}
+	defer func() {
+		c.Done()
+		release()
+	}()
+	go func() { spawned() }()
+	f := func(n int) { viaVar(n) }
+	f(1)
+}
+
+func release() {}
+
+func spawned() {}
+
+func viaVar(n int) {}
+
+func unused() {}
+`,
+	})
+	cb, err := BuildCodebaseForFiles([]string{root}, BuildOptions{RepoRoot: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep, err := FindDeadCode(cb, DeadCodeOptions{Main: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, d := range rep.Unreachable {
+		got = append(got, d.Address)
+	}
+	if want := []string{"main.go::unused"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unreachable = %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,352 @@
+package codebase
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Breaking change kinds recorded in BreakingChange.Change.
+const (
+	ChangeRemoved   = "removed"
+	ChangeSignature = "signature"
+)
+
+// DiffOptions tunes DiffCodeBases.
+type DiffOptions struct {
+	// Announced lists addresses whose breaking changes are expected, e.g. from a changelog or
+	// the PR description. Either the old or the new address of a symbol matches.
+	Announced []string
+}
+
+// APIDiff is the change report between two CodeBase snapshots. Every list is ordered by
+// address, and calls by caller then callee.
+type APIDiff struct {
+	Added            []DiffSymbol      `json:"added"`
+	Removed          []DiffSymbol      `json:"removed"`
+	Moved            []MovedSymbol     `json:"moved"`
+	SignatureChanges []SignatureChange `json:"signature_changes"`
+	AddedCalls       []CallPair        `json:"added_calls"`
+	RemovedCalls     []CallPair        `json:"removed_calls"`
+	Breaking         []BreakingChange  `json:"breaking"`
+}
+
+// DiffSymbol is a symbol present in only one snapshot.
+type DiffSymbol struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Address  string `json:"address"`
+	Exported bool   `json:"exported"`
+}
+
+// MovedSymbol is a symbol that changed file within its package; its qualified name and kind
+// are unchanged.
+type MovedSymbol struct {
+	Kind string `json:"kind"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// SignatureChange is a function or method whose parameters, results or type parameters
+// changed. Breaking is set for exported symbols when a type changed, not just a name.
+type SignatureChange struct {
+	Address  string `json:"address"`
+	Old      string `json:"old"`
+	New      string `json:"new"`
+	Exported bool   `json:"exported"`
+	Breaking bool   `json:"breaking"`
+}
+
+// CallPair is a caller/callee pair, regardless of how many call sites link them.
+type CallPair struct {
+	Caller string `json:"caller"`
+	Callee string `json:"callee"`
+}
+
+// BreakingChange is a removal or incompatible signature change of exported Go API.
+type BreakingChange struct {
+	Address   string `json:"address"`
+	Change    string `json:"change"`
+	Detail    string `json:"detail"`
+	Announced bool   `json:"announced"`
+}
+
+// Unannounced returns the breaking changes not listed in DiffOptions.Announced.
+func (d *APIDiff) Unannounced() []BreakingChange {
+	out := []BreakingChange{}
+	for _, b := range d.Breaking {
+		if !b.Announced {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// DiffCodeBases compares an old and a new snapshot of the same repository.
+//
+// A symbol that disappears from one file and appears in another of the same package under the
+// same qualified name and kind is moved, not removed and added; call edges are compared after
+// mapping moved addresses, so a move alone adds or removes no calls. Exported API follows Go's
+// rules: exported names (and, for methods, exported receivers) in .go files outside _test.go
+// files and internal/ packages. Signatures are only compared when both snapshots record them:
+// a snapshot written before parameters were indexed has none, which is not a change.
+func DiffCodeBases(old, cur *CodeBase, opt DiffOptions) *APIDiff {
+	d := &APIDiff{
+		Added: []DiffSymbol{}, Removed: []DiffSymbol{}, Moved: []MovedSymbol{},
+		SignatureChanges: []SignatureChange{},
+		AddedCalls:       []CallPair{}, RemovedCalls: []CallPair{},
+		Breaking: []BreakingChange{},
+	}
+	oldSyms, curSyms := symbolsByAddress(old), symbolsByAddress(cur)
+
+	renamed := map[string]string{} // old address -> new address, for symbols present in both
+	var removed, added []*CodeSymbol
+	for addr, s := range oldSyms {
+		if curSyms[addr] != nil {
+			renamed[addr] = addr
+		} else {
+			removed = append(removed, s)
+		}
+	}
+	for addr, s := range curSyms {
+		if oldSyms[addr] == nil {
+			added = append(added, s)
+		}
+	}
+	moveKey := func(s *CodeSymbol) string {
+		_, qual, _ := strings.Cut(s.Address, "::")
+		return path.Dir(s.FilePath) + "\x00" + s.Kind + "\x00" + qual
+	}
+	addedByKey := map[string]*CodeSymbol{}
+	for _, s := range added {
+		addedByKey[moveKey(s)] = s
+	}
+	movedTo := map[string]bool{}
+	for _, s := range removed {
+		if to := addedByKey[moveKey(s)]; to != nil && !movedTo[to.Address] {
+			movedTo[to.Address] = true
+			renamed[s.Address] = to.Address
+			d.Moved = append(d.Moved, MovedSymbol{Kind: s.Kind, From: s.Address, To: to.Address})
+			continue
+		}
+		d.Removed = append(d.Removed, diffSymbol(s))
+	}
+	for _, s := range added {
+		if !movedTo[s.Address] {
+			d.Added = append(d.Added, diffSymbol(s))
+		}
+	}
+
+	announced := map[string]bool{}
+	for _, a := range opt.Announced {
+		announced[a] = true
+	}
+	for _, r := range d.Removed {
+		if r.Exported {
+			d.Breaking = append(d.Breaking, BreakingChange{
+				Address: r.Address, Change: ChangeRemoved, Detail: r.Kind + " removed", Announced: announced[r.Address],
+			})
+		}
+	}
+	for from, to := range renamed {
+		o, n := oldSyms[from], curSyms[to]
+		if o.Kind != n.Kind || (n.Kind != "function" && n.Kind != "method") {
+			continue
+		}
+		if o.Parameters == nil || n.Parameters == nil {
+			continue // a snapshot from before signatures were recorded
+		}
+		oldSig, newSig := renderSignature(o, true), renderSignature(n, true)
+		if oldSig == newSig {
+			continue
+		}
+		exported := isExportedAPI(n)
+		typesChanged := renderSignature(o, false) != renderSignature(n, false)
+		d.SignatureChanges = append(d.SignatureChanges, SignatureChange{
+			Address: to, Old: oldSig, New: newSig, Exported: exported, Breaking: exported && typesChanged,
+		})
+		if exported && typesChanged {
+			d.Breaking = append(d.Breaking, BreakingChange{
+				Address: to, Change: ChangeSignature, Detail: oldSig + " -> " + newSig, Announced: announced[to] || announced[from],
+			})
+		}
+	}
+
+	oldCalls := map[CallPair]bool{}
+	for _, e := range old.Calls {
+		p := CallPair{Caller: e.CallerAddress, Callee: e.CalleeAddress}
+		if to, ok := renamed[p.Caller]; ok {
+			p.Caller = to
+		}
+		if to, ok := renamed[p.Callee]; ok {
+			p.Callee = to
+		}
+		oldCalls[p] = true
+	}
+	curCalls := map[CallPair]bool{}
+	for _, e := range cur.Calls {
+		curCalls[CallPair{Caller: e.CallerAddress, Callee: e.CalleeAddress}] = true
+	}
+	for p := range curCalls {
+		if !oldCalls[p] {
+			d.AddedCalls = append(d.AddedCalls, p)
+		}
+	}
+	for p := range oldCalls {
+		if !curCalls[p] {
+			d.RemovedCalls = append(d.RemovedCalls, p)
+		}
+	}
+
+	sortDiffSymbols(d.Added)
+	sortDiffSymbols(d.Removed)
+	sort.Slice(d.Moved, func(i, j int) bool { return d.Moved[i].From < d.Moved[j].From })
+	sort.Slice(d.SignatureChanges, func(i, j int) bool { return d.SignatureChanges[i].Address < d.SignatureChanges[j].Address })
+	sortCallPairs(d.AddedCalls)
+	sortCallPairs(d.RemovedCalls)
+	sort.Slice(d.Breaking, func(i, j int) bool {
+		if d.Breaking[i].Address != d.Breaking[j].Address {
+			return d.Breaking[i].Address < d.Breaking[j].Address
+		}
+		return d.Breaking[i].Change < d.Breaking[j].Change
+	})
+	return d
+}
+
+func symbolsByAddress(cb *CodeBase) map[string]*CodeSymbol {
+	out := make(map[string]*CodeSymbol, len(cb.Symbols))
+	for i := range cb.Symbols {
+		out[cb.Symbols[i].Address] = &cb.Symbols[i]
+	}
+	return out
+}
+
+func diffSymbol(s *CodeSymbol) DiffSymbol {
+	return DiffSymbol{Name: s.Name, Kind: s.Kind, Address: s.Address, Exported: isExportedAPI(s)}
+}
+
+// isExportedAPI reports whether s is part of a Go package's importable API.
+func isExportedAPI(s *CodeSymbol) bool {
+	if !strings.HasSuffix(s.FilePath, ".go") || strings.HasSuffix(s.FilePath, "_test.go") {
+		return false
+	}
+	for _, elem := range strings.Split(path.Dir(s.FilePath), "/") {
+		if elem == "internal" {
+			return false
+		}
+	}
+	if !isExportedName(s.Name) {
+		return false
+	}
+	return s.Kind != "method" || s.ReceiverType == nil || isExportedName(*s.ReceiverType)
+}
+
+// renderSignature prints "[T any](a int, b ...string) (int, error)"; without names only the
+// types are kept, which is what callers compile against.
+func renderSignature(s *CodeSymbol, names bool) string {
+	var b strings.Builder
+	if len(s.TypeParameters) > 0 {
+		var tps []string
+		for _, tp := range s.TypeParameters {
+			t := tp.Constraint
+			if names {
+				t = strings.TrimSpace(tp.Name + " " + t)
+			}
+			tps = append(tps, t)
+		}
+		b.WriteString("[" + strings.Join(tps, ", ") + "]")
+	}
+	var params []string
+	for _, p := range s.Parameters {
+		t := ""
+		if p.Annotation != nil {
+			t = *p.Annotation
+		}
+		if names {
+			t = strings.TrimSpace(p.Name + " " + t)
+		}
+		params = append(params, t)
+	}
+	b.WriteString("(" + strings.Join(params, ", ") + ")")
+	if s.ReturnAnnotation != nil {
+		b.WriteString(" " + *s.ReturnAnnotation)
+	}
+	return b.String()
+}
+
+func sortDiffSymbols(s []DiffSymbol) {
+	sort.Slice(s, func(i, j int) bool { return s[i].Address < s[j].Address })
+}
+
+func sortCallPairs(p []CallPair) {
+	sort.Slice(p, func(i, j int) bool {
+		if p[i].Caller != p[j].Caller {
+			return p[i].Caller < p[j].Caller
+		}
+		return p[i].Callee < p[j].Callee
+	})
+}
+
+// WriteDiffMarkdown renders d as a Markdown comment for a pull request: breaking changes
+// first, then signature changes, symbol changes and a collapsed list of call changes.
+func WriteDiffMarkdown(w io.Writer, d *APIDiff) error {
+	var b strings.Builder
+	b.WriteString("## API changes\n\n")
+	unannounced := len(d.Unannounced())
+	fmt.Fprintf(&b, "%d added, %d removed, %d moved, %d signature change(s), %d breaking (%d unannounced), calls +%d/-%d\n",
+		len(d.Added), len(d.Removed), len(d.Moved), len(d.SignatureChanges), len(d.Breaking), unannounced,
+		len(d.AddedCalls), len(d.RemovedCalls))
+
+	if len(d.Breaking) > 0 {
+		b.WriteString("\n### Breaking changes\n\n| Address | Change | Detail | Announced |\n|---|---|---|---|\n")
+		for _, c := range d.Breaking {
+			ann := "no"
+			if c.Announced {
+				ann = "yes"
+			}
+			fmt.Fprintf(&b, "| `%s` | %s | %s | %s |\n", c.Address, c.Change, markdownCell(c.Detail), ann)
+		}
+	}
+	if len(d.SignatureChanges) > 0 {
+		b.WriteString("\n### Signature changes\n\n")
+		for _, c := range d.SignatureChanges {
+			fmt.Fprintf(&b, "- `%s`: `%s` → `%s`\n", c.Address, c.Old, c.New)
+		}
+	}
+	for _, sec := range []struct {
+		title string
+		syms  []DiffSymbol
+	}{{"Added", d.Added}, {"Removed", d.Removed}} {
+		if len(sec.syms) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n", sec.title)
+		for _, s := range sec.syms {
+			fmt.Fprintf(&b, "- %s `%s`\n", s.Kind, s.Address)
+		}
+	}
+	if len(d.Moved) > 0 {
+		b.WriteString("\n### Moved\n\n")
+		for _, m := range d.Moved {
+			fmt.Fprintf(&b, "- %s `%s` → `%s`\n", m.Kind, m.From, m.To)
+		}
+	}
+	if len(d.AddedCalls)+len(d.RemovedCalls) > 0 {
+		b.WriteString("\n<details><summary>Call changes</summary>\n\n")
+		for _, p := range d.AddedCalls {
+			fmt.Fprintf(&b, "- + `%s` → `%s`\n", p.Caller, p.Callee)
+		}
+		for _, p := range d.RemovedCalls {
+			fmt.Fprintf(&b, "- − `%s` → `%s`\n", p.Caller, p.Callee)
+		}
+		b.WriteString("\n</details>\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func markdownCell(s string) string {
+	return "`" + strings.ReplaceAll(s, "|", "\\|") + "`"
+}
@@ -0,0 +1,122 @@
+package codebase
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestDiffCodeBases builds two snapshots of a small module and checks every section of the
+// report, including announced and unannounced breaking changes.
+func TestDiffCodeBases(t *testing.T) {
+	build := func(files map[string]string) *CodeBase {
+		root := t.TempDir()
+		files["go.mod"] = "module example.com/d\n\ngo 1.25\n"
+		writeTestModule(t, root, files)
+		cb, err := BuildCodebaseForFiles([]string{root}, BuildOptions{RepoRoot: root})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cb
+	}
+	old := build(map[string]string{
+		"lib/a.go": `package lib
+
+type T struct{}
+
+func (T) M() {}
+
+func Exported(a int) string { return "" }
+
+func Keep(x int) { helper() }
+
+func helper() {}
+
+func Gone() {}
+`,
+		"lib/b.go":        "package lib\n\nfunc Mover() { helper() }\n",
+		"internal/x/x.go": "package x\n\nfunc Hidden(a int) {}\n",
+	})
+	cur := build(map[string]string{
+		"lib/a.go": `package lib
+
+type T struct{}
+
+func (T) M() {}
+
+func Exported(a, b int) string { return "" }
+
+func Keep(y int) { NewFunc() }
+
+func helper() {}
+`,
+		"lib/c.go":        "package lib\n\nfunc Mover() { helper() }\n\nfunc NewFunc() {}\n",
+		"internal/x/x.go": "package x\n\nfunc Hidden(a string) {}\n",
+	})
+
+	d := DiffCodeBases(old, cur, DiffOptions{Announced: []string{"lib/a.go::Gone"}})
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{name: "added", got: d.Added, want: []DiffSymbol{{Name: "NewFunc", Kind: "function", Address: "lib/c.go::NewFunc", Exported: true}}},
+		{name: "removed", got: d.Removed, want: []DiffSymbol{{Name: "Gone", Kind: "function", Address: "lib/a.go::Gone", Exported: true}}},
+		{name: "moved", got: d.Moved, want: []MovedSymbol{{Kind: "function", From: "lib/b.go::Mover", To: "lib/c.go::Mover"}}},
+		{
+			name: "signature changes",
+			got:  d.SignatureChanges,
+			want: []SignatureChange{
+				{Address: "internal/x/x.go::Hidden", Old: "(a int)", New: "(a string)"},
+				{Address: "lib/a.go::Exported", Old: "(a int) string", New: "(a int, b int) string", Exported: true, Breaking: true},
+				{Address: "lib/a.go::Keep", Old: "(x int)", New: "(y int)", Exported: true},
+			},
+		},
+		{name: "added calls", got: d.AddedCalls, want: []CallPair{{Caller: "lib/a.go::Keep", Callee: "lib/c.go::NewFunc"}}},
+		{name: "removed calls", got: d.RemovedCalls, want: []CallPair{{Caller: "lib/a.go::Keep", Callee: "lib/a.go::helper"}}},
+		{
+			name: "breaking",
+			got:  d.Breaking,
+			want: []BreakingChange{
+				{Address: "lib/a.go::Exported", Change: ChangeSignature, Detail: "(a int) string -> (a int, b int) string"},
+				{Address: "lib/a.go::Gone", Change: ChangeRemoved, Detail: "function removed", Announced: true},
+			},
+		},
+		{name: "unannounced", got: len(d.Unannounced()), want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", tt.got, tt.want)
+			}
+		})
+	}
+
+	var md bytes.Buffer
+	if err := WriteDiffMarkdown(&md, d); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"## API changes", "2 breaking (1 unannounced)", "| `lib/a.go::Gone` | removed |", "`lib/b.go::Mover` → `lib/c.go::Mover`"} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown missing %q:\n%s", want, md.String())
+		}
+	}
+
+	t.Run("old snapshot without signatures", func(t *testing.T) {
+		legacy := &CodeBase{Symbols: append([]CodeSymbol{}, old.Symbols...), Calls: old.Calls}
+		for i := range legacy.Symbols {
+			legacy.Symbols[i].Parameters, legacy.Symbols[i].ReturnAnnotation = nil, nil
+		}
+		d := DiffCodeBases(legacy, cur, DiffOptions{})
+		if len(d.SignatureChanges) != 0 {
+			t.Errorf("signature changes = %+v; want none", d.SignatureChanges)
+		}
+		want := []BreakingChange{{Address: "lib/a.go::Gone", Change: ChangeRemoved, Detail: "function removed"}}
+		if !reflect.DeepEqual(d.Breaking, want) {
+			t.Errorf("breaking = %+v; want %+v", d.Breaking, want)
+		}
+	})
+}
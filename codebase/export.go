@@ -0,0 +1,358 @@
+package codebase
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Directions for ExportOptions.Direction.
+const (
+	ExportBoth    = "both"
+	ExportCallees = "callees"
+	ExportCallers = "callers"
+)
+
+// ExportOptions selects the part of the call graph written by WriteDOT, WriteMermaid and WriteGraphML.
+//
+// A call edge is kept when both ends pass Packages and FileGlob; nodes are the ends of the kept
+// edges. With Root set, only nodes within Depth calls of Root (following Direction) remain.
+type ExportOptions struct {
+	// Packages are posix package directories relative to the repo root (e.g. "utilities"); empty keeps all.
+	Packages []string
+	// FileGlob is a path.Match pattern on the symbol's file path (e.g. "codebase/*.go"); empty keeps all.
+	FileGlob string
+	// ClusterByPackage groups nodes by package directory (DOT clusters, Mermaid subgraphs, nested GraphML graphs).
+	ClusterByPackage bool
+	// Root is a symbol address to center the graph on; empty exports everything selected.
+	Root string
+	// Depth bounds the number of calls from Root; zero or negative means unbounded.
+	Depth int
+	// Direction is ExportCallees, ExportCallers or ExportBoth (default) when walking from Root.
+	Direction string
+}
+
+type exportNode struct {
+	addr  string
+	label string // qualified name
+	pkg   string
+	kind  string
+}
+
+// exportEdge merges every call site between two symbols.
+type exportEdge struct {
+	from, to string
+	lines    []int
+	via      *string // interface address when all merged calls are dispatch candidates
+}
+
+type exportGraph struct {
+	nodes []exportNode
+	edges []exportEdge
+}
+
+// packageOfAddress returns the posix package directory of an address ("." for the repo root).
+func packageOfAddress(addr string) string {
+	rel, _, _ := strings.Cut(addr, "::")
+	return path.Dir(rel)
+}
+
+func selectExportGraph(cb *CodeBase, opt ExportOptions) (*exportGraph, error) {
+	if opt.FileGlob != "" {
+		if _, err := path.Match(opt.FileGlob, ""); err != nil {
+			return nil, fmt.Errorf("file glob %q: %w", opt.FileGlob, err)
+		}
+	}
+	switch opt.Direction {
+	case "", ExportBoth, ExportCallees, ExportCallers:
+	default:
+		return nil, fmt.Errorf("unknown export direction %q", opt.Direction)
+	}
+	syms := map[string]*CodeSymbol{}
+	for i := range cb.Symbols {
+		syms[cb.Symbols[i].Address] = &cb.Symbols[i]
+	}
+	keep := func(addr string) bool {
+		if len(opt.Packages) > 0 && !containsStr(opt.Packages, packageOfAddress(addr)) {
+			return false
+		}
+		if opt.FileGlob != "" {
+			rel, _, _ := strings.Cut(addr, "::")
+			if ok, _ := path.Match(opt.FileGlob, rel); !ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	merged := map[[2]string]*exportEdge{}
+	for _, c := range cb.Calls {
+		if !keep(c.CallerAddress) || !keep(c.CalleeAddress) {
+			continue
+		}
+		k := [2]string{c.CallerAddress, c.CalleeAddress}
+		e := merged[k]
+		if e == nil {
+			e = &exportEdge{from: k[0], to: k[1], via: c.ViaInterface}
+			merged[k] = e
+		} else if c.ViaInterface == nil {
+			e.via = nil
+		}
+		e.lines = append(e.lines, c.CallLine)
+	}
+
+	var reach map[string]bool
+	if opt.Root != "" {
+		if _, ok := syms[opt.Root]; !ok {
+			return nil, fmt.Errorf("no symbol at %s", opt.Root)
+		}
+		reach = reachable(merged, opt.Root, opt.Depth, opt.Direction)
+	}
+
+	g := &exportGraph{}
+	seen := map[string]bool{}
+	addNode := func(addr string) {
+		if seen[addr] {
+			return
+		}
+		seen[addr] = true
+		_, qual, _ := strings.Cut(addr, "::")
+		n := exportNode{addr: addr, label: qual, pkg: packageOfAddress(addr)}
+		if s := syms[addr]; s != nil {
+			n.kind = s.Kind
+		}
+		g.nodes = append(g.nodes, n)
+	}
+	for _, e := range merged {
+		if reach != nil && (!reach[e.from] || !reach[e.to]) {
+			continue
+		}
+		sort.Ints(e.lines)
+		g.edges = append(g.edges, *e)
+		addNode(e.from)
+		addNode(e.to)
+	}
+	if opt.Root != "" {
+		addNode(opt.Root)
+	}
+	sort.Slice(g.nodes, func(i, j int) bool { return g.nodes[i].addr < g.nodes[j].addr })
+	sort.Slice(g.edges, func(i, j int) bool {
+		if g.edges[i].from != g.edges[j].from {
+			return g.edges[i].from < g.edges[j].from
+		}
+		return g.edges[i].to < g.edges[j].to
+	})
+	return g, nil
+}
+
+// reachable returns the nodes within depth edges of root (breadth-first, unbounded if depth <= 0).
+func reachable(edges map[[2]string]*exportEdge, root string, depth int, dir string) map[string]bool {
+	adj := map[string][]string{}
+	for k := range edges {
+		if dir != ExportCallers {
+			adj[k[0]] = append(adj[k[0]], k[1])
+		}
+		if dir != ExportCallees {
+			adj[k[1]] = append(adj[k[1]], k[0])
+		}
+	}
+	seen := map[string]bool{root: true}
+	frontier := []string{root}
+	for d := 0; len(frontier) > 0 && (depth <= 0 || d < depth); d++ {
+		var next []string
+		for _, n := range frontier {
+			for _, m := range adj[n] {
+				if !seen[m] {
+					seen[m] = true
+					next = append(next, m)
+				}
+			}
+		}
+		frontier = next
+	}
+	return seen
+}
+
+// clusters groups node indexes by package in package order.
+func (g *exportGraph) clusters() ([]string, map[string][]int) {
+	byPkg := map[string][]int{}
+	var pkgs []string
+	for i, n := range g.nodes {
+		if _, ok := byPkg[n.pkg]; !ok {
+			pkgs = append(pkgs, n.pkg)
+		}
+		byPkg[n.pkg] = append(byPkg[n.pkg], i)
+	}
+	sort.Strings(pkgs)
+	return pkgs, byPkg
+}
+
+func joinLines(lines []int) string {
+	parts := make([]string, len(lines))
+	for i, l := range lines {
+		parts[i] = strconv.Itoa(l)
+	}
+	return strings.Join(parts, ",")
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// WriteDOT writes the selected call graph as a Graphviz digraph. Nodes are keyed by address and
+// labelled with the qualified name; calls made only through an interface are dashed.
+func WriteDOT(w io.Writer, cb *CodeBase, opt ExportOptions) error {
+	g, err := selectExportGraph(cb, opt)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph calls {")
+	fmt.Fprintln(bw, "\trankdir=LR;")
+	fmt.Fprintln(bw, "\tnode [shape=box, fontname=\"Helvetica\"];")
+	writeNode := func(indent string, n exportNode) {
+		attrs := "label=" + dotQuote(n.label) + ", tooltip=" + dotQuote(n.addr)
+		if n.addr == opt.Root {
+			attrs += ", style=bold"
+		}
+		fmt.Fprintf(bw, "%s%s [%s];\n", indent, dotQuote(n.addr), attrs)
+	}
+	if opt.ClusterByPackage {
+		pkgs, byPkg := g.clusters()
+		for _, pkg := range pkgs {
+			fmt.Fprintf(bw, "\tsubgraph %s {\n", dotQuote("cluster_"+pkg))
+			fmt.Fprintf(bw, "\t\tlabel=%s;\n", dotQuote(pkg))
+			for _, i := range byPkg[pkg] {
+				writeNode("\t\t", g.nodes[i])
+			}
+			fmt.Fprintln(bw, "\t}")
+		}
+	} else {
+		for _, n := range g.nodes {
+			writeNode("\t", n)
+		}
+	}
+	for _, e := range g.edges {
+		attrs := "tooltip=" + dotQuote("line "+joinLines(e.lines))
+		if e.via != nil {
+			attrs += ", style=dashed, label=" + dotQuote("via "+*e.via)
+		}
+		fmt.Fprintf(bw, "\t%s -> %s [%s];\n", dotQuote(e.from), dotQuote(e.to), attrs)
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// mermaidLabel escapes a label for a quoted Mermaid node text.
+func mermaidLabel(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;").Replace(s)
+}
+
+// WriteMermaid writes the selected call graph as a Mermaid flowchart. Mermaid ids cannot hold
+// addresses, so nodes are numbered n0, n1, ... in address order.
+func WriteMermaid(w io.Writer, cb *CodeBase, opt ExportOptions) error {
+	g, err := selectExportGraph(cb, opt)
+	if err != nil {
+		return err
+	}
+	ids := map[string]string{}
+	for i, n := range g.nodes {
+		ids[n.addr] = "n" + strconv.Itoa(i)
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "flowchart LR")
+	writeNode := func(indent string, n exportNode) {
+		fmt.Fprintf(bw, "%s%s[\"%s\"]\n", indent, ids[n.addr], mermaidLabel(n.label))
+	}
+	if opt.ClusterByPackage {
+		pkgs, byPkg := g.clusters()
+		for ci, pkg := range pkgs {
+			fmt.Fprintf(bw, "    subgraph c%d [\"%s\"]\n", ci, mermaidLabel(pkg))
+			for _, i := range byPkg[pkg] {
+				writeNode("        ", g.nodes[i])
+			}
+			fmt.Fprintln(bw, "    end")
+		}
+	} else {
+		for _, n := range g.nodes {
+			writeNode("    ", n)
+		}
+	}
+	for _, e := range g.edges {
+		arrow := "-->"
+		if e.via != nil {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(bw, "    %s %s %s\n", ids[e.from], arrow, ids[e.to])
+	}
+	if opt.Root != "" {
+		fmt.Fprintf(bw, "    style %s stroke-width:3px\n", ids[opt.Root])
+	}
+	return bw.Flush()
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// WriteGraphML writes the selected call graph as GraphML for yEd or Gephi. Nodes carry label,
+// kind and package data; edges carry the call lines and the interface for dispatch candidates.
+// With ClusterByPackage, each package becomes a node holding a nested graph.
+func WriteGraphML(w io.Writer, cb *CodeBase, opt ExportOptions) error {
+	g, err := selectExportGraph(cb, opt)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(bw, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://graphml.graphdrawing.org/xmlns http://graphml.graphdrawing.org/xmlns/1.0/graphml.xsd">`)
+	fmt.Fprintln(bw, `  <key id="label" for="node" attr.name="label" attr.type="string"/>`)
+	fmt.Fprintln(bw, `  <key id="kind" for="node" attr.name="kind" attr.type="string"/>`)
+	fmt.Fprintln(bw, `  <key id="package" for="node" attr.name="package" attr.type="string"/>`)
+	fmt.Fprintln(bw, `  <key id="lines" for="edge" attr.name="lines" attr.type="string"/>`)
+	fmt.Fprintln(bw, `  <key id="via" for="edge" attr.name="via_interface" attr.type="string"/>`)
+	fmt.Fprintln(bw, `  <graph id="calls" edgedefault="directed">`)
+	writeNode := func(indent string, n exportNode) {
+		fmt.Fprintf(bw, "%s<node id=\"%s\">\n", indent, xmlEscape(n.addr))
+		fmt.Fprintf(bw, "%s  <data key=\"label\">%s</data>\n", indent, xmlEscape(n.label))
+		fmt.Fprintf(bw, "%s  <data key=\"kind\">%s</data>\n", indent, xmlEscape(n.kind))
+		fmt.Fprintf(bw, "%s  <data key=\"package\">%s</data>\n", indent, xmlEscape(n.pkg))
+		fmt.Fprintf(bw, "%s</node>\n", indent)
+	}
+	if opt.ClusterByPackage {
+		pkgs, byPkg := g.clusters()
+		for _, pkg := range pkgs {
+			id := xmlEscape("pkg:" + pkg)
+			fmt.Fprintf(bw, "    <node id=\"%s\">\n", id)
+			fmt.Fprintf(bw, "      <data key=\"label\">%s</data>\n", xmlEscape(pkg))
+			fmt.Fprintf(bw, "      <graph id=\"%s:\" edgedefault=\"directed\">\n", id)
+			for _, i := range byPkg[pkg] {
+				writeNode("        ", g.nodes[i])
+			}
+			fmt.Fprintln(bw, "      </graph>")
+			fmt.Fprintln(bw, "    </node>")
+		}
+	} else {
+		for _, n := range g.nodes {
+			writeNode("    ", n)
+		}
+	}
+	for _, e := range g.edges {
+		fmt.Fprintf(bw, "    <edge source=\"%s\" target=\"%s\">\n", xmlEscape(e.from), xmlEscape(e.to))
+		fmt.Fprintf(bw, "      <data key=\"lines\">%s</data>\n", joinLines(e.lines))
+		if e.via != nil {
+			fmt.Fprintf(bw, "      <data key=\"via\">%s</data>\n", xmlEscape(*e.via))
+		}
+		fmt.Fprintln(bw, "    </edge>")
+	}
+	fmt.Fprintln(bw, "  </graph>")
+	fmt.Fprintln(bw, "</graphml>")
+	return bw.Flush()
+}
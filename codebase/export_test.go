@@ -0,0 +1,155 @@
+package codebase
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func exportTestCodeBase() *CodeBase {
+	iface := "b/s.go::Store"
+	return &CodeBase{
+		Symbols: []CodeSymbol{
+			{Name: "A", Kind: "function", Address: "a/a.go::A"},
+			{Name: "B", Kind: "function", Address: "a/a.go::B"},
+			{Name: "Get", Kind: "method", Address: "b/m.go::Mem.Get"},
+			{Name: "C", Kind: "function", Address: "c/c.go::C"},
+		},
+		Calls: []CallEdge{
+			{CallerAddress: "a/a.go::A", CalleeAddress: "a/a.go::B", CallLine: 3},
+			{CallerAddress: "a/a.go::A", CalleeAddress: "a/a.go::B", CallLine: 5},
+			{CallerAddress: "a/a.go::B", CalleeAddress: "b/m.go::Mem.Get", CallLine: 9, ViaInterface: &iface},
+			{CallerAddress: "b/m.go::Mem.Get", CalleeAddress: "c/c.go::C", CallLine: 4},
+		},
+	}
+}
+
+// TestWriteDOT checks filtering, root depth and clustering through the DOT exporter.
+func TestWriteDOT(t *testing.T) {
+	tests := []struct {
+		name    string
+		opt     ExportOptions
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "everything",
+			want: []string{`"a/a.go::A" -> "a/a.go::B" [tooltip="line 3,5"]`, `style=dashed, label="via b/s.go::Store"`, `"c/c.go::C" [label="C"`},
+		},
+		{
+			name:    "package filter",
+			opt:     ExportOptions{Packages: []string{"a", "b"}},
+			want:    []string{`"a/a.go::B" -> "b/m.go::Mem.Get"`},
+			notWant: []string{"c/c.go::C"},
+		},
+		{
+			name:    "file glob",
+			opt:     ExportOptions{FileGlob: "a/*.go"},
+			want:    []string{`"a/a.go::A" -> "a/a.go::B"`},
+			notWant: []string{"Mem.Get"},
+		},
+		{
+			name:    "callees depth 1",
+			opt:     ExportOptions{Root: "a/a.go::B", Depth: 1, Direction: ExportCallees},
+			want:    []string{`"a/a.go::B" -> "b/m.go::Mem.Get"`, `tooltip="a/a.go::B", style=bold`},
+			notWant: []string{"a/a.go::A", "c/c.go::C"},
+		},
+		{
+			name:    "both directions depth 1",
+			opt:     ExportOptions{Root: "a/a.go::B", Depth: 1},
+			want:    []string{`"a/a.go::A" -> "a/a.go::B"`, `"a/a.go::B" -> "b/m.go::Mem.Get"`},
+			notWant: []string{"c/c.go::C"},
+		},
+		{
+			name: "clusters",
+			opt:  ExportOptions{ClusterByPackage: true},
+			want: []string{`subgraph "cluster_a" {`, `label="b";`, `subgraph "cluster_c" {`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			if err := WriteDOT(&buf, exportTestCodeBase(), tt.opt); err != nil {
+				t.Fatal(err)
+			}
+
+			out := buf.String()
+			for _, w := range tt.want {
+				if !strings.Contains(out, w) {
+					t.Errorf("missing %q in:\n%s", w, out)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(out, w) {
+					t.Errorf("unexpected %q in:\n%s", w, out)
+				}
+			}
+		})
+	}
+}
+
+// TestWriteMermaidAndGraphML checks the other two formats on the same graph, and that GraphML is well-formed XML.
+func TestWriteMermaidAndGraphML(t *testing.T) {
+	opt := ExportOptions{ClusterByPackage: true, Root: "a/a.go::A", Depth: 2, Direction: ExportCallees}
+
+	var mm, gm bytes.Buffer
+	if err := WriteMermaid(&mm, exportTestCodeBase(), opt); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteGraphML(&gm, exportTestCodeBase(), opt); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, w := range []string{"flowchart LR", `subgraph c1 ["b"]`, `n2["Mem.Get"]`, "n0 --> n1", "n1 -.-> n2", "style n0 stroke-width:3px"} {
+		if !strings.Contains(mm.String(), w) {
+			t.Errorf("mermaid missing %q in:\n%s", w, mm.String())
+		}
+	}
+	if strings.Contains(mm.String(), "C\"]") {
+		t.Errorf("mermaid exceeds depth:\n%s", mm.String())
+	}
+	dec := xml.NewDecoder(&gm)
+	nodes, edges := 0, 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			switch se.Name.Local {
+			case "node":
+				nodes++
+			case "edge":
+				edges++
+			}
+		}
+	}
+	// two package nodes (a, b) plus A, B and Mem.Get
+	if nodes != 5 || edges != 2 {
+		t.Errorf("graphml has %d nodes and %d edges, want 5 and 2:\n%s", nodes, edges, gm.String())
+	}
+}
+
+// TestExportOptionsErrors checks that bad options are reported instead of exporting an empty graph.
+func TestExportOptionsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  ExportOptions
+	}{
+		{name: "unknown root", opt: ExportOptions{Root: "x.go::X"}},
+		{name: "bad glob", opt: ExportOptions{FileGlob: "["}},
+		{name: "bad direction", opt: ExportOptions{Root: "a/a.go::A", Direction: "up"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteDOT(&buf, exportTestCodeBase(), tt.opt); err == nil {
+				t.Errorf("expected an error, got:\n%s", buf.String())
+			}
+		})
+	}
+}
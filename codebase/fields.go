@@ -0,0 +1,186 @@
+package codebase
+
+import (
+	"strconv"
+	"strings"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// classMembers extracts the fields, interface method specs and embedded types of a struct_type
+// or interface_type. Embedded types keep their import path; Address is set by
+// linkEmbeddedTypes once every file's classes are known.
+func classMembers(tdef *sitter.Node, src []byte, imports []importRow) ([]FieldInfo, []InterfaceMethodInfo, []EmbeddedTypeInfo) {
+	fields, methods, embeds := []FieldInfo{}, []InterfaceMethodInfo{}, []EmbeddedTypeInfo{}
+	switch tdef.Kind() {
+	case "struct_type":
+		var list *sitter.Node
+		for i := uint(0); i < tdef.NamedChildCount(); i++ {
+			if ch := tdef.NamedChild(i); ch.Kind() == "field_declaration_list" {
+				list = ch
+			}
+		}
+		if list == nil {
+			break
+		}
+		for i := uint(0); i < list.NamedChildCount(); i++ {
+			fd := list.NamedChild(i)
+			if fd.Kind() != "field_declaration" {
+				continue
+			}
+			typ := fd.ChildByFieldName("type")
+			if typ == nil {
+				continue
+			}
+			base := FieldInfo{
+				Type:    collapseSpace(nodeText(src, typ)),
+				Tag:     fieldTag(fd.ChildByFieldName("tag"), src),
+				Line:    lineStart1(fd),
+				Comment: fieldComment(fd, src),
+			}
+			var names []string
+			for j := uint(0); j < fd.ChildCount(); j++ {
+				if fd.FieldNameForChild(uint32(j)) == "name" {
+					names = append(names, nodeText(src, fd.Child(j)))
+				}
+			}
+			if len(names) > 0 {
+				for _, n := range names {
+					f := base
+					f.Name = n
+					fields = append(fields, f)
+				}
+				continue
+			}
+			pointer := hasChildKind(fd, "*")
+			if pointer {
+				base.Type = "*" + base.Type
+			}
+			emb, ok := embeddedType(typ, src, imports)
+			if !ok {
+				continue
+			}
+			emb.Type, emb.Pointer = base.Type, pointer
+			base.Name, base.Embedded = emb.Name, true
+			fields = append(fields, base)
+			embeds = append(embeds, emb)
+		}
+	case "interface_type":
+		for i := uint(0); i < tdef.NamedChildCount(); i++ {
+			el := tdef.NamedChild(i)
+			switch el.Kind() {
+			case "method_elem":
+				nm := el.ChildByFieldName("name")
+				if nm == nil {
+					continue
+				}
+				sig := nodeText(src, el.ChildByFieldName("parameters"))
+				if res := el.ChildByFieldName("result"); res != nil {
+					sig += " " + nodeText(src, res)
+				}
+				methods = append(methods, InterfaceMethodInfo{Name: nodeText(src, nm), Signature: collapseSpace(sig), Line: lineStart1(el)})
+			case "type_elem":
+				if el.NamedChildCount() != 1 {
+					continue
+				}
+				if emb, ok := embeddedType(el.NamedChild(0), src, imports); ok {
+					emb.Type = collapseSpace(nodeText(src, el.NamedChild(0)))
+					embeds = append(embeds, emb)
+				}
+			}
+		}
+	}
+	return fields, methods, embeds
+}
+
+// embeddedType names the type t refers to: T, pkg.T, or either with type arguments. A package
+// alias that is not imported leaves ImportPath empty and Address unresolved.
+func embeddedType(t *sitter.Node, src []byte, imports []importRow) (EmbeddedTypeInfo, bool) {
+	if t.Kind() == "generic_type" {
+		if inner := t.ChildByFieldName("type"); inner != nil {
+			t = inner
+		}
+	}
+	switch t.Kind() {
+	case "type_identifier":
+		return EmbeddedTypeInfo{Name: nodeText(src, t)}, true
+	case "qualified_type":
+		pkg, nm := t.ChildByFieldName("package"), t.ChildByFieldName("name")
+		if pkg == nil || nm == nil {
+			return EmbeddedTypeInfo{}, false
+		}
+		emb := EmbeddedTypeInfo{Name: nodeText(src, nm)}
+		for _, row := range imports {
+			if row.local == nodeText(src, pkg) {
+				emb.ImportPath = row.path
+			}
+		}
+		return emb, true
+	}
+	return EmbeddedTypeInfo{}, false
+}
+
+func fieldTag(tag *sitter.Node, src []byte) string {
+	if tag == nil {
+		return ""
+	}
+	raw := nodeText(src, tag)
+	if s, err := strconv.Unquote(raw); err == nil {
+		return s
+	}
+	return raw
+}
+
+// fieldComment returns the comment lines directly above a field, or else a comment starting on
+// the field's last line.
+func fieldComment(fd *sitter.Node, src []byte) *string {
+	strip := func(c string) []string {
+		if rest, ok := strings.CutPrefix(c, "//"); ok {
+			return []string{strings.TrimSpace(rest)}
+		}
+		return []string{strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(c, "/*"), "*/"))}
+	}
+	above := commentsAbove(fd)
+	// A trailing comment of the previous field is on that field's line, not above this one.
+	for len(above) > 0 {
+		prev := above[0].PrevSibling()
+		if prev == nil || prev.EndPosition().Row != above[0].StartPosition().Row {
+			break
+		}
+		above = above[1:]
+	}
+	if len(above) > 0 {
+		return joinComments(src, above, strip)
+	}
+	if next := fd.NextSibling(); next != nil && next.Kind() == "comment" && next.StartPosition().Row == fd.EndPosition().Row {
+		return joinComments(src, []*sitter.Node{next}, strip)
+	}
+	return nil
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// linkEmbeddedTypes sets the Address of embedded types that name a class among syms: in the
+// declaring package, or in an imported package the module graph can locate.
+func linkEmbeddedTypes(syms []CodeSymbol, repoRoot string, mods *moduleGraph) {
+	classIdx := packageIndexOfKinds(syms, repoRoot, "class")
+	for i := range syms {
+		s := &syms[i]
+		for j := range s.EmbeddedTypes {
+			e := &s.EmbeddedTypes[j]
+			dir := packageDirOf(repoRoot, s.FilePath)
+			if e.ImportPath != "" {
+				d, ok := mods.importDir(e.ImportPath)
+				if !ok {
+					continue
+				}
+				dir = d
+			}
+			if a := packageTopLevelAddress(classIdx[dir], e.Name); a != "" {
+				e.Address = &a
+			}
+		}
+	}
+}
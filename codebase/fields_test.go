@@ -0,0 +1,120 @@
+package codebase
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// TestBuildCodebaseForFilesFields checks struct fields, interface method specs and embedded
+// types linked across packages.
+func TestBuildCodebaseForFilesFields(t *testing.T) {
+	root := t.TempDir()
+	writeTestModule(t, root, map[string]string{
+		"go.mod": "module example.com/f\n\ngo 1.25\n",
+		"base/base.go": `package base
+
+type Base struct{}
+
+type Named interface {
+	Name() string
+}
+`,
+		"svc/svc.go": "package svc\n\nimport (\n\t\"io\"\n\n\t\"example.com/f/base\"\n)\n\n" +
+			"type User struct {\n" +
+			"\t// ID is the primary key.\n" +
+			"\tID, Parent int64 `json:\"id\"`\n" +
+			"\tname string // display name\n" +
+			"\t*base.Base\n" +
+			"\tio.Reader\n" +
+			"\tOpts[int]\n" +
+			"}\n\n" +
+			"type Opts[T any] struct{ v T }\n\n" +
+			"type Store interface {\n" +
+			"\tbase.Named\n" +
+			"\tGet(id int64,\n\t\tfull bool) (*User, error)\n" +
+			"\tClose()\n" +
+			"}\n",
+	})
+	cb, err := BuildCodebaseForFiles([]string{root}, BuildOptions{RepoRoot: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byAddr := map[string]CodeSymbol{}
+	for _, s := range cb.Symbols {
+		byAddr[s.Address] = s
+	}
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		address string
+		fields  []FieldInfo
+		methods []InterfaceMethodInfo
+		embeds  []EmbeddedTypeInfo
+	}{
+		{
+			address: "svc/svc.go::User",
+			fields: []FieldInfo{
+				{Name: "ID", Type: "int64", Tag: `json:"id"`, Line: 11, Comment: str("ID is the primary key.")},
+				{Name: "Parent", Type: "int64", Tag: `json:"id"`, Line: 11, Comment: str("ID is the primary key.")},
+				{Name: "name", Type: "string", Line: 12, Comment: str("display name")},
+				{Name: "Base", Type: "*base.Base", Embedded: true, Line: 13},
+				{Name: "Reader", Type: "io.Reader", Embedded: true, Line: 14},
+				{Name: "Opts", Type: "Opts[int]", Embedded: true, Line: 15},
+			},
+			methods: []InterfaceMethodInfo{},
+			embeds: []EmbeddedTypeInfo{
+				{Type: "*base.Base", Name: "Base", ImportPath: "example.com/f/base", Pointer: true, Address: str("base/base.go::Base")},
+				{Type: "io.Reader", Name: "Reader", ImportPath: "io"},
+				{Type: "Opts[int]", Name: "Opts", Address: str("svc/svc.go::Opts")},
+			},
+		},
+		{
+			address: "svc/svc.go::Store",
+			fields:  []FieldInfo{},
+			methods: []InterfaceMethodInfo{
+				{Name: "Get", Signature: "(id int64, full bool) (*User, error)", Line: 22},
+				{Name: "Close", Signature: "()", Line: 24},
+			},
+			embeds: []EmbeddedTypeInfo{
+				{Type: "base.Named", Name: "Named", ImportPath: "example.com/f/base", Address: str("base/base.go::Named")},
+			},
+		},
+		{
+			address: "base/base.go::Base",
+			fields:  []FieldInfo{},
+			methods: []InterfaceMethodInfo{},
+			embeds:  []EmbeddedTypeInfo{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			s, ok := byAddr[tt.address]
+			if !ok {
+				t.Fatalf("missing symbol %q", tt.address)
+			}
+			if !reflect.DeepEqual(s.Fields, tt.fields) {
+				t.Errorf("Fields = %s\nwant     %s", jsonOf(t, s.Fields), jsonOf(t, tt.fields))
+			}
+			if !reflect.DeepEqual(s.InterfaceMethods, tt.methods) {
+				t.Errorf("InterfaceMethods = %+v; want %+v", s.InterfaceMethods, tt.methods)
+			}
+			if !reflect.DeepEqual(s.EmbeddedTypes, tt.embeds) {
+				t.Errorf("EmbeddedTypes = %s\nwant            %s", jsonOf(t, s.EmbeddedTypes), jsonOf(t, tt.embeds))
+			}
+		})
+	}
+	if s := byAddr["base/base.go::Base"]; s.Fields == nil {
+		t.Error("class Fields should be empty, not null")
+	}
+}
+
+func jsonOf(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
@@ -0,0 +1,292 @@
+package codebase
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Frontend indexes the source files of one language other than Go into the CodeSymbol/CallEdge
+// schema. BuildCodebaseForFiles picks a frontend from BuildOptions.Frontends by file extension
+// and parses each file with the grammar it returns; .go files always use the built-in indexer.
+type Frontend interface {
+	// Name identifies the language, e.g. "python".
+	Name() string
+	// Extensions lists the lower-case file extensions handled, with the dot (".py").
+	Extensions() []string
+	// Grammar returns the tree-sitter language for files with extension ext.
+	Grammar(ext string) *sitter.Language
+	// Index extracts symbols and resolved call edges from all files of the language together, so
+	// calls between files resolve. CallsTo and CalledBy are filled in by the caller.
+	Index(repoRoot string, files []*SourceFile) ([]CodeSymbol, []CallEdge, error)
+}
+
+// SourceFile is one parsed input file handed to a Frontend.
+type SourceFile struct {
+	Abs  string
+	Rel  string // posix path relative to the repo root
+	Src  []byte
+	Tree *sitter.Tree
+}
+
+// DefaultFrontends returns every frontend shipped with the package: Python and TypeScript.
+func DefaultFrontends() []Frontend {
+	return []Frontend{NewPythonFrontend(), NewTypeScriptFrontend()}
+}
+
+// frontendDirsSkipped are never descended into when frontends are enabled; they hold installed
+// dependencies or caches rather than the repo's own sources.
+var frontendDirsSkipped = map[string]struct{}{"node_modules": {}, "__pycache__": {}}
+
+// frontendByExt maps extensions to the first frontend claiming them.
+func frontendByExt(frontends []Frontend) map[string]Frontend {
+	out := map[string]Frontend{}
+	for _, fe := range frontends {
+		for _, ext := range fe.Extensions() {
+			if _, ok := out[ext]; !ok {
+				out[ext] = fe
+			}
+		}
+	}
+	return out
+}
+
+// buildFrontends indexes the files under paths that a frontend in opt.Frontends handles.
+// Symbols and edges are returned in frontend order, then file order as each frontend emits them.
+func buildFrontends(paths []string, opt BuildOptions) ([]CodeSymbol, []CallEdge, error) {
+	byExt := frontendByExt(opt.Frontends)
+	files, err := expandRoots(paths, opt.Ignore, func(p string) bool {
+		_, ok := byExt[strings.ToLower(filepath.Ext(p))]
+		return ok
+	}, frontendDirsSkipped)
+	if err != nil || len(files) == 0 {
+		return nil, nil, err
+	}
+	repoRoot := opt.RepoRoot
+	if repoRoot == "" {
+		if repoRoot, err = findRepoRoot(filepath.Dir(files[0])); err != nil {
+			return nil, nil, fmt.Errorf("frontends need BuildOptions.RepoRoot when there is no go.mod: %w", err)
+		}
+	}
+	if repoRoot, err = filepath.Abs(repoRoot); err != nil {
+		return nil, nil, err
+	}
+
+	sources := make([]*SourceFile, len(files))
+	for i, abs := range files {
+		src, err := os.ReadFile(abs)
+		if err != nil {
+			return nil, nil, err
+		}
+		rel, err := toPosixRel(repoRoot, abs)
+		if err != nil {
+			return nil, nil, err
+		}
+		sources[i] = &SourceFile{Abs: abs, Rel: rel, Src: src}
+	}
+	defer func() {
+		for _, sf := range sources {
+			if sf.Tree != nil {
+				sf.Tree.Close()
+			}
+		}
+	}()
+	pool := &parserPool{}
+	defer pool.close()
+	err = parallel(pool, workerCount(opt.Concurrency), len(sources), func(i int, p *sitter.Parser) error {
+		sf := sources[i]
+		ext := strings.ToLower(filepath.Ext(sf.Abs))
+		if err := p.SetLanguage(byExt[ext].Grammar(ext)); err != nil {
+			return fmt.Errorf("%s: %w", sf.Rel, err)
+		}
+		if sf.Tree = p.Parse(sf.Src, nil); sf.Tree == nil {
+			return fmt.Errorf("parse failed for %s", sf.Abs)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var syms []CodeSymbol
+	var edges []CallEdge
+	for _, fe := range opt.Frontends {
+		var mine []*SourceFile
+		for _, sf := range sources {
+			if byExt[strings.ToLower(filepath.Ext(sf.Abs))] == fe {
+				mine = append(mine, sf)
+			}
+		}
+		if len(mine) == 0 {
+			continue
+		}
+		s, e, err := fe.Index(repoRoot, mine)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s frontend: %w", fe.Name(), err)
+		}
+		syms = append(syms, s...)
+		edges = append(edges, e...)
+	}
+	for i := range syms {
+		if syms[i].CallsTo == nil {
+			syms[i].CallsTo = []string{}
+		}
+		if syms[i].CalledBy == nil {
+			syms[i].CalledBy = []string{}
+		}
+	}
+	linkCallEdges(syms, edges)
+	return syms, edges, nil
+}
+
+// scriptFile is one file of a dynamic language reduced to what call resolution needs. The
+// Python and TypeScript frontends fill it and share resolveScriptCalls.
+type scriptFile struct {
+	rel      string
+	topLevel map[string]string       // name -> address of a module-level function, class or constant
+	methods  map[string]string       // "Class.method" -> address
+	imports  map[string]scriptImport // local name -> what it is bound to
+	calls    []scriptCall
+	syms     []CodeSymbol
+}
+
+func newScriptFile(rel string) *scriptFile {
+	return &scriptFile{rel: rel, topLevel: map[string]string{}, methods: map[string]string{}, imports: map[string]scriptImport{}}
+}
+
+// scriptImport is the target of an imported name: a module file, or a name inside one.
+type scriptImport struct {
+	file string // rel path of the imported module
+	name string // imported name; "" when the local name is the module itself
+}
+
+// scriptCall is a call site: name(), object.name(), or self/this.name() inside class.
+type scriptCall struct {
+	caller string
+	class  string
+	object string
+	self   bool
+	name   string
+	line   int
+}
+
+// addSymbol records s in the file and, for methods, links it to its class: ReceiverType and
+// ReceiverAddress are set, and the class lists the method in both method sets (these languages
+// have no pointer receivers).
+func (f *scriptFile) addSymbol(s CodeSymbol) {
+	_, qual, _ := strings.Cut(s.Address, "::")
+	if s.Kind == "method" {
+		f.methods[qual] = s.Address
+	} else if _, dup := f.topLevel[qual]; !dup {
+		f.topLevel[qual] = s.Address
+	}
+	f.syms = append(f.syms, s)
+}
+
+// symbols returns the file's symbols with method sets linked.
+func (f *scriptFile) symbols() []CodeSymbol {
+	idx := map[string]int{}
+	for i, s := range f.syms {
+		if s.Kind == "class" {
+			idx[s.Name] = i
+		}
+	}
+	for i := range f.syms {
+		m := &f.syms[i]
+		if m.Kind != "method" || m.ReceiverType == nil {
+			continue
+		}
+		if ci, ok := idx[*m.ReceiverType]; ok {
+			c := &f.syms[ci]
+			ra := c.Address
+			m.ReceiverAddress = &ra
+			c.MethodSet = append(c.MethodSet, m.Address)
+			c.PointerMethodSet = append(c.PointerMethodSet, m.Address)
+		}
+	}
+	for i := range f.syms {
+		sort.Strings(f.syms[i].MethodSet)
+		sort.Strings(f.syms[i].PointerMethodSet)
+	}
+	return f.syms
+}
+
+// resolveScriptCalls resolves every recorded call against the caller's file, its imports and
+// the enclosing class. Calls that resolve nowhere (builtins, packages outside the repo,
+// attribute calls on values) are dropped.
+func resolveScriptCalls(files []*scriptFile) []CallEdge {
+	byRel := map[string]*scriptFile{}
+	for _, f := range files {
+		byRel[f.rel] = f
+	}
+	var edges []CallEdge
+	for _, f := range files {
+		for _, c := range f.calls {
+			var addr string
+			switch {
+			case c.self:
+				addr = f.methods[c.class+"."+c.name]
+			case c.object == "":
+				addr = f.topLevel[c.name]
+				if imp, ok := f.imports[c.name]; addr == "" && ok && imp.name != "" {
+					if t := byRel[imp.file]; t != nil {
+						addr = t.topLevel[imp.name]
+					}
+				}
+			default:
+				if imp, ok := f.imports[c.object]; ok && imp.name == "" {
+					if t := byRel[imp.file]; t != nil {
+						addr = t.topLevel[c.name]
+					}
+				}
+			}
+			if addr != "" {
+				edges = append(edges, CallEdge{CallerAddress: c.caller, CalleeAddress: addr, CallLine: c.line})
+			}
+		}
+	}
+	return edges
+}
+
+// commentsAbove returns the comment nodes directly preceding anchor among its siblings,
+// top to bottom, stopping at a blank line or any other node.
+func commentsAbove(anchor *sitter.Node) []*sitter.Node {
+	var out []*sitter.Node
+	line := anchor.StartPosition().Row
+	for sib := anchor.PrevSibling(); sib != nil && sib.Kind() == "comment"; sib = sib.PrevSibling() {
+		if line-sib.EndPosition().Row > 1 {
+			break
+		}
+		out = append([]*sitter.Node{sib}, out...)
+		line = sib.StartPosition().Row
+	}
+	return out
+}
+
+// joinComments strips the comment markers in strip from each comment and joins the lines.
+func joinComments(src []byte, nodes []*sitter.Node, strip func(string) []string) *string {
+	var lines []string
+	for _, n := range nodes {
+		lines = append(lines, strip(nodeText(src, n))...)
+	}
+	joined := strings.TrimSpace(strings.Join(lines, "\n"))
+	if joined == "" {
+		return nil
+	}
+	if len(joined) > 4000 {
+		joined = joined[:3997] + "..."
+	}
+	return &joined
+}
+
+func truncatedValue(src []byte, n *sitter.Node) string {
+	val := strings.TrimSpace(nodeText(src, n))
+	if len(val) > 400 {
+		val = val[:397] + "..."
+	}
+	return val
+}
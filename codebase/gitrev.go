@@ -0,0 +1,141 @@
+package codebase
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// revisionMetaFiles are read by module resolution and exported whatever the extensions indexed.
+var revisionMetaFiles = map[string]bool{"go.mod": true, "go.work": true, "modules.txt": true}
+
+// exportRevision writes the files a build reads (sources of the indexed languages and module
+// metadata) as of opt.Revision into a new temporary directory, without touching the working
+// tree, and returns paths and opt rewritten to point into it. The caller removes dir.
+//
+// The git repository is the one containing opt.RepoRoot, or else the first path. Paths must be
+// inside its working tree; replace directives and go.work entries leaving the repository are
+// not exported and so no longer resolve.
+func exportRevision(paths []string, opt BuildOptions) (dir string, _ []string, _ BuildOptions, err error) {
+	start := opt.RepoRoot
+	if start == "" && len(paths) > 0 {
+		start = paths[0]
+	}
+	if start, err = filepath.Abs(start); err != nil {
+		return "", nil, opt, err
+	}
+	for {
+		if _, err := os.Stat(start); err == nil || filepath.Dir(start) == start {
+			break
+		}
+		start = filepath.Dir(start) // the path may not exist in the working tree any more
+	}
+	repo, err := git.PlainOpenWithOptions(start, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return "", nil, opt, fmt.Errorf("revision %s: %w", opt.Revision, err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return "", nil, opt, fmt.Errorf("revision %s: %w", opt.Revision, err)
+	}
+	top := wt.Filesystem.Root()
+	hash, err := repo.ResolveRevision(plumbing.Revision(opt.Revision))
+	if err != nil {
+		return "", nil, opt, fmt.Errorf("revision %s: %w", opt.Revision, err)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return "", nil, opt, fmt.Errorf("revision %s: %w", opt.Revision, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", nil, opt, fmt.Errorf("revision %s: %w", opt.Revision, err)
+	}
+
+	exts := map[string]bool{".go": true}
+	for _, fe := range opt.Frontends {
+		for _, ext := range fe.Extensions() {
+			exts[ext] = true
+		}
+	}
+	if dir, err = os.MkdirTemp("", "xgen-rev-"); err != nil {
+		return "", nil, opt, err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(dir)
+		}
+	}()
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if f.Mode != filemode.Regular && f.Mode != filemode.Executable {
+			return nil
+		}
+		if !exts[strings.ToLower(path.Ext(f.Name))] && !revisionMetaFiles[path.Base(f.Name)] {
+			return nil
+		}
+		return writeGitFile(f, filepath.Join(dir, filepath.FromSlash(f.Name)))
+	})
+	if err != nil {
+		return "", nil, opt, fmt.Errorf("revision %s: %w", opt.Revision, err)
+	}
+
+	rebase := func(p string) (string, error) {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return "", err
+		}
+		rel, err := filepath.Rel(top, abs)
+		if err != nil || !isLocalRel(rel) {
+			return "", fmt.Errorf("%s is outside the git repository at %s", p, top)
+		}
+		return filepath.Join(dir, rel), nil
+	}
+	out := make([]string, len(paths))
+	for i, p := range paths {
+		if out[i], err = rebase(p); err != nil {
+			return "", nil, opt, err
+		}
+	}
+	if opt.RepoRoot != "" {
+		if opt.RepoRoot, err = rebase(opt.RepoRoot); err != nil {
+			return "", nil, opt, err
+		}
+	}
+	ignore := make([]string, len(opt.Ignore))
+	for i, p := range opt.Ignore {
+		if ignore[i], err = rebase(p); err != nil {
+			return "", nil, opt, err
+		}
+	}
+	opt.Ignore = ignore
+	opt.Revision = ""
+	return dir, out, opt, nil
+}
+
+func writeGitFile(f *object.File, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	r, err := f.Reader()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
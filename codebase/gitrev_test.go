@@ -0,0 +1,105 @@
+package codebase
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// TestBuildCodebaseForFilesRevision checks that a build at a git revision sees the committed
+// sources of that revision, not the working tree, and leaves the working tree untouched.
+func TestBuildCodebaseForFilesRevision(t *testing.T) {
+	top := t.TempDir()
+	repo, err := git.PlainInit(top, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := func(files map[string]string, remove ...string) {
+		t.Helper()
+		writeTestModule(t, top, files)
+		for _, rel := range remove {
+			if _, err := wt.Remove(rel); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := wt.AddGlob("."); err != nil {
+			t.Fatal(err)
+		}
+		sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Unix(1700000000, 0)}
+		if _, err := wt.Commit("change", &git.CommitOptions{Author: sig}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The module sits below the repository top, so addresses are relative to mod/.
+	commit(map[string]string{
+		"mod/go.mod": "module example.com/x\n\ngo 1.25\n",
+		"mod/a.go":   "package x\n\nfunc A() { B() }\n",
+		"mod/b.go":   "package x\n\nfunc B() {}\n",
+	})
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateTag("v1", head.Hash(), nil); err != nil {
+		t.Fatal(err)
+	}
+	commit(map[string]string{
+		"mod/a.go": "package x\n\nfunc A() { C() }\n",
+		"mod/c.go": "package x\n\nfunc C() {}\n",
+	}, "mod/b.go")
+	writeTestModule(t, top, map[string]string{"mod/a.go": "package x\n\nfunc A() { D() }\n\nfunc D() {}\n"})
+
+	root := filepath.Join(top, "mod")
+	tests := []struct {
+		name      string
+		revision  string
+		wantCalls []string
+		wantErr   bool
+	}{
+		{name: "working tree", wantCalls: []string{"a.go::A -> a.go::D"}},
+		{name: "head", revision: "HEAD", wantCalls: []string{"a.go::A -> c.go::C"}},
+		{name: "parent", revision: "HEAD~1", wantCalls: []string{"a.go::A -> b.go::B"}},
+		{name: "tag", revision: "v1", wantCalls: []string{"a.go::A -> b.go::B"}},
+		{name: "unknown revision", revision: "nope", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb, err := BuildCodebaseForFiles([]string{root}, BuildOptions{RepoRoot: root, Revision: tt.revision})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var calls []string
+			for _, e := range cb.Calls {
+				calls = append(calls, e.CallerAddress+" -> "+e.CalleeAddress)
+			}
+			sort.Strings(calls)
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+
+	src, err := os.ReadFile(filepath.Join(root, "a.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(src) != "package x\n\nfunc A() { D() }\n\nfunc D() {}\n" {
+		t.Errorf("working tree a.go changed: %q", src)
+	}
+	if _, err := BuildCodebaseForFiles([]string{t.TempDir()}, BuildOptions{Revision: "HEAD"}); err == nil {
+		t.Error("build at a revision outside any git repository succeeded")
+	}
+}
@@ -11,45 +11,43 @@ import (
 	"sync"
 
 	sitter "github.com/tree-sitter/go-tree-sitter"
-	tree_sitter_go "github.com/tree-sitter/tree-sitter-go/bindings/go"
 )
 
-var (
-	goParser     *sitter.Parser
-	goParserInit sync.Once
-	goParserErr  error
-)
-
-func parser() (*sitter.Parser, error) {
-	goParserInit.Do(func() {
-		p := sitter.NewParser()
-		lang := sitter.NewLanguage(tree_sitter_go.Language())
-		if err := p.SetLanguage(lang); err != nil {
-			goParserErr = err
-			p.Close()
-			return
-		}
-		goParser = p
-	})
-	if goParserErr != nil {
-		return nil, goParserErr
-	}
-	return goParser, nil
-}
-
 // BuildOptions configures BuildCodebaseForFiles.
 type BuildOptions struct {
 	// RepoRoot is the module root (directory containing go.mod). Relative paths in JSON are posix paths relative to this.
 	RepoRoot string
 	// Ignore is absolute or relative file/dir paths; files under them are skipped (same idea as Python ignore=).
 	Ignore []string
+	// IndexPath, if set, is a JSON sidecar (e.g. cb.index.json next to cb.json) caching per-file
+	// hashes, symbols and edges between runs. It is created on first use and rewritten each build.
+	IndexPath string
+	// Concurrency is the number of files parsed and resolved at once, each worker with its own
+	// parser. Zero or negative means runtime.GOMAXPROCS(0). Output does not depend on it.
+	Concurrency int
+	// ModuleCache resolves imports of required modules that have no local copy (go.work module,
+	// local replace or vendor/) from the module cache ($GOMODCACHE), parsing their sources on
+	// demand. Edges into them are addressed as modulePath/relPath::Name.
+	ModuleCache bool
+	// Frontends index files of other languages (e.g. DefaultFrontends()) into the same graph.
+	// Their symbols and edges follow the Go ones and are not cached in IndexPath.
+	Frontends []Frontend
+	// Revision, if set, reads sources as of this git revision (anything go-git resolves, e.g.
+	// "HEAD~20", a tag or a commit hash) of the repository holding RepoRoot instead of the
+	// working tree, which is left untouched. Addresses stay relative to RepoRoot.
+	Revision string
 }
 
 type parsedFile struct {
 	abs string
 	rel string
-	src []byte
-	tr  *sitter.Tree
+	// addrRel is the path part of the addresses of the file's symbols; see moduleGraph.relPath.
+	addrRel string
+	src     []byte
+	tr      *sitter.Tree
+	// topLevel holds the names of top-level funcs and types, filled once the file's facts are
+	// known, so lookups from other workers never need this file's tree.
+	topLevel map[string]struct{}
 }
 
 func (p *parsedFile) close() {
@@ -119,6 +117,12 @@ func shouldIgnorePath(path string, ignore []string) (bool, error) {
 }
 
 func expandGoRoots(roots []string, ignore []string) ([]string, error) {
+	return expandRoots(roots, ignore, func(p string) bool { return strings.HasSuffix(strings.ToLower(p), ".go") }, nil)
+}
+
+// expandRoots walks roots and returns the sorted files keep accepts, skipping ignored paths and
+// directories whose base name is in skipDirs.
+func expandRoots(roots []string, ignore []string, keep func(path string) bool, skipDirs map[string]struct{}) ([]string, error) {
 	seen := map[string]struct{}{}
 	var out []string
 	for _, raw := range roots {
@@ -136,9 +140,12 @@ func expandGoRoots(roots []string, ignore []string) ([]string, error) {
 					return err
 				}
 				if d.IsDir() {
+					if _, skip := skipDirs[d.Name()]; skip && path != p {
+						return filepath.SkipDir
+					}
 					return nil
 				}
-				if !strings.HasSuffix(strings.ToLower(path), ".go") {
+				if !keep(path) {
 					return nil
 				}
 				if skip, _ := shouldIgnorePath(path, ignore); skip {
@@ -152,7 +159,7 @@ func expandGoRoots(roots []string, ignore []string) ([]string, error) {
 				return nil
 			})
 		} else {
-			if !strings.HasSuffix(strings.ToLower(p), ".go") {
+			if !keep(p) {
 				continue
 			}
 			if skip, err := shouldIgnorePath(p, ignore); err != nil {
@@ -271,6 +278,10 @@ func typeExprShortName(n *sitter.Node, src []byte) string {
 		if name := n.ChildByFieldName("name"); name != nil {
 			return typeExprShortName(name, src)
 		}
+	case "generic_type":
+		if base := n.ChildByFieldName("type"); base != nil {
+			return typeExprShortName(base, src)
+		}
 	}
 	t := strings.TrimSpace(nodeText(src, n))
 	if len(t) > 64 {
@@ -280,8 +291,14 @@ func typeExprShortName(n *sitter.Node, src []byte) string {
 }
 
 func receiverShortName(recv *sitter.Node, src []byte) string {
+	name, _ := receiverInfo(recv, src)
+	return name
+}
+
+// receiverInfo returns the receiver base type name and whether the receiver is a pointer.
+func receiverInfo(recv *sitter.Node, src []byte) (string, bool) {
 	if recv == nil || recv.Kind() != "parameter_list" {
-		return "?"
+		return "?", false
 	}
 	for i := uint(0); i < recv.NamedChildCount(); i++ {
 		ch := recv.NamedChild(i)
@@ -292,9 +309,12 @@ func receiverShortName(recv *sitter.Node, src []byte) string {
 			continue
 		}
 		typ := ch.ChildByFieldName("type")
-		return typeExprShortName(typ, src)
+		for typ != nil && typ.Kind() == "parenthesized_type" && typ.NamedChildCount() > 0 {
+			typ = typ.NamedChild(0)
+		}
+		return typeExprShortName(typ, src), typ != nil && typ.Kind() == "pointer_type"
 	}
-	return "?"
+	return "?", false
 }
 
 func godocAbove(anchor *sitter.Node, src []byte) *string {
@@ -346,6 +366,16 @@ func godocAbove(anchor *sitter.Node, src []byte) *string {
 	return &joined
 }
 
+// symbolParameters is extractParameters with an empty list instead of nil.
+func symbolParameters(fn *sitter.Node, src []byte) []ParameterInfo {
+	if params := extractParameters(fn, src); params != nil {
+		return params
+	}
+	return []ParameterInfo{}
+}
+
+// extractParameters lists one ParameterInfo per parameter: a, b int yields two entries, an
+// unnamed parameter has Name "", and a variadic one is annotated ...T.
 func extractParameters(fn *sitter.Node, src []byte) []ParameterInfo {
 	params := fn.ChildByFieldName("parameters")
 	if params == nil {
@@ -354,49 +384,96 @@ func extractParameters(fn *sitter.Node, src []byte) []ParameterInfo {
 	var out []ParameterInfo
 	for i := uint(0); i < params.NamedChildCount(); i++ {
 		ch := params.NamedChild(i)
-		if ch == nil {
+		if ch == nil || (ch.Kind() != "parameter_declaration" && ch.Kind() != "variadic_parameter_declaration") {
 			continue
 		}
-		switch ch.Kind() {
-		case "parameter_declaration":
-			var nameNode *sitter.Node
-			for j := uint(0); j < ch.NamedChildCount(); j++ {
-				c := ch.NamedChild(j)
-				if c != nil && c.Kind() == "identifier" {
-					nameNode = c
-					break
-				}
+		var annStr *string
+		if ann := ch.ChildByFieldName("type"); ann != nil {
+			s := strings.TrimSpace(nodeText(src, ann))
+			if ch.Kind() == "variadic_parameter_declaration" {
+				s = "..." + s
 			}
-			if nameNode == nil {
-				continue
+			if len(s) > 200 {
+				s = s[:197] + "..."
 			}
-			ann := ch.ChildByFieldName("type")
-			var annStr *string
-			if ann != nil {
-				s := strings.TrimSpace(nodeText(src, ann))
-				if len(s) > 200 {
-					s = s[:197] + "..."
-				}
-				annStr = &s
+			annStr = &s
+		}
+		named := false
+		for j := uint(0); j < ch.ChildCount(); j++ {
+			if ch.FieldNameForChild(uint32(j)) != "name" {
+				continue
 			}
+			nameNode := ch.Child(j)
+			named = true
 			out = append(out, ParameterInfo{
 				Name:       strings.TrimSpace(nodeText(src, nameNode)),
 				Line:       lineStart1(nameNode),
 				Annotation: annStr,
 			})
-		case "variadic_parameter_declaration":
-			nameNode := ch.ChildByFieldName("name")
-			if nameNode != nil && nameNode.Kind() == "identifier" {
-				out = append(out, ParameterInfo{
-					Name: strings.TrimSpace(nodeText(src, nameNode)),
-					Line: lineStart1(nameNode),
-				})
+		}
+		if !named {
+			out = append(out, ParameterInfo{Line: lineStart1(ch), Annotation: annStr})
+		}
+	}
+	return out
+}
+
+// extractTypeParameters lists the type parameters of a function_declaration or type_spec, one
+// entry per name ([K, V any] yields two). It returns nil for non-generic declarations.
+func extractTypeParameters(decl *sitter.Node, src []byte) []TypeParameterInfo {
+	list := decl.ChildByFieldName("type_parameters")
+	if list == nil {
+		return nil
+	}
+	var out []TypeParameterInfo
+	for i := uint(0); i < list.NamedChildCount(); i++ {
+		ch := list.NamedChild(i)
+		if ch == nil || ch.Kind() != "type_parameter_declaration" {
+			continue
+		}
+		constraint := strings.Join(strings.Fields(nodeText(src, ch.ChildByFieldName("type"))), " ")
+		for j := uint(0); j < ch.ChildCount(); j++ {
+			if ch.FieldNameForChild(uint32(j)) == "name" {
+				out = append(out, TypeParameterInfo{Name: nodeText(src, ch.Child(j)), Constraint: constraint})
 			}
 		}
 	}
 	return out
 }
 
+// receiverTypeParameters lists the type parameter names a method receiver binds, e.g. K and V
+// for (c *Cache[K, V]). Constraints are left empty for linkMethods to fill in.
+func receiverTypeParameters(recv *sitter.Node, src []byte) []TypeParameterInfo {
+	if recv == nil {
+		return nil
+	}
+	for i := uint(0); i < recv.NamedChildCount(); i++ {
+		ch := recv.NamedChild(i)
+		if ch == nil || ch.Kind() != "parameter_declaration" {
+			continue
+		}
+		typ := ch.ChildByFieldName("type")
+		for typ != nil && (typ.Kind() == "parenthesized_type" || typ.Kind() == "pointer_type") && typ.NamedChildCount() > 0 {
+			typ = typ.NamedChild(0)
+		}
+		if typ == nil || typ.Kind() != "generic_type" {
+			return nil
+		}
+		args := typ.ChildByFieldName("type_arguments")
+		if args == nil {
+			return nil
+		}
+		var out []TypeParameterInfo
+		for j := uint(0); j < args.NamedChildCount(); j++ {
+			if a := args.NamedChild(j); a != nil {
+				out = append(out, TypeParameterInfo{Name: strings.TrimSpace(nodeText(src, a))})
+			}
+		}
+		return out
+	}
+	return nil
+}
+
 func resultTypeText(fn *sitter.Node, src []byte) *string {
 	rt := fn.ChildByFieldName("result")
 	if rt == nil {
@@ -430,7 +507,7 @@ type importRow struct {
 	dotted string // same as local for from-import parity; optional
 }
 
-func collectImports(root *sitter.Node, src []byte) []importRow {
+func collectImports(root *sitter.Node, src []byte, mods *moduleGraph) []importRow {
 	var rows []importRow
 	for i := uint(0); i < root.ChildCount(); i++ {
 		ch := root.Child(i)
@@ -449,7 +526,7 @@ func collectImports(root *sitter.Node, src []byte) []importRow {
 				if ip == "" {
 					return
 				}
-				local := path.Base(ip)
+				local := mods.packageName(ip)
 				if name := n.ChildByFieldName("name"); name != nil {
 					switch name.Kind() {
 					case "package_identifier":
@@ -474,32 +551,13 @@ func collectImports(root *sitter.Node, src []byte) []importRow {
 	return rows
 }
 
-func importDirForPath(repoRoot, modulePath, importPath string) (string, bool) {
-	if importPath == "" {
-		return "", false
-	}
-	var suffix string
-	switch {
-	case importPath == modulePath:
-		suffix = ""
-	case strings.HasPrefix(importPath, modulePath+"/"):
-		suffix = strings.TrimPrefix(importPath, modulePath+"/")
-	default:
-		return "", false
-	}
-	dir := filepath.Join(repoRoot, filepath.FromSlash(suffix))
-	st, err := os.Stat(dir)
-	if err != nil || !st.IsDir() {
-		return "", false
-	}
-	return dir, true
-}
-
-func topLevelNameInFile(tree *sitter.Tree, src []byte, want string) bool {
+// topLevelNames lists the top-level func and type names declared in a file.
+func topLevelNames(tree *sitter.Tree, src []byte) []string {
 	root := tree.RootNode()
 	if root == nil {
-		return false
+		return nil
 	}
+	var out []string
 	for i := uint(0); i < root.ChildCount(); i++ {
 		ch := root.Child(i)
 		if ch == nil {
@@ -507,8 +565,8 @@ func topLevelNameInFile(tree *sitter.Tree, src []byte, want string) bool {
 		}
 		switch ch.Kind() {
 		case "function_declaration":
-			if nm := ch.ChildByFieldName("name"); nm != nil && nodeText(src, nm) == want {
-				return true
+			if nm := ch.ChildByFieldName("name"); nm != nil {
+				out = append(out, nodeText(src, nm))
 			}
 		case "type_declaration":
 			for j := uint(0); j < ch.NamedChildCount(); j++ {
@@ -517,17 +575,51 @@ func topLevelNameInFile(tree *sitter.Tree, src []byte, want string) bool {
 					continue
 				}
 				if sub.Kind() == "type_spec" {
-					if nm := sub.ChildByFieldName("name"); nm != nil && nodeText(src, nm) == want {
-						return true
+					if nm := sub.ChildByFieldName("name"); nm != nil {
+						out = append(out, nodeText(src, nm))
 					}
 				}
 			}
 		}
 	}
-	return false
+	return out
+}
+
+func nameSet(names []string) map[string]struct{} {
+	m := make(map[string]struct{}, len(names))
+	for _, n := range names {
+		m[n] = struct{}{}
+	}
+	return m
 }
 
-func findDefInPackageDir(dir, simple string, cache map[string]*parsedFile, p *sitter.Parser) (absFile, qual string, ok bool) {
+// defFinder answers findDefInPackageDir for every worker of one build. Requested files answer
+// from their topLevel names; other files in imported packages are read and parsed under mu.
+type defFinder struct {
+	mu      sync.Mutex
+	inputs  map[string]*parsedFile // requested files, read-only during a build
+	foreign map[string]*parsedFile
+	memo    map[[2]string]string // (dir, name) -> defining file, "" when not found
+}
+
+func newDefFinder(inputs map[string]*parsedFile) *defFinder {
+	return &defFinder{inputs: inputs, foreign: map[string]*parsedFile{}, memo: map[[2]string]string{}}
+}
+
+func (d *defFinder) close() {
+	for _, pf := range d.foreign {
+		pf.close()
+	}
+}
+
+func findDefInPackageDir(dir, simple string, defs *defFinder, p *sitter.Parser) (absFile, qual string, ok bool) {
+	defs.mu.Lock()
+	defer defs.mu.Unlock()
+	key := [2]string{dir, simple}
+	if abs, hit := defs.memo[key]; hit {
+		return abs, simple, abs != ""
+	}
+	defs.memo[key] = ""
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		return "", "", false
@@ -537,20 +629,23 @@ func findDefInPackageDir(dir, simple string, cache map[string]*parsedFile, p *si
 			continue
 		}
 		path := filepath.Join(dir, e.Name())
-		pf, ok2 := cache[path]
+		pf, ok2 := defs.inputs[path]
+		if !ok2 {
+			pf, ok2 = defs.foreign[path]
+		}
 		if !ok2 {
 			b, err := os.ReadFile(path)
 			if err != nil {
 				continue
 			}
-			tr := p.Parse(b, nil)
-			if tr == nil {
-				continue
+			pf = &parsedFile{abs: path, src: b}
+			defs.foreign[path] = pf
+			if pf.tr = p.Parse(pf.src, nil); pf.tr != nil {
+				pf.topLevel = nameSet(topLevelNames(pf.tr, pf.src))
 			}
-			pf = &parsedFile{abs: path, src: b, tr: tr}
-			cache[path] = pf
 		}
-		if topLevelNameInFile(pf.tr, pf.src, simple) {
+		if _, found := pf.topLevel[simple]; found {
+			defs.memo[key] = path
 			return path, simple, true
 		}
 	}
@@ -563,7 +658,7 @@ func sameFileIndex(syms []CodeSymbol, relFile string) map[string][][2]string {
 		if s.FilePath != relFile {
 			continue
 		}
-		if s.Kind != "function" && s.Kind != "class" {
+		if s.Kind != "function" && s.Kind != "method" && s.Kind != "class" {
 			continue
 		}
 		line := fmt.Sprintf("%d", s.LineStart)
@@ -581,9 +676,14 @@ func sameFileIndex(syms []CodeSymbol, relFile string) map[string][][2]string {
 // packageTopLevelIndex maps absolute package directory -> short name -> (line, address) for top-level
 // funcs and types only (qualified name has no '.'), so method names do not collide across receivers.
 func packageTopLevelIndex(syms []CodeSymbol, repoRoot string) map[string]map[string][][2]string {
+	return packageIndexOfKinds(syms, repoRoot, "function", "class")
+}
+
+// packageIndexOfKinds is packageTopLevelIndex over an explicit set of symbol kinds.
+func packageIndexOfKinds(syms []CodeSymbol, repoRoot string, kinds ...string) map[string]map[string][][2]string {
 	out := map[string]map[string][][2]string{}
 	for _, s := range syms {
-		if s.Kind != "function" && s.Kind != "class" {
+		if !containsStr(kinds, s.Kind) {
 			continue
 		}
 		_, qual, ok := strings.Cut(s.Address, "::")
@@ -638,30 +738,51 @@ func packageTopLevelAddress(m map[string][][2]string, name string) string {
 	return pairs[len(pairs)-1][1]
 }
 
-func calleeFromCall(call *sitter.Node, src []byte) (callee string, pkgAlias string) {
-	if call == nil || call.Kind() != "call_expression" {
-		return "", ""
+// calleeFromCall names the function a call expression invokes. Calls to instantiated generics
+// are unwrapped to the generic name: f[int, string](x) is a call_expression whose function is
+// f, f[K](x) an index_expression call, and f[int](x) parses as a conversion to generic_type.
+func calleeFromCall(call *sitter.Node, src []byte) (callee string, pkgAlias string, selector bool) {
+	if call == nil {
+		return "", "", false
 	}
-	fn := call.ChildByFieldName("function")
-	fn = unwrapPrimary(fn)
-	if fn == nil {
-		return "", ""
+	var fn *sitter.Node
+	switch call.Kind() {
+	case "call_expression":
+		fn = unwrapPrimary(call.ChildByFieldName("function"))
+		if fn != nil && fn.Kind() == "index_expression" {
+			fn = unwrapPrimary(fn.ChildByFieldName("operand"))
+		}
+	case "type_conversion_expression":
+		typ := call.ChildByFieldName("type")
+		if typ == nil || typ.Kind() != "generic_type" {
+			return "", "", false
+		}
+		fn = typ.ChildByFieldName("type")
 	}
-	if fn.Kind() == "identifier" {
-		return strings.TrimSpace(nodeText(src, fn)), ""
+	if fn == nil {
+		return "", "", false
 	}
-	if fn.Kind() == "selector_expression" {
+	switch fn.Kind() {
+	case "identifier", "type_identifier":
+		return strings.TrimSpace(nodeText(src, fn)), "", false
+	case "selector_expression":
 		fd := fn.ChildByFieldName("field")
 		if fd == nil {
-			return "", ""
+			return "", "", false
 		}
 		callee = strings.TrimSpace(nodeText(src, fd))
 		if op := fn.ChildByFieldName("operand"); op != nil && op.Kind() == "identifier" {
 			pkgAlias = strings.TrimSpace(nodeText(src, op))
 		}
-		return callee, pkgAlias
+		return callee, pkgAlias, true
+	case "qualified_type":
+		pkg, nm := fn.ChildByFieldName("package"), fn.ChildByFieldName("name")
+		if pkg == nil || nm == nil {
+			return "", "", false
+		}
+		return strings.TrimSpace(nodeText(src, nm)), strings.TrimSpace(nodeText(src, pkg)), true
 	}
-	return "", ""
+	return "", "", false
 }
 
 func unwrapPrimary(n *sitter.Node) *sitter.Node {
@@ -678,33 +799,14 @@ func unwrapPrimary(n *sitter.Node) *sitter.Node {
 type rawCall struct {
 	callee   string
 	pkgAlias string
+	selector bool // called as x.callee(), where x may be a package alias or a value
 	line     int
+	node     *sitter.Node // the call expression
 }
 
-func collectCallsSkipNested(node *sitter.Node, src []byte) []rawCall {
-	var out []rawCall
-	var walk func(*sitter.Node)
-	walk = func(n *sitter.Node) {
-		if n == nil {
-			return
-		}
-		if n.Kind() == "function_declaration" || n.Kind() == "func_literal" {
-			return
-		}
-		if n.Kind() == "call_expression" {
-			callee, pkg := calleeFromCall(n, src)
-			if callee != "" {
-				out = append(out, rawCall{callee: callee, pkgAlias: pkg, line: lineStart1(n)})
-			}
-		}
-		for i := uint(0); i < n.ChildCount(); i++ {
-			walk(n.Child(i))
-		}
-	}
-	walk(node)
-	return out
-}
-
+// collectCallsInNode lists the calls under node. In a function body this includes calls inside
+// function literals: a closure, deferred or started as a goroutine, runs on behalf of the
+// enclosing declaration, so its calls are attributed to it.
 func collectCallsInNode(node *sitter.Node, src []byte) []rawCall {
 	var out []rawCall
 	var walk func(*sitter.Node)
@@ -712,10 +814,10 @@ func collectCallsInNode(node *sitter.Node, src []byte) []rawCall {
 		if n == nil {
 			return
 		}
-		if n.Kind() == "call_expression" {
-			callee, pkg := calleeFromCall(n, src)
+		if n.Kind() == "call_expression" || n.Kind() == "type_conversion_expression" {
+			callee, pkg, sel := calleeFromCall(n, src)
 			if callee != "" {
-				out = append(out, rawCall{callee: callee, pkgAlias: pkg, line: lineStart1(n)})
+				out = append(out, rawCall{callee: callee, pkgAlias: pkg, selector: sel, line: lineStart1(n), node: n})
 			}
 		}
 		for i := uint(0); i < n.ChildCount(); i++ {
@@ -727,13 +829,13 @@ func collectCallsInNode(node *sitter.Node, src []byte) []rawCall {
 }
 
 func resolveCallee(
-	repoRoot, modulePath, importerAbs string,
+	mods *moduleGraph, importerAbs string,
 	callee, pkgAlias string,
 	imports []importRow,
 	usageLine int,
 	fileIdx map[string][][2]string,
 	pkgIdx map[string]map[string][][2]string,
-	cache map[string]*parsedFile,
+	defs *defFinder,
 	p *sitter.Parser,
 ) string {
 	if _, ok := builtinsGo[callee]; ok {
@@ -757,13 +859,13 @@ func resolveCallee(
 			if row.local != pkgAlias {
 				continue
 			}
-			dir, ok := importDirForPath(repoRoot, modulePath, row.path)
+			dir, ok := mods.importDir(row.path)
 			if !ok {
 				continue
 			}
-			abs, qual, ok := findDefInPackageDir(dir, callee, cache, p)
+			abs, qual, ok := findDefInPackageDir(dir, callee, defs, p)
 			if ok {
-				rel, _ := toPosixRel(repoRoot, abs)
+				rel, _ := mods.relPath(abs)
 				return rel + "::" + qual
 			}
 		}
@@ -776,13 +878,14 @@ type funcBody struct {
 	node *sitter.Node
 }
 
-func collectPackageLevel(tree *sitter.Tree, src []byte, abs, rel string, repoRoot string, outSyms *[]CodeSymbol, bodies *[]funcBody) error {
+func collectPackageLevel(tree *sitter.Tree, src []byte, rel, addrRel string, mods *moduleGraph, outSyms *[]CodeSymbol, bodies *[]funcBody) error {
 	cur := tree.Walk()
 	defer cur.Close()
 	root := tree.RootNode()
 	if root == nil {
 		return nil
 	}
+	imports := collectImports(root, src, mods)
 	for i := uint(0); i < root.ChildCount(); i++ {
 		st := root.Child(i)
 		if st == nil {
@@ -795,39 +898,41 @@ func collectPackageLevel(tree *sitter.Tree, src []byte, abs, rel string, repoRoo
 				continue
 			}
 			nm := strings.TrimSpace(nodeText(src, name))
-			addr, err := SymbolAddress(repoRoot, abs, nm)
-			if err != nil {
-				return err
-			}
+			addr := addrRel + "::" + nm
 			doc := godocAbove(st, src)
 			*outSyms = append(*outSyms, CodeSymbol{
 				Name: nm, Kind: "function",
 				LineStart: lineStart1(st), LineEnd: lineEnd1(st),
 				LineCode: lineSnippet(src, st), FilePath: rel, Address: addr,
 				CallsTo: []string{}, CalledBy: []string{},
-				Docstring: doc,
+				Docstring:      doc,
+				TypeParameters: extractTypeParameters(st, src),
+				Parameters:     symbolParameters(st, src), ReturnAnnotation: resultTypeText(st, src),
+				Metrics: symbolMetrics(st, src),
 			})
 			*bodies = append(*bodies, funcBody{qual: nm, node: st})
 		case "method_declaration":
 			recv := st.ChildByFieldName("receiver")
-			rname := receiverShortName(recv, src)
+			rname, ptr := receiverInfo(recv, src)
 			name := st.ChildByFieldName("name")
 			if name == nil {
 				continue
 			}
 			meth := strings.TrimSpace(nodeText(src, name))
 			qual := rname + "." + meth
-			addr, err := SymbolAddress(repoRoot, abs, qual)
-			if err != nil {
-				return err
-			}
+			addr := addrRel + "::" + qual
 			doc := godocAbove(st, src)
+			recvType := rname
 			*outSyms = append(*outSyms, CodeSymbol{
-				Name: meth, Kind: "function",
+				Name: meth, Kind: "method",
 				LineStart: lineStart1(st), LineEnd: lineEnd1(st),
 				LineCode: lineSnippet(src, st), FilePath: rel, Address: addr,
 				CallsTo: []string{}, CalledBy: []string{},
-				Docstring: doc,
+				Docstring:    doc,
+				ReceiverType: &recvType, PointerReceiver: ptr,
+				TypeParameters: receiverTypeParameters(recv, src),
+				Parameters:     symbolParameters(st, src), ReturnAnnotation: resultTypeText(st, src),
+				Metrics: symbolMetrics(st, src),
 			})
 			*bodies = append(*bodies, funcBody{qual: qual, node: st})
 		case "type_declaration":
@@ -840,26 +945,23 @@ func collectPackageLevel(tree *sitter.Tree, src []byte, abs, rel string, repoRoo
 				if tdef == nil {
 					continue
 				}
-				k := tdef.Kind()
-				if k != "struct_type" && k != "interface_type" {
-					continue
-				}
 				name := spec.ChildByFieldName("name")
 				if name == nil {
 					continue
 				}
 				tnm := strings.TrimSpace(nodeText(src, name))
-				addr, err := SymbolAddress(repoRoot, abs, tnm)
-				if err != nil {
-					return err
-				}
+				addr := addrRel + "::" + tnm
 				doc := godocAbove(st, src)
+				fields, methods, embeds := classMembers(tdef, src, imports)
 				*outSyms = append(*outSyms, CodeSymbol{
 					Name: tnm, Kind: "class",
 					LineStart: lineStart1(spec), LineEnd: lineEnd1(st),
 					LineCode: lineSnippet(src, spec), FilePath: rel, Address: addr,
 					CallsTo: []string{}, CalledBy: []string{},
 					Docstring: doc,
+					MethodSet: []string{}, PointerMethodSet: []string{},
+					TypeParameters: extractTypeParameters(spec, src),
+					Fields:         fields, InterfaceMethods: methods, EmbeddedTypes: embeds,
 				})
 			}
 		case "const_declaration":
@@ -877,10 +979,7 @@ func collectPackageLevel(tree *sitter.Tree, src []byte, abs, rel string, repoRoo
 					if !constNameGo.MatchString(nm) {
 						continue
 					}
-					addr, err := SymbolAddress(repoRoot, abs, nm)
-					if err != nil {
-						return err
-					}
+					addr := addrRel + "::" + nm
 					val := ""
 					if v := sp.ChildByFieldName("value"); v != nil {
 						val = strings.TrimSpace(nodeText(src, v))
@@ -925,10 +1024,7 @@ func collectPackageLevel(tree *sitter.Tree, src []byte, abs, rel string, repoRoo
 						if !constNameGo.MatchString(nm) {
 							continue
 						}
-						addr, err := SymbolAddress(repoRoot, abs, nm)
-						if err != nil {
-							return err
-						}
+						addr := addrRel + "::" + nm
 						val := ""
 						if v := sp.ChildByFieldName("value"); v != nil {
 							val = strings.TrimSpace(nodeText(src, v))
@@ -952,50 +1048,137 @@ func collectPackageLevel(tree *sitter.Tree, src []byte, abs, rel string, repoRoo
 	return nil
 }
 
-func edgesFromFunc(repoRoot, modulePath, abs, rel string, fb funcBody, src []byte, imports []importRow, fileIdx map[string][][2]string, pkgIdx map[string]map[string][][2]string, cache map[string]*parsedFile, p *sitter.Parser) ([]CallEdge, error) {
-	callerAddr, err := SymbolAddress(repoRoot, abs, fb.qual)
-	if err != nil {
-		return nil, err
-	}
+func edgesFromFunc(mods *moduleGraph, abs, addrRel string, fb funcBody, src []byte, imports []importRow, fileIdx map[string][][2]string, pkgIdx map[string]map[string][][2]string, dispatch func(method string) []dispatchTarget, types *typeScope, defs *defFinder, p *sitter.Parser) ([]CallEdge, error) {
+	callerAddr := addrRel + "::" + fb.qual
+	env := types.funcEnv(fb.node, src, imports)
 	var edges []CallEdge
+	var visible map[string]bool
+	add := func(rc rawCall) {
+		// x.M() where x has an inferred type: call its method, or dispatch through its interface.
+		// A type from outside the repo calls nothing indexed.
+		if addr, iface, ok := env.methodCall(rc.node, rc.callee, rc.line); ok {
+			if addr != "" {
+				edges = append(edges, CallEdge{CallerAddress: callerAddr, CalleeAddress: addr, CallLine: rc.line})
+				return
+			}
+			if iface == "" {
+				return
+			}
+			for _, dt := range dispatch(rc.callee) {
+				if dt.iface == iface {
+					edges = append(edges, CallEdge{CallerAddress: callerAddr, CalleeAddress: dt.callee, CallLine: rc.line, ViaInterface: &iface})
+				}
+			}
+			return
+		}
+		if addr := resolveCallee(mods, abs, rc.callee, rc.pkgAlias, imports, rc.line, fileIdx, pkgIdx, defs, p); addr != "" {
+			edges = append(edges, CallEdge{CallerAddress: callerAddr, CalleeAddress: addr, CallLine: rc.line})
+			return
+		}
+		if !rc.selector || isImportAlias(imports, rc.pkgAlias) {
+			return
+		}
+		// x.M() with no static match: fan out to the implementations of interfaces declaring M that
+		// the caller can name, i.e. declared in its package or an imported one, once per callee.
+		if visible == nil {
+			visible = visiblePackageDirs(mods, abs, imports)
+		}
+		seen := map[string]bool{}
+		for _, dt := range dispatch(rc.callee) {
+			if seen[dt.callee] || !visible[dt.ifaceDir] {
+				continue
+			}
+			seen[dt.callee] = true
+			iface := dt.iface
+			edges = append(edges, CallEdge{CallerAddress: callerAddr, CalleeAddress: dt.callee, CallLine: rc.line, ViaInterface: &iface})
+		}
+	}
 	params := fb.node.ChildByFieldName("parameters")
 	if params != nil {
 		for _, rc := range collectCallsInNode(params, src) {
-			if addr := resolveCallee(repoRoot, modulePath, abs, rc.callee, rc.pkgAlias, imports, rc.line, fileIdx, pkgIdx, cache, p); addr != "" {
-				edges = append(edges, CallEdge{CallerAddress: callerAddr, CalleeAddress: addr, CallLine: rc.line})
-			}
+			add(rc)
 		}
 	}
 	if rt := fb.node.ChildByFieldName("result"); rt != nil {
 		for _, rc := range collectCallsInNode(rt, src) {
-			if addr := resolveCallee(repoRoot, modulePath, abs, rc.callee, rc.pkgAlias, imports, rc.line, fileIdx, pkgIdx, cache, p); addr != "" {
-				edges = append(edges, CallEdge{CallerAddress: callerAddr, CalleeAddress: addr, CallLine: rc.line})
-			}
+			add(rc)
 		}
 	}
 	if body := fb.node.ChildByFieldName("body"); body != nil {
-		for _, rc := range collectCallsSkipNested(body, src) {
-			if addr := resolveCallee(repoRoot, modulePath, abs, rc.callee, rc.pkgAlias, imports, rc.line, fileIdx, pkgIdx, cache, p); addr != "" {
-				edges = append(edges, CallEdge{CallerAddress: callerAddr, CalleeAddress: addr, CallLine: rc.line})
-			}
+		for _, rc := range collectCallsInNode(body, src) {
+			add(rc)
 		}
 	}
 	return edges, nil
 }
 
-// BuildCodebaseForFiles parses .go files (or walks directories) and returns symbols + resolved call edges,
-// aligned with Python build_codebase_for_files / CodeBase.
-func BuildCodebaseForFiles(paths []string, opt BuildOptions) (*CodeBase, error) {
-	pr, err := parser()
-	if err != nil {
-		return nil, err
+// visiblePackageDirs returns the directory of the package of abs and of every local package it imports.
+func visiblePackageDirs(mods *moduleGraph, abs string, imports []importRow) map[string]bool {
+	dirs := map[string]bool{filepath.Dir(abs): true}
+	for _, row := range imports {
+		if dir, ok := mods.importDir(row.path); ok {
+			dirs[filepath.Clean(dir)] = true
+		}
+	}
+	return dirs
+}
+
+func isImportAlias(imports []importRow, name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, row := range imports {
+		if row.local == name {
+			return true
+		}
+	}
+	return false
+}
+
+// buildInput is the source state shared by the Build* entry points. Every requested file is
+// read up front; trees are parsed on demand by parsed, on whichever worker owns the file.
+type buildInput struct {
+	repoRoot   string
+	modulePath string
+	modules    *moduleGraph
+	files      []string               // requested .go files, absolute and sorted
+	cache      map[string]*parsedFile // requested files by absolute path; the map is read-only after load
+	defs       *defFinder
+	pool       *parserPool
+	workers    int
+}
+
+func (in *buildInput) close() {
+	for _, pf := range in.cache {
+		pf.close()
+	}
+	in.defs.close()
+	in.pool.close()
+}
+
+// parsed returns the file at abs with its syntax tree, parsing it with p on first use.
+func (in *buildInput) parsed(abs string, p *sitter.Parser) (*parsedFile, error) {
+	pf := in.cache[abs]
+	if pf == nil {
+		return nil, fmt.Errorf("%s is not a build input", abs)
+	}
+	if pf.tr == nil {
+		if pf.tr = p.Parse(pf.src, nil); pf.tr == nil {
+			return nil, fmt.Errorf("parse failed for %s", abs)
+		}
 	}
+	return pf, nil
+}
+
+// loadBuildInput expands paths, locates the module root and reads every requested file.
+// It returns a nil input (and no error) when paths contain no .go files.
+func loadBuildInput(paths []string, opt BuildOptions) (*buildInput, error) {
 	files, err := expandGoRoots(paths, opt.Ignore)
 	if err != nil {
 		return nil, err
 	}
 	if len(files) == 0 {
-		return &CodeBase{Symbols: nil, Calls: nil}, nil
+		return nil, nil
 	}
 	repoRoot := opt.RepoRoot
 	if repoRoot == "" {
@@ -1014,62 +1197,411 @@ func BuildCodebaseForFiles(paths []string, opt BuildOptions) (*CodeBase, error)
 		return nil, err
 	}
 
+	mods := loadModuleGraph(repoRoot, modulePath, opt.ModuleCache)
 	cache := map[string]*parsedFile{}
-	defer func() {
-		for _, pf := range cache {
-			pf.close()
-		}
-	}()
-
 	for _, abs := range files {
 		src, err := os.ReadFile(abs)
 		if err != nil {
 			return nil, err
 		}
-		tr := pr.Parse(src, nil)
-		if tr == nil {
-			return nil, fmt.Errorf("parse failed for %s", abs)
-		}
 		rel, err := toPosixRel(repoRoot, abs)
 		if err != nil {
-			tr.Close()
 			return nil, err
 		}
-		cache[abs] = &parsedFile{abs: abs, rel: rel, src: src, tr: tr}
+		addrRel, err := mods.relPath(abs)
+		if err != nil {
+			return nil, err
+		}
+		cache[abs] = &parsedFile{abs: abs, rel: rel, addrRel: addrRel, src: src}
+	}
+	return &buildInput{
+		repoRoot:   repoRoot,
+		modulePath: modulePath,
+		modules:    mods,
+		files:      files,
+		cache:      cache,
+		defs:       newDefFinder(cache),
+		pool:       &parserPool{},
+		workers:    workerCount(opt.Concurrency),
+	}, nil
+}
+
+// eachFile runs fn over every requested file index on the build's worker pool.
+func (in *buildInput) eachFile(fn func(i int, p *sitter.Parser) error) error {
+	return parallel(in.pool, in.workers, len(in.files), fn)
+}
+
+// fileFacts is everything extracted from one file that depends only on that file's content.
+type fileFacts struct {
+	Symbols    []CodeSymbol      `json:"symbols"` // as collected, before linkMethods
+	Interfaces []ifaceFact       `json:"interfaces"`
+	MethodSigs map[string]string `json:"method_sigs"` // method address -> signature key
+	Imports    []string          `json:"imports"`
+	// ImportNames maps the local name of each named or default import to its path.
+	ImportNames map[string]string `json:"import_names"`
+	TopLevel    []string          `json:"top_level"` // top-level func and type names, see topLevelNames
+}
+
+// collectFileFacts parses abs if needed and extracts its facts and function bodies.
+func collectFileFacts(in *buildInput, abs string, p *sitter.Parser) (*fileFacts, []funcBody, error) {
+	pf, err := in.parsed(abs, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	ff := &fileFacts{}
+	var bodies []funcBody
+	if err := collectPackageLevel(pf.tr, pf.src, pf.rel, pf.addrRel, in.modules, &ff.Symbols, &bodies); err != nil {
+		return nil, nil, err
 	}
+	imports := collectImports(pf.tr.RootNode(), pf.src, in.modules)
+	ff.ImportNames = map[string]string{}
+	for _, row := range imports {
+		ff.Imports = append(ff.Imports, row.path)
+		if row.local != "." {
+			ff.ImportNames[row.local] = row.path
+		}
+	}
+	ff.Interfaces = fileInterfaces(pf, imports)
+	ff.MethodSigs = fileMethodSigs(pf.addrRel, bodies, pf.src)
+	ff.TopLevel = topLevelNames(pf.tr, pf.src)
+	return ff, bodies, nil
+}
 
-	var allSyms []CodeSymbol
-	var allEdges []CallEdge
-	perFileBodies := map[string][]funcBody{}
+// collectBodies re-walks abs for its function bodies only, for files whose facts came from the index.
+func collectBodies(in *buildInput, abs string, p *sitter.Parser) ([]funcBody, error) {
+	pf, err := in.parsed(abs, p)
+	if err != nil {
+		return nil, err
+	}
+	var syms []CodeSymbol
+	var bodies []funcBody
+	err = collectPackageLevel(pf.tr, pf.src, pf.rel, pf.addrRel, in.modules, &syms, &bodies)
+	return bodies, err
+}
+
+// flattenSymbols concatenates per-file symbols in file order and links methods to their types.
+// Slices are copied so later linking never writes through to the per-file facts.
+func flattenSymbols(facts []*fileFacts, repoRoot string, mods *moduleGraph) []CodeSymbol {
+	var all []CodeSymbol
+	for _, ff := range facts {
+		for _, s := range ff.Symbols {
+			s.CallsTo = append([]string{}, s.CallsTo...)
+			s.CalledBy = append([]string{}, s.CalledBy...)
+			if s.MethodSet != nil {
+				s.MethodSet = append([]string{}, s.MethodSet...)
+				s.PointerMethodSet = append([]string{}, s.PointerMethodSet...)
+			}
+			if s.TypeParameters != nil {
+				s.TypeParameters = append([]TypeParameterInfo{}, s.TypeParameters...)
+			}
+			if s.Parameters != nil {
+				s.Parameters = append([]ParameterInfo{}, s.Parameters...)
+			}
+			if s.EmbeddedTypes != nil {
+				s.EmbeddedTypes = append([]EmbeddedTypeInfo{}, s.EmbeddedTypes...)
+			}
+			all = append(all, s)
+		}
+	}
+	linkMethods(all, repoRoot)
+	linkEmbeddedTypes(all, repoRoot, mods)
+	promoteMethods(all)
+	return all
+}
+
+// collectSymbols parses every requested file and returns linked symbols.
+func collectSymbols(in *buildInput) ([]CodeSymbol, error) {
+	facts := make([]*fileFacts, len(in.files))
+	err := in.eachFile(func(i int, p *sitter.Parser) error {
+		var err error
+		facts[i], _, err = collectFileFacts(in, in.files[i], p)
+		if err == nil {
+			in.cache[in.files[i]].topLevel = nameSet(facts[i].TopLevel)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return flattenSymbols(facts, in.repoRoot, in.modules), nil
+}
+
+// linkMethods points each method at its receiver type and fills the method sets of "class"
+// symbols declared in the same package: MethodSet holds value-receiver methods (the method set
+// of T), PointerMethodSet holds every declared method (the method set of *T).
+func linkMethods(syms []CodeSymbol, repoRoot string) {
+	classIdx := packageIndexOfKinds(syms, repoRoot, "class")
+	byAddr := map[string]*CodeSymbol{}
+	for i := range syms {
+		byAddr[syms[i].Address] = &syms[i]
+	}
+	for i := range syms {
+		m := &syms[i]
+		if m.Kind != "method" || m.ReceiverType == nil {
+			continue
+		}
+		dir := filepath.Join(repoRoot, filepath.FromSlash(path.Dir(m.FilePath)))
+		owner := packageTopLevelAddress(classIdx[dir], *m.ReceiverType)
+		if owner == "" {
+			continue
+		}
+		ra := owner
+		m.ReceiverAddress = &ra
+		c := byAddr[owner]
+		if !m.PointerReceiver {
+			c.MethodSet = append(c.MethodSet, m.Address)
+		}
+		c.PointerMethodSet = append(c.PointerMethodSet, m.Address)
+		if len(m.TypeParameters) == len(c.TypeParameters) {
+			for j := range m.TypeParameters {
+				m.TypeParameters[j].Constraint = c.TypeParameters[j].Constraint
+			}
+		}
+	}
+	for i := range syms {
+		sort.Strings(syms[i].MethodSet)
+		sort.Strings(syms[i].PointerMethodSet)
+	}
+}
+
+// promotedMethod is one entry of a method set under construction. An empty addr marks a name
+// that hides deeper methods without being in the set: a field, or a pointer-receiver method in
+// the method set of T.
+type promotedMethod struct {
+	addr      string
+	depth     int
+	ambiguous bool
+}
+
+// promoteMethods adds to the method sets of "class" symbols the methods promoted from their
+// embedded classes, as Go does: a shallower method or field hides deeper ones of the same name,
+// two at the same depth hide each other, and an embedded *T brings its pointer methods into the
+// method set of T too. It needs the embedded type addresses set by linkEmbeddedTypes.
+func promoteMethods(syms []CodeSymbol) {
+	byAddr := map[string]*CodeSymbol{}
+	names := map[string]string{}
+	for i := range syms {
+		byAddr[syms[i].Address] = &syms[i]
+		if syms[i].Kind == "method" {
+			names[syms[i].Address] = syms[i].Name
+		}
+	}
+	type setKey struct {
+		addr string
+		ptr  bool
+	}
+	memo := map[setKey]map[string]promotedMethod{}
+	visiting := map[string]bool{}
+	var setOf func(addr string, ptr bool) map[string]promotedMethod
+	setOf = func(addr string, ptr bool) map[string]promotedMethod {
+		if m, ok := memo[setKey{addr, ptr}]; ok {
+			return m
+		}
+		out := map[string]promotedMethod{}
+		c := byAddr[addr]
+		if c == nil || visiting[addr] {
+			return out
+		}
+		visiting[addr] = true
+		defer delete(visiting, addr)
+
+		for _, f := range c.Fields {
+			out[f.Name] = promotedMethod{}
+		}
+		for _, m := range c.PointerMethodSet {
+			out[names[m]] = promotedMethod{}
+		}
+		for _, m := range c.MethodSet {
+			out[names[m]] = promotedMethod{addr: m}
+		}
+		if ptr {
+			for _, m := range c.PointerMethodSet {
+				out[names[m]] = promotedMethod{addr: m}
+			}
+		}
+		for _, e := range c.EmbeddedTypes {
+			if e.Address == nil {
+				continue
+			}
+			for name, p := range setOf(*e.Address, ptr || e.Pointer) {
+				p.depth++
+				if cur, ok := out[name]; ok && cur.depth <= p.depth {
+					if cur.depth == p.depth {
+						cur.ambiguous = true
+						out[name] = cur
+					}
+					continue
+				}
+				out[name] = p
+			}
+		}
+		memo[setKey{addr, ptr}] = out
+		return out
+	}
+
+	sets := map[string][2][]string{}
+	for i := range syms {
+		c := &syms[i]
+		if c.Kind != "class" || len(c.EmbeddedTypes) == 0 {
+			continue
+		}
+		var value, pointer []string
+		for _, p := range setOf(c.Address, false) {
+			if p.addr != "" && !p.ambiguous {
+				value = append(value, p.addr)
+			}
+		}
+		for _, p := range setOf(c.Address, true) {
+			if p.addr != "" && !p.ambiguous {
+				pointer = append(pointer, p.addr)
+			}
+		}
+		sort.Strings(value)
+		sort.Strings(pointer)
+		sets[c.Address] = [2][]string{value, pointer}
+	}
+	for addr, set := range sets {
+		c := byAddr[addr]
+		c.MethodSet = append([]string{}, set[0]...)
+		c.PointerMethodSet = append([]string{}, set[1]...)
+	}
+}
 
-	for abs, pf := range cache {
-		var bodies []funcBody
-		if err := collectPackageLevel(pf.tr, pf.src, abs, pf.rel, repoRoot, &allSyms, &bodies); err != nil {
+// BuildCodebaseForFiles parses .go files (or walks directories) and returns symbols + resolved call edges,
+// aligned with Python build_codebase_for_files / CodeBase. Files with an extension claimed by one
+// of opt.Frontends are indexed by that frontend and appended to the same graph.
+//
+// When opt.IndexPath is set, per-file facts and edges are cached there keyed on content hashes,
+// and only changed files (and files whose edges depend on them) are parsed again.
+func BuildCodebaseForFiles(paths []string, opt BuildOptions) (*CodeBase, error) {
+	if opt.Revision != "" {
+		dir, revPaths, revOpt, err := exportRevision(paths, opt)
+		if err != nil {
 			return nil, err
 		}
-		perFileBodies[abs] = bodies
+		defer os.RemoveAll(dir)
+		paths, opt = revPaths, revOpt
+	}
+	cb, err := buildGoCodebase(paths, opt)
+	if err != nil || len(opt.Frontends) == 0 {
+		return cb, err
+	}
+	syms, edges, err := buildFrontends(paths, opt)
+	if err != nil {
+		return nil, err
 	}
+	cb.Symbols = append(cb.Symbols, syms...)
+	cb.Calls = append(cb.Calls, edges...)
+	return cb, nil
+}
 
-	pkgIdx := packageTopLevelIndex(allSyms, repoRoot)
+func buildGoCodebase(paths []string, opt BuildOptions) (*CodeBase, error) {
+	in, err := loadBuildInput(paths, opt)
+	if err != nil {
+		return nil, err
+	}
+	if in == nil {
+		return &CodeBase{Symbols: nil, Calls: nil}, nil
+	}
+	defer in.close()
 
-	for abs, pf := range cache {
-		imports := collectImports(pf.tr.RootNode(), pf.src)
-		fileIdx := sameFileIndex(allSyms, pf.rel)
-		for _, fb := range perFileBodies[abs] {
-			e, err := edgesFromFunc(repoRoot, modulePath, abs, pf.rel, fb, pf.src, imports, fileIdx, pkgIdx, cache, pr)
-			if err != nil {
-				return nil, err
+	var prev, next *buildIndex
+	if opt.IndexPath != "" {
+		if prev, err = loadBuildIndex(opt.IndexPath, in.modulePath); err != nil {
+			return nil, err
+		}
+		next = newBuildIndex(in.modulePath)
+	}
+
+	facts := make([]*fileFacts, len(in.files))
+	bodies := make([][]funcBody, len(in.files))
+	parsedNow := make([]bool, len(in.files))
+	hashes := make([]string, len(in.files))
+	err = in.eachFile(func(i int, p *sitter.Parser) error {
+		pf := in.cache[in.files[i]]
+		hashes[i] = contentHash(pf.src)
+		if e := prev.entry(pf.rel, hashes[i]); e != nil {
+			facts[i] = &e.Facts
+		} else {
+			var err error
+			if facts[i], bodies[i], err = collectFileFacts(in, in.files[i], p); err != nil {
+				return err
 			}
-			allEdges = append(allEdges, e...)
+			parsedNow[i] = true
+		}
+		pf.topLevel = nameSet(facts[i].TopLevel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	allSyms := flattenSymbols(facts, in.repoRoot, in.modules)
+	impls := implementsEdges(in.repoRoot, in.modules, allSyms, facts)
+	dispatch := dispatchIndex(impls)
+	pkgIdx := packageTopLevelIndex(allSyms, in.repoRoot)
+	types := newTypeIndex(in, allSyms, facts, impls)
+
+	var deps *depHasher
+	if next != nil {
+		deps = newDepHasher(in, hashes, dispatch)
+	}
+
+	perFileEdges := make([][]CallEdge, len(in.files))
+	entries := make([]*indexEntry, len(in.files))
+	err = in.eachFile(func(i int, p *sitter.Parser) error {
+		abs := in.files[i]
+		pf := in.cache[abs]
+		if e := prev.entry(pf.rel, hashes[i]); e != nil && e.DepHash == deps.hash(abs, facts[i].Imports, e.DispatchNames, e.TypeDirs) {
+			perFileEdges[i], entries[i] = e.Edges, e
+			return nil
+		}
+		if !parsedNow[i] {
+			var err error
+			if bodies[i], err = collectBodies(in, abs, p); err != nil {
+				return err
+			}
+		}
+		edges, names, dirs, err := fileEdges(in, abs, p, bodies[i], allSyms, pkgIdx, dispatch, types)
+		if err != nil {
+			return err
+		}
+		perFileEdges[i] = edges
+		if next != nil {
+			entries[i] = &indexEntry{
+				Hash: hashes[i], Facts: *facts[i], Edges: edges,
+				DispatchNames: names, TypeDirs: dirs, DepHash: deps.hash(abs, facts[i].Imports, names, dirs),
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var allEdges []CallEdge
+	for i, abs := range in.files {
+		allEdges = append(allEdges, perFileEdges[i]...)
+		next.put(in.cache[abs].rel, entries[i])
+	}
+
+	linkCallEdges(allSyms, allEdges)
+
+	if next != nil {
+		if err := next.save(opt.IndexPath); err != nil {
+			return nil, err
 		}
 	}
+	return &CodeBase{Symbols: allSyms, Calls: allEdges, Implements: impls.edges}, nil
+}
 
+// linkCallEdges fills CallsTo and CalledBy of syms from edges, sorted and without duplicates.
+func linkCallEdges(syms []CodeSymbol, edges []CallEdge) {
 	addrToSym := map[string]*CodeSymbol{}
-	for i := range allSyms {
-		addrToSym[allSyms[i].Address] = &allSyms[i]
+	for i := range syms {
+		addrToSym[syms[i].Address] = &syms[i]
 	}
-	for i := range allEdges {
-		e := &allEdges[i]
+	for i := range edges {
+		e := &edges[i]
 		if c, ok := addrToSym[e.CallerAddress]; ok {
 			if !containsStr(c.CallsTo, e.CalleeAddress) {
 				c.CallsTo = append(c.CallsTo, e.CalleeAddress)
@@ -1081,12 +1613,47 @@ func BuildCodebaseForFiles(paths []string, opt BuildOptions) (*CodeBase, error)
 			}
 		}
 	}
-	for i := range allSyms {
-		sort.Strings(allSyms[i].CallsTo)
-		sort.Strings(allSyms[i].CalledBy)
+	for i := range syms {
+		sort.Strings(syms[i].CallsTo)
+		sort.Strings(syms[i].CalledBy)
 	}
+}
 
-	return &CodeBase{Symbols: allSyms, Calls: allEdges}, nil
+// fileEdges resolves the calls made by every function body of one file. For dependency
+// tracking it also returns the method names whose calls were looked up in dispatch, sorted, and
+// the package directories type inference consulted.
+func fileEdges(in *buildInput, abs string, p *sitter.Parser, bodies []funcBody, allSyms []CodeSymbol, pkgIdx map[string]map[string][][2]string, dispatch map[string][]dispatchTarget, types *typeIndex) ([]CallEdge, []string, []string, error) {
+	pf, err := in.parsed(abs, p)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	scope := types.scope(pf.rel)
+	imports := collectImports(pf.tr.RootNode(), pf.src, in.modules)
+	fileIdx := sameFileIndex(allSyms, pf.rel)
+	used := map[string]struct{}{}
+	lookup := func(name string) []dispatchTarget {
+		used[name] = struct{}{}
+		return dispatch[name]
+	}
+	var edges []CallEdge
+	for _, fb := range bodies {
+		e, err := edgesFromFunc(in.modules, abs, pf.addrRel, fb, pf.src, imports, fileIdx, pkgIdx, lookup, scope, in.defs, p)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		edges = append(edges, e...)
+	}
+	names := make([]string, 0, len(used))
+	for name := range used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var dirs []string
+	if scope != nil {
+		dirs = scope.usedDirs()
+		sort.Strings(dirs)
+	}
+	return edges, names, dirs, nil
 }
 
 func containsStr(sl []string, v string) bool {
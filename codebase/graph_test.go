@@ -156,6 +156,210 @@ func TestBuildCodebaseForFiles(t *testing.T) {
 	})
 }
 
+// TestBuildCodebaseForFilesMethods checks method symbols and the method sets of their receiver types.
+func TestBuildCodebaseForFilesMethods(t *testing.T) {
+	repoRoot := findRepoRootForTest(t)
+	packDir := filepath.Join(repoRoot, "codebase", "testdata", "pack")
+	cb, err := BuildCodebaseForFiles([]string{packDir}, BuildOptions{RepoRoot: repoRoot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byAddr := map[string]CodeSymbol{}
+	for _, s := range cb.Symbols {
+		byAddr[s.Address] = s
+	}
+	const counter = "codebase/testdata/pack/c.go::Counter"
+
+	tests := []struct {
+		name        string
+		address     string
+		wantPointer bool
+	}{
+		{
+			name:        "value receiver",
+			address:     "codebase/testdata/pack/c.go::Counter.Value",
+			wantPointer: false,
+		},
+		{
+			name:        "pointer receiver",
+			address:     "codebase/testdata/pack/c.go::Counter.Reset",
+			wantPointer: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, ok := byAddr[tt.address]
+			if !ok {
+				t.Fatalf("missing symbol %q", tt.address)
+			}
+			if m.Kind != "method" {
+				t.Errorf("Kind = %q; want %q", m.Kind, "method")
+			}
+			if m.ReceiverType == nil || *m.ReceiverType != "Counter" {
+				t.Errorf("ReceiverType = %v; want Counter", m.ReceiverType)
+			}
+			if m.ReceiverAddress == nil || *m.ReceiverAddress != counter {
+				t.Errorf("ReceiverAddress = %v; want %q", m.ReceiverAddress, counter)
+			}
+			if m.PointerReceiver != tt.wantPointer {
+				t.Errorf("PointerReceiver = %v; want %v", m.PointerReceiver, tt.wantPointer)
+			}
+			cls := byAddr[counter]
+			if !slices.Contains(cls.PointerMethodSet, tt.address) {
+				t.Errorf("PointerMethodSet %v missing %q", cls.PointerMethodSet, tt.address)
+			}
+			if got := slices.Contains(cls.MethodSet, tt.address); got == tt.wantPointer {
+				t.Errorf("MethodSet %v contains %q = %v; want %v", cls.MethodSet, tt.address, got, !tt.wantPointer)
+			}
+		})
+	}
+}
+
+// TestBuildCodebaseForFilesPromotedMethods checks that methods on named non-struct types link to
+// their type and that method sets include the methods promoted from embedded types.
+func TestBuildCodebaseForFilesPromotedMethods(t *testing.T) {
+	root := t.TempDir()
+	writeTestModule(t, root, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.22\n",
+		"p/p.go": `package p
+
+type State int
+
+func (s State) String() string { return "" }
+
+type Base struct{}
+
+func (b Base) Name() string { return "" }
+func (b *Base) Reset()      {}
+
+type Left struct{}
+
+func (Left) Both() {}
+
+type Right struct{}
+
+func (Right) Both() {}
+
+type Value struct {
+	Base
+	Left
+	Right
+}
+
+type Pointer struct {
+	*Base
+}
+
+func (p *Pointer) Name() string { return "" }
+`,
+	})
+	cb, err := BuildCodebaseForFiles([]string{root}, BuildOptions{RepoRoot: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byAddr := map[string]CodeSymbol{}
+	for _, s := range cb.Symbols {
+		byAddr[s.Address] = s
+	}
+
+	if m := byAddr["p/p.go::State.String"]; m.ReceiverAddress == nil || *m.ReceiverAddress != "p/p.go::State" {
+		t.Errorf("State.String ReceiverAddress = %v; want p/p.go::State", m.ReceiverAddress)
+	}
+	tests := []struct {
+		class       string
+		wantValue   []string
+		wantPointer []string
+	}{
+		{
+			class:       "p/p.go::State",
+			wantValue:   []string{"p/p.go::State.String"},
+			wantPointer: []string{"p/p.go::State.String"},
+		},
+		{
+			class:       "p/p.go::Value",
+			wantValue:   []string{"p/p.go::Base.Name"},
+			wantPointer: []string{"p/p.go::Base.Name", "p/p.go::Base.Reset"},
+		},
+		{
+			class:       "p/p.go::Pointer",
+			wantValue:   []string{"p/p.go::Base.Reset"},
+			wantPointer: []string{"p/p.go::Base.Reset", "p/p.go::Pointer.Name"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.class, func(t *testing.T) {
+			cls := byAddr[tt.class]
+			if !slices.Equal(cls.MethodSet, tt.wantValue) {
+				t.Errorf("MethodSet = %v; want %v", cls.MethodSet, tt.wantValue)
+			}
+			if !slices.Equal(cls.PointerMethodSet, tt.wantPointer) {
+				t.Errorf("PointerMethodSet = %v; want %v", cls.PointerMethodSet, tt.wantPointer)
+			}
+		})
+	}
+}
+
+// TestBuildCodebaseForFilesGenerics checks type parameters on generic declarations and that
+// calls with explicit type arguments resolve to the generic definition.
+func TestBuildCodebaseForFilesGenerics(t *testing.T) {
+	repoRoot := findRepoRootForTest(t)
+	dir := filepath.Join(repoRoot, "codebase", "testdata", "generics")
+	cb, err := BuildCodebaseForFiles([]string{dir}, BuildOptions{RepoRoot: repoRoot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const pkg = "codebase/testdata/generics/cache.go::"
+	byAddr := map[string]CodeSymbol{}
+	for _, s := range cb.Symbols {
+		byAddr[s.Address] = s
+	}
+	kv := []TypeParameterInfo{{Name: "K", Constraint: "comparable"}, {Name: "V", Constraint: "any"}}
+
+	symTests := []struct {
+		address string
+		want    []TypeParameterInfo
+	}{
+		{address: pkg + "Cache", want: kv},
+		{address: pkg + "NewCache", want: kv},
+		{address: pkg + "Cache.Get", want: kv},
+		{address: pkg + "Sum", want: []TypeParameterInfo{{Name: "N", Constraint: "Number"}}},
+		{address: pkg + "Map", want: []TypeParameterInfo{{Name: "T", Constraint: "any"}, {Name: "R", Constraint: "any"}}},
+		{address: pkg + "Warm", want: nil},
+	}
+	for _, tt := range symTests {
+		t.Run(tt.address, func(t *testing.T) {
+			s, ok := byAddr[tt.address]
+			if !ok {
+				t.Fatalf("missing symbol %q", tt.address)
+			}
+			if !slices.Equal(s.TypeParameters, tt.want) {
+				t.Errorf("TypeParameters = %v; want %v", s.TypeParameters, tt.want)
+			}
+		})
+	}
+
+	edgeTests := []struct {
+		caller, callee string
+	}{
+		{caller: pkg + "Warm", callee: pkg + "NewCache"},
+		{caller: pkg + "Warm", callee: pkg + "Cache.Get"},
+		{caller: pkg + "Warm", callee: pkg + "Sum"},
+		{caller: pkg + "Warm", callee: pkg + "Map"},
+		{caller: "codebase/testdata/generics/use/use.go::Use", callee: pkg + "NewCache"},
+		{caller: "codebase/testdata/generics/use/use.go::Use", callee: pkg + "Sum"},
+	}
+	for _, tt := range edgeTests {
+		t.Run(tt.caller+"->"+tt.callee, func(t *testing.T) {
+			for _, e := range cb.Calls {
+				if e.CallerAddress == tt.caller && e.CalleeAddress == tt.callee {
+					return
+				}
+			}
+			t.Errorf("missing edge; calls=%+v", cb.Calls)
+		})
+	}
+}
+
 func findRepoRootForTest(t *testing.T) string {
 	t.Helper()
 	dir, err := os.Getwd()
@@ -0,0 +1,337 @@
+package codebase
+
+import (
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// ifaceFact is an interface "class" as declared in one file: its own method specs and the
+// interfaces it embeds, still unresolved so the fact only depends on that file's content.
+type ifaceFact struct {
+	Address    string            `json:"address"`
+	Methods    map[string]string `json:"methods"` // method name -> signature key
+	Embeds     []typeRef         `json:"embeds"`
+	Constraint bool              `json:"constraint"` // has union/approximation elements; only usable as a type constraint
+}
+
+// typeRef names a type either in the declaring package (ImportPath "") or in an imported one.
+type typeRef struct {
+	ImportPath string `json:"import_path"`
+	Name       string `json:"name"`
+}
+
+// concreteMethod is one method in the method set of a "class" type, declared or promoted;
+// ptr is set when only *T has it.
+type concreteMethod struct {
+	addr string
+	sig  string
+	ptr  bool
+}
+
+type implementsResult struct {
+	edges []ImplementsEdge
+	// methods maps interface address -> flattened method name -> signature key.
+	methods map[string]map[string]string
+	// owners maps type address -> method name -> method in its pointer method set.
+	owners map[string]map[string]concreteMethod
+	// dirs maps class address -> absolute package directory.
+	dirs map[string]string
+}
+
+type dispatchTarget struct {
+	callee   string // concrete method address
+	iface    string // interface address
+	ifaceDir string // package directory of iface
+}
+
+var qualifierRe = regexp.MustCompile(`\b[A-Za-z_][A-Za-z0-9_]*\.`)
+
+// normalizeTypeText collapses whitespace and drops package qualifiers so that pkg.Foo and Foo
+// compare equal across packages.
+func normalizeTypeText(t string) string {
+	return qualifierRe.ReplaceAllString(strings.Join(strings.Fields(t), " "), "")
+}
+
+// paramTypeList lists one type per formal parameter (a, b int counts twice), ignoring names.
+func paramTypeList(list *sitter.Node, src []byte) []string {
+	if list == nil {
+		return nil
+	}
+	if list.Kind() != "parameter_list" {
+		return []string{normalizeTypeText(nodeText(src, list))}
+	}
+	var out []string
+	for i := uint(0); i < list.NamedChildCount(); i++ {
+		ch := list.NamedChild(i)
+		if ch == nil {
+			continue
+		}
+		typ := normalizeTypeText(nodeText(src, ch.ChildByFieldName("type")))
+		switch ch.Kind() {
+		case "parameter_declaration":
+			n := 0
+			for j := uint(0); j < ch.ChildCount(); j++ {
+				if ch.FieldNameForChild(uint32(j)) == "name" {
+					n++
+				}
+			}
+			if n == 0 {
+				n = 1
+			}
+			for ; n > 0; n-- {
+				out = append(out, typ)
+			}
+		case "variadic_parameter_declaration":
+			out = append(out, "..."+typ)
+		}
+	}
+	return out
+}
+
+// signatureKey renders the parameter and result types of a method or method spec.
+func signatureKey(fn *sitter.Node, src []byte) string {
+	params := paramTypeList(fn.ChildByFieldName("parameters"), src)
+	results := paramTypeList(fn.ChildByFieldName("result"), src)
+	return "(" + strings.Join(params, ",") + ")(" + strings.Join(results, ",") + ")"
+}
+
+// fileInterfaces lists the interface types declared at package level in one parsed file.
+func fileInterfaces(pf *parsedFile, imports []importRow) []ifaceFact {
+	var out []ifaceFact
+	root := pf.tr.RootNode()
+	if root == nil {
+		return nil
+	}
+	for i := uint(0); i < root.ChildCount(); i++ {
+		st := root.Child(i)
+		if st == nil || st.Kind() != "type_declaration" {
+			continue
+		}
+		for j := uint(0); j < st.NamedChildCount(); j++ {
+			spec := st.NamedChild(j)
+			if spec == nil || spec.Kind() != "type_spec" {
+				continue
+			}
+			tdef := spec.ChildByFieldName("type")
+			name := spec.ChildByFieldName("name")
+			if tdef == nil || name == nil || tdef.Kind() != "interface_type" {
+				continue
+			}
+			addr := pf.addrRel + "::" + strings.TrimSpace(nodeText(pf.src, name))
+			out = append(out, interfaceFact(addr, tdef, pf.src, imports))
+		}
+	}
+	return out
+}
+
+func interfaceFact(addr string, it *sitter.Node, src []byte, imports []importRow) ifaceFact {
+	f := ifaceFact{Address: addr, Methods: map[string]string{}}
+	for i := uint(0); i < it.NamedChildCount(); i++ {
+		el := it.NamedChild(i)
+		if el == nil {
+			continue
+		}
+		switch el.Kind() {
+		case "method_elem":
+			if nm := el.ChildByFieldName("name"); nm != nil {
+				f.Methods[nodeText(src, nm)] = signatureKey(el, src)
+			}
+		case "type_elem":
+			if el.NamedChildCount() != 1 {
+				f.Constraint = true
+				continue
+			}
+			t := el.NamedChild(0)
+			switch t.Kind() {
+			case "type_identifier":
+				f.Embeds = append(f.Embeds, typeRef{Name: nodeText(src, t)})
+			case "qualified_type":
+				pkg, nm := t.ChildByFieldName("package"), t.ChildByFieldName("name")
+				if pkg == nil || nm == nil {
+					continue
+				}
+				for _, row := range imports {
+					if row.local == nodeText(src, pkg) {
+						f.Embeds = append(f.Embeds, typeRef{ImportPath: row.path, Name: nodeText(src, nm)})
+					}
+				}
+			default:
+				f.Constraint = true
+			}
+		}
+	}
+	return f
+}
+
+// fileMethodSigs maps each method address declared in a file to its signature key.
+func fileMethodSigs(rel string, bodies []funcBody, src []byte) map[string]string {
+	out := map[string]string{}
+	for _, fb := range bodies {
+		if fb.node.Kind() == "method_declaration" {
+			out[rel+"::"+fb.qual] = signatureKey(fb.node, src)
+		}
+	}
+	return out
+}
+
+// resolveEmbeds turns the embedded type references of an interface declared in package
+// directory pkgDir into interface addresses.
+func resolveEmbeds(f ifaceFact, pkgDir string, mods *moduleGraph, classIdx map[string]map[string][][2]string) []string {
+	var out []string
+	for _, ref := range f.Embeds {
+		dir := pkgDir
+		if ref.ImportPath != "" {
+			d, ok := mods.importDir(ref.ImportPath)
+			if !ok {
+				continue
+			}
+			dir = d
+		}
+		if a := packageTopLevelAddress(classIdx[dir], ref.Name); a != "" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// flattenInterface returns the full method set of addr including embedded interfaces.
+func flattenInterface(addr string, ifaces map[string]ifaceFact, embeds map[string][]string, seen map[string]bool) map[string]string {
+	f, ok := ifaces[addr]
+	if !ok || seen[addr] {
+		return nil
+	}
+	seen[addr] = true
+	out := map[string]string{}
+	for _, e := range embeds[addr] {
+		for k, v := range flattenInterface(e, ifaces, embeds, seen) {
+			out[k] = v
+		}
+	}
+	for k, v := range f.Methods {
+		out[k] = v
+	}
+	return out
+}
+
+// implementsEdges matches every non-interface "class" with methods, declared or promoted from
+// embedded classes, against every non-empty, non-constraint interface in the requested files.
+func implementsEdges(repoRoot string, mods *moduleGraph, syms []CodeSymbol, facts []*fileFacts) *implementsResult {
+	classIdx := packageIndexOfKinds(syms, repoRoot, "class")
+	dirs := map[string]string{}
+	for _, s := range syms {
+		if s.Kind == "class" {
+			dirs[s.Address] = packageDirOf(repoRoot, s.FilePath)
+		}
+	}
+	ifaces := map[string]ifaceFact{}
+	embeds := map[string][]string{}
+	sigs := map[string]string{}
+	for _, ff := range facts {
+		for _, f := range ff.Interfaces {
+			ifaces[f.Address] = f
+			embeds[f.Address] = resolveEmbeds(f, dirs[f.Address], mods, classIdx)
+		}
+		for addr, sig := range ff.MethodSigs {
+			sigs[addr] = sig
+		}
+	}
+
+	res := &implementsResult{
+		edges:   []ImplementsEdge{},
+		methods: map[string]map[string]string{},
+		owners:  map[string]map[string]concreteMethod{},
+		dirs:    dirs,
+	}
+	names := map[string]string{}
+	for _, s := range syms {
+		if s.Kind == "method" {
+			names[s.Address] = s.Name
+		}
+	}
+	for _, s := range syms {
+		if s.Kind != "class" || len(s.PointerMethodSet) == 0 {
+			continue
+		}
+		for _, m := range s.PointerMethodSet {
+			sig, ok := sigs[m]
+			if !ok {
+				continue
+			}
+			if res.owners[s.Address] == nil {
+				res.owners[s.Address] = map[string]concreteMethod{}
+			}
+			res.owners[s.Address][names[m]] = concreteMethod{addr: m, sig: sig, ptr: !slices.Contains(s.MethodSet, m)}
+		}
+	}
+
+	for addr, f := range ifaces {
+		if f.Constraint {
+			continue
+		}
+		if ms := flattenInterface(addr, ifaces, embeds, map[string]bool{}); len(ms) > 0 {
+			res.methods[addr] = ms
+		}
+	}
+
+	for owner, have := range res.owners {
+		if _, isIface := ifaces[owner]; isIface {
+			continue
+		}
+		for iface, want := range res.methods {
+			satisfied, pointerOnly := true, false
+			for name, sig := range want {
+				m, ok := have[name]
+				if !ok || m.sig != sig {
+					satisfied = false
+					break
+				}
+				if m.ptr {
+					pointerOnly = true
+				}
+			}
+			if satisfied {
+				res.edges = append(res.edges, ImplementsEdge{TypeAddress: owner, InterfaceAddress: iface, PointerOnly: pointerOnly})
+			}
+		}
+	}
+	sort.Slice(res.edges, func(i, j int) bool {
+		if res.edges[i].TypeAddress != res.edges[j].TypeAddress {
+			return res.edges[i].TypeAddress < res.edges[j].TypeAddress
+		}
+		return res.edges[i].InterfaceAddress < res.edges[j].InterfaceAddress
+	})
+	return res
+}
+
+// dispatchIndex maps an interface method name to the concrete methods a call through it may reach.
+func dispatchIndex(res *implementsResult) map[string][]dispatchTarget {
+	out := map[string][]dispatchTarget{}
+	for _, e := range res.edges {
+		for name := range res.methods[e.InterfaceAddress] {
+			m := res.owners[e.TypeAddress][name]
+			out[name] = append(out[name], dispatchTarget{callee: m.addr, iface: e.InterfaceAddress, ifaceDir: res.dirs[e.InterfaceAddress]})
+		}
+	}
+	for name, ts := range out {
+		sort.Slice(ts, func(i, j int) bool {
+			if ts[i].callee != ts[j].callee {
+				return ts[i].callee < ts[j].callee
+			}
+			return ts[i].iface < ts[j].iface
+		})
+		// A promoted method reaches the interface through each type that embeds it.
+		out[name] = slices.Compact(ts)
+	}
+	return out
+}
+
+// packageDirOf returns the absolute package directory of a repo-relative posix file path.
+func packageDirOf(repoRoot, relFile string) string {
+	return filepath.Join(repoRoot, filepath.FromSlash(path.Dir(relFile)))
+}
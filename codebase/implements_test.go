@@ -0,0 +1,220 @@
+package codebase
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestBuildCodebaseForFilesImplements checks implements edges and interface-dispatched calls
+// for the testdata/iface package.
+func TestBuildCodebaseForFilesImplements(t *testing.T) {
+	repoRoot := findRepoRootForTest(t)
+	dir := filepath.Join(repoRoot, "codebase", "testdata", "iface")
+	cb, err := BuildCodebaseForFiles([]string{dir}, BuildOptions{RepoRoot: repoRoot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const (
+		getter   = "codebase/testdata/iface/store.go::Getter"
+		store    = "codebase/testdata/iface/store.go::Store"
+		number   = "codebase/testdata/iface/store.go::Number"
+		memStore = "codebase/testdata/iface/mem.go::MemStore"
+		readOnly = "codebase/testdata/iface/mem.go::ReadOnly"
+	)
+
+	tests := []struct {
+		name        string
+		typ         string
+		iface       string
+		wantEdge    bool
+		pointerOnly bool
+	}{
+		{name: "value receivers satisfy Getter", typ: memStore, iface: getter, wantEdge: true},
+		{name: "embedded interface needs pointer receiver Put", typ: memStore, iface: store, wantEdge: true, pointerOnly: true},
+		{name: "empty receiver name still satisfies Getter", typ: readOnly, iface: getter, wantEdge: true},
+		{name: "mismatched signature does not satisfy Store", typ: readOnly, iface: store, wantEdge: false},
+		{name: "constraint interfaces are skipped", typ: memStore, iface: number, wantEdge: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *ImplementsEdge
+			for i, e := range cb.Implements {
+				if e.TypeAddress == tt.typ && e.InterfaceAddress == tt.iface {
+					got = &cb.Implements[i]
+				}
+			}
+			if (got != nil) != tt.wantEdge {
+				t.Fatalf("edge %s -> %s present = %v; want %v (all: %+v)", tt.typ, tt.iface, got != nil, tt.wantEdge, cb.Implements)
+			}
+			if got != nil && got.PointerOnly != tt.pointerOnly {
+				t.Errorf("PointerOnly = %v; want %v", got.PointerOnly, tt.pointerOnly)
+			}
+		})
+	}
+
+	t.Run("call through interface fans out to implementations", func(t *testing.T) {
+		want := map[string]bool{
+			"codebase/testdata/iface/mem.go::MemStore.Get": false,
+			"codebase/testdata/iface/mem.go::ReadOnly.Get": false,
+		}
+		for _, e := range cb.Calls {
+			if e.CallerAddress != "codebase/testdata/iface/store.go::Lookup" || e.ViaInterface == nil {
+				continue
+			}
+			if _, ok := want[e.CalleeAddress]; ok {
+				want[e.CalleeAddress] = true
+			}
+		}
+		for callee, seen := range want {
+			if !seen {
+				t.Errorf("missing dispatched edge Lookup -> %s; calls=%+v", callee, cb.Calls)
+			}
+		}
+	})
+}
+
+// TestBuildCodebaseForFilesImplementsPromoted checks implements edges for types that get methods
+// by embedding and for named types that are neither structs nor interfaces.
+func TestBuildCodebaseForFilesImplementsPromoted(t *testing.T) {
+	root := t.TempDir()
+	writeTestModule(t, root, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.22\n",
+		"p/p.go": `package p
+
+type ReadCloser interface {
+	Read(p []byte) (int, error)
+	Close() error
+}
+
+type Listener interface{ OnEvent(name string) }
+
+type Lener interface{ Len() int }
+
+type Base struct{}
+
+func (b *Base) Read(p []byte) (int, error) { return 0, nil }
+
+type File struct{ *Base }
+
+func (f File) Close() error { return nil }
+
+type Value struct{ Base }
+
+func (v Value) Close() error { return nil }
+
+type ListenerFunc func(name string)
+
+func (f ListenerFunc) OnEvent(name string) { f(name) }
+
+type Names []string
+
+func (n Names) Len() int { return len(n) }
+
+type Count int
+
+func (c *Count) Len() int { return int(*c) }
+`,
+	})
+	cb, err := BuildCodebaseForFiles([]string{root}, BuildOptions{RepoRoot: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		typ         string
+		iface       string
+		pointerOnly bool
+	}{
+		{typ: "p/p.go::File", iface: "p/p.go::ReadCloser"},
+		{typ: "p/p.go::Value", iface: "p/p.go::ReadCloser", pointerOnly: true},
+		{typ: "p/p.go::ListenerFunc", iface: "p/p.go::Listener"},
+		{typ: "p/p.go::Names", iface: "p/p.go::Lener"},
+		{typ: "p/p.go::Count", iface: "p/p.go::Lener", pointerOnly: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typ, func(t *testing.T) {
+			var got *ImplementsEdge
+			for i, e := range cb.Implements {
+				if e.TypeAddress == tt.typ && e.InterfaceAddress == tt.iface {
+					got = &cb.Implements[i]
+				}
+			}
+			if got == nil {
+				t.Fatalf("missing edge %s -> %s (all: %+v)", tt.typ, tt.iface, cb.Implements)
+			}
+			if got.PointerOnly != tt.pointerOnly {
+				t.Errorf("PointerOnly = %v; want %v", got.PointerOnly, tt.pointerOnly)
+			}
+		})
+	}
+}
+
+// TestBuildCodebaseForFilesDispatchVisibility checks that a method call with no inferred
+// receiver type only fans out to interfaces the calling package can name, once per callee.
+func TestBuildCodebaseForFilesDispatchVisibility(t *testing.T) {
+	root := t.TempDir()
+	writeTestModule(t, root, map[string]string{
+		"go.mod": "module example.com/t\n\ngo 1.25\n",
+		"locks/locks.go": `package locks
+
+type Locker interface {
+	Lock()
+}
+
+type Mutex interface {
+	Lock()
+	Unlock()
+}
+
+type File struct{}
+
+func (f *File) Lock() {}
+
+func (f *File) Unlock() {}
+`,
+		"user/user.go": `package user
+
+import "example.com/t/locks"
+
+func Use(m map[string]locks.Locker) {
+	m["a"].Lock()
+}
+`,
+		"other/other.go": `package other
+
+import "sync"
+
+func Foreign(m map[string]*sync.Mutex) {
+	m["a"].Lock()
+}
+`,
+	})
+	cb, err := BuildCodebaseForFiles([]string{root}, BuildOptions{RepoRoot: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := map[string][]string{}
+	for _, e := range cb.Calls {
+		c := e.CalleeAddress
+		if e.ViaInterface != nil {
+			c += " via " + *e.ViaInterface
+		}
+		calls[e.CallerAddress] = append(calls[e.CallerAddress], c)
+	}
+
+	tests := []struct {
+		caller string
+		want   []string
+	}{
+		{"user/user.go::Use", []string{"locks/locks.go::File.Lock via locks/locks.go::Locker"}},
+		{"other/other.go::Foreign", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.caller, func(t *testing.T) {
+			if got := calls[tt.caller]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("calls = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
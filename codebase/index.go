@@ -0,0 +1,173 @@
+package codebase
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// buildIndexVersion is bumped whenever the cached facts or edge resolution change shape,
+// so a stale sidecar is discarded instead of producing results that differ from a full build.
+const buildIndexVersion = 14
+
+// buildIndex is the incremental cache persisted at BuildOptions.IndexPath.
+type buildIndex struct {
+	Version    int                    `json:"version"`
+	ModulePath string                 `json:"module_path"`
+	Files      map[string]*indexEntry `json:"files"` // keyed by posix path relative to the repo root
+}
+
+// indexEntry caches one file. Facts are reused while Hash matches; Edges are reused only while
+// DepHash (the hash of everything edge resolution looked at outside the file) matches too.
+type indexEntry struct {
+	Hash          string     `json:"hash"`
+	Facts         fileFacts  `json:"facts"`
+	Edges         []CallEdge `json:"edges"`
+	DispatchNames []string   `json:"dispatch_names"`
+	TypeDirs      []string   `json:"type_dirs"` // package dirs consulted by type inference, repo-relative
+	DepHash       string     `json:"dep_hash"`
+}
+
+func newBuildIndex(modulePath string) *buildIndex {
+	return &buildIndex{Version: buildIndexVersion, ModulePath: modulePath, Files: map[string]*indexEntry{}}
+}
+
+// loadBuildIndex reads the sidecar at p. A missing file, another version or another module
+// yields an empty index rather than an error.
+func loadBuildIndex(p, modulePath string) (*buildIndex, error) {
+	raw, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return newBuildIndex(modulePath), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var idx buildIndex
+	if err := json.Unmarshal(raw, &idx); err != nil || idx.Version != buildIndexVersion || idx.ModulePath != modulePath || idx.Files == nil {
+		return newBuildIndex(modulePath), nil
+	}
+	return &idx, nil
+}
+
+// entry returns the cached entry for rel if its content hash still matches.
+func (idx *buildIndex) entry(rel, hash string) *indexEntry {
+	if idx == nil {
+		return nil
+	}
+	e := idx.Files[rel]
+	if e == nil || e.Hash != hash {
+		return nil
+	}
+	return e
+}
+
+func (idx *buildIndex) put(rel string, e *indexEntry) {
+	if idx != nil {
+		idx.Files[rel] = e
+	}
+}
+
+func (idx *buildIndex) save(p string) error {
+	b, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, b, 0o644)
+}
+
+func contentHash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// depHasher fingerprints the inputs that edge resolution for one file reads from outside it:
+// the other requested files of its package, every .go file on disk in the local packages it
+// imports, and the dispatch candidates of the interface methods it calls.
+type depHasher struct {
+	in       *buildInput
+	pkgs     map[string]string // package dir -> digest of requested files in it
+	dispatch map[string][]dispatchTarget
+
+	mu   sync.Mutex
+	dirs map[string]string // imported dir -> digest of .go files on disk
+}
+
+func newDepHasher(in *buildInput, hashes []string, dispatch map[string][]dispatchTarget) *depHasher {
+	parts := map[string][]string{}
+	for i, abs := range in.files {
+		dir := filepath.Dir(abs)
+		parts[dir] = append(parts[dir], in.cache[abs].rel+"="+hashes[i])
+	}
+	pkgs := map[string]string{}
+	for dir, p := range parts {
+		pkgs[dir] = contentHash([]byte(strings.Join(p, "\n")))
+	}
+	return &depHasher{in: in, pkgs: pkgs, dirs: map[string]string{}, dispatch: dispatch}
+}
+
+func (d *depHasher) hash(abs string, imports, dispatchNames, typeDirs []string) string {
+	var b strings.Builder
+	b.WriteString("pkg " + d.pkgs[filepath.Dir(abs)] + "\n")
+	sorted := append([]string{}, imports...)
+	sort.Strings(sorted)
+	for _, ip := range sorted {
+		dir, ok := d.in.modules.importDir(ip)
+		if !ok {
+			b.WriteString("import " + ip + " -\n")
+			continue
+		}
+		b.WriteString("import " + ip + " " + d.dirDigest(dir) + "\n")
+	}
+	for _, name := range dispatchNames {
+		b.WriteString("dispatch " + name)
+		for _, t := range d.dispatch[name] {
+			b.WriteString(" " + t.callee + "@" + t.iface)
+		}
+		b.WriteString("\n")
+	}
+	// A consulted package is fingerprinted by its requested files, or by its sources when
+	// inference loaded it from outside them.
+	for _, dir := range typeDirs {
+		abs := filepath.Join(d.in.repoRoot, filepath.FromSlash(dir))
+		h, ok := d.pkgs[abs]
+		if !ok {
+			h = d.dirDigest(abs)
+		}
+		b.WriteString("types " + dir + " " + h + "\n")
+	}
+	return contentHash([]byte(b.String()))
+}
+
+// dirDigest hashes the names and contents of the .go files directly in dir, the same set
+// findDefInPackageDir searches.
+func (d *depHasher) dirDigest(dir string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if h, ok := d.dirs[dir]; ok {
+		return h
+	}
+	var b strings.Builder
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") {
+			continue
+		}
+		p := filepath.Join(dir, e.Name())
+		var src []byte
+		if pf := d.in.cache[p]; pf != nil {
+			src = pf.src
+		} else if raw, err := os.ReadFile(p); err == nil {
+			src = raw
+		}
+		b.WriteString(e.Name() + "=" + contentHash(src) + "\n")
+	}
+	h := contentHash([]byte(b.String()))
+	d.dirs[dir] = h
+	return h
+}
@@ -0,0 +1,114 @@
+package codebase
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestModule lays out a throwaway module at root from relative path -> content.
+func writeTestModule(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func codeBaseJSON(t *testing.T, cb *CodeBase) string {
+	t.Helper()
+	b, err := json.Marshal(cb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// TestBuildCodebaseForFilesIndexPath checks that incremental builds match full builds across edits,
+// including edits that only change files an unchanged caller depends on.
+func TestBuildCodebaseForFilesIndexPath(t *testing.T) {
+	base := map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.25\n",
+		"a/a.go": "package a\n\nimport \"example.com/m/b\"\n\n// A calls into b.\nfunc A() {\n\tb.B()\n\thelper()\n\tb.New().T.Go()\n}\n",
+		"a/h.go": "package a\n\nfunc helper() {}\n",
+		"b/b.go": "package b\n\nimport \"example.com/m/c\"\n\n// B is called from a.\nfunc B() {}\n\ntype W struct{ T *c.T }\n\nfunc New() *W { return &W{} }\n",
+		"c/c.go": "package c\n\ntype T struct{}\n\nfunc (*T) Go() {}\n",
+	}
+
+	tests := []struct {
+		name     string
+		edits    map[string]string
+		wantEdge string // callee that A must reach after the edit ("" = none checked)
+		lostEdge string // callee that A must no longer reach after the edit
+	}{
+		{
+			name:     "no changes",
+			wantEdge: "b/b.go::B",
+		},
+		{
+			name:     "callee package renamed its function",
+			edits:    map[string]string{"b/b.go": "package b\n\n// C replaced B.\nfunc C() {}\n"},
+			lostEdge: "b/b.go::B",
+		},
+		{
+			name:     "sibling file moved helper",
+			edits:    map[string]string{"a/h.go": "package a\n", "a/h2.go": "package a\n\nfunc helper() {}\n"},
+			wantEdge: "a/h2.go::helper",
+		},
+		{
+			name:     "field type moved its method",
+			edits:    map[string]string{"c/c.go": "package c\n\ntype T struct{}\n", "c/go.go": "package c\n\nfunc (*T) Go() {}\n"},
+			wantEdge: "c/go.go::T.Go",
+			lostEdge: "c/c.go::T.Go",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTestModule(t, root, base)
+			opt := BuildOptions{RepoRoot: root, IndexPath: filepath.Join(root, "cb.index.json")}
+
+			// Arrange: warm the index with the base tree.
+			if _, err := BuildCodebaseForFiles([]string{root}, opt); err != nil {
+				t.Fatal(err)
+			}
+			writeTestModule(t, root, tt.edits)
+
+			// Act: incremental and full builds of the edited tree.
+			inc, err := BuildCodebaseForFiles([]string{root}, opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			full, err := BuildCodebaseForFiles([]string{root}, BuildOptions{RepoRoot: root})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Assert: identical output, and the edit was actually observed.
+			if got, want := codeBaseJSON(t, inc), codeBaseJSON(t, full); got != want {
+				t.Fatalf("incremental build differs from full build\nincremental: %s\nfull:        %s", got, want)
+			}
+			reaches := func(callee string) bool {
+				for _, e := range inc.Calls {
+					if e.CallerAddress == "a/a.go::A" && e.CalleeAddress == callee {
+						return true
+					}
+				}
+				return false
+			}
+			if tt.wantEdge != "" && !reaches(tt.wantEdge) {
+				t.Errorf("A does not reach %s; calls=%+v", tt.wantEdge, inc.Calls)
+			}
+			if tt.lostEdge != "" && reaches(tt.lostEdge) {
+				t.Errorf("A still reaches %s; calls=%+v", tt.lostEdge, inc.Calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package codebase
+
+import (
+	"fmt"
+	"sort"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// SymbolMetrics measures the size and complexity of one Go function or method body.
+//
+// Cyclomatic is 1 plus one per if, for, non-default case clause, && and ||, as gocyclo counts
+// it; closures count towards the enclosing function. MaxNesting is the deepest chain of if, for,
+// switch, select and func literals (else-if chains stay at one level). Statements counts simple
+// and compound statements in the body. ReturnPaths counts return statements outside closures,
+// plus one when a function without results can fall off the end of its body.
+type SymbolMetrics struct {
+	Cyclomatic  int `json:"cyclomatic"`
+	MaxNesting  int `json:"max_nesting"`
+	Statements  int `json:"statements"`
+	Parameters  int `json:"parameters"`
+	ReturnPaths int `json:"return_paths"`
+	Lines       int `json:"lines"`
+}
+
+// Metric names accepted by MetricsOptions.SortBy.
+const (
+	MetricCyclomatic  = "cyclomatic"
+	MetricNesting     = "nesting"
+	MetricStatements  = "statements"
+	MetricParameters  = "parameters"
+	MetricReturnPaths = "returns"
+	MetricLines       = "lines"
+)
+
+// metricNames lists every metric in report order.
+var metricNames = []string{MetricCyclomatic, MetricNesting, MetricStatements, MetricParameters, MetricReturnPaths, MetricLines}
+
+// Value returns the named metric; ok is false for an unknown name.
+func (m SymbolMetrics) Value(metric string) (v int, ok bool) {
+	switch metric {
+	case MetricCyclomatic:
+		return m.Cyclomatic, true
+	case MetricNesting:
+		return m.MaxNesting, true
+	case MetricStatements:
+		return m.Statements, true
+	case MetricParameters:
+		return m.Parameters, true
+	case MetricReturnPaths:
+		return m.ReturnPaths, true
+	case MetricLines:
+		return m.Lines, true
+	}
+	return 0, false
+}
+
+var statementKinds = map[string]bool{
+	"expression_statement": true, "send_statement": true, "inc_statement": true, "dec_statement": true,
+	"assignment_statement": true, "short_var_declaration": true, "var_declaration": true,
+	"const_declaration": true, "type_declaration": true, "go_statement": true, "defer_statement": true,
+	"if_statement": true, "for_statement": true, "expression_switch_statement": true,
+	"type_switch_statement": true, "select_statement": true, "return_statement": true,
+	"break_statement": true, "continue_statement": true, "goto_statement": true, "fallthrough_statement": true,
+}
+
+var nestingKinds = map[string]bool{
+	"if_statement": true, "for_statement": true, "expression_switch_statement": true,
+	"type_switch_statement": true, "select_statement": true, "func_literal": true,
+}
+
+// symbolMetrics measures fn, a function_declaration or method_declaration.
+func symbolMetrics(fn *sitter.Node, src []byte) *SymbolMetrics {
+	m := &SymbolMetrics{
+		Cyclomatic: 1,
+		Parameters: len(extractParameters(fn, src)),
+		Lines:      lineEnd1(fn) - lineStart1(fn) + 1,
+	}
+	body := fn.ChildByFieldName("body")
+	if body == nil {
+		return m
+	}
+	var walk func(n *sitter.Node, depth int, closure bool)
+	walk = func(n *sitter.Node, depth int, closure bool) {
+		switch n.Kind() {
+		case "if_statement", "for_statement", "expression_case", "type_case", "communication_case":
+			m.Cyclomatic++
+		case "binary_expression":
+			if op := n.ChildByFieldName("operator"); op != nil && (op.Kind() == "&&" || op.Kind() == "||") {
+				m.Cyclomatic++
+			}
+		case "return_statement":
+			if !closure {
+				m.ReturnPaths++
+			}
+		case "func_literal":
+			closure = true
+		}
+		if statementKinds[n.Kind()] {
+			m.Statements++
+		}
+		if nestingKinds[n.Kind()] {
+			depth++
+			m.MaxNesting = max(m.MaxNesting, depth)
+		}
+		for i := uint(0); i < n.NamedChildCount(); i++ {
+			ch := n.NamedChild(i)
+			d := depth
+			if n.Kind() == "if_statement" && ch.Kind() == "if_statement" {
+				d-- // else if
+			}
+			walk(ch, d, closure)
+		}
+	}
+	walk(body, 0, false)
+	if fn.ChildByFieldName("result") == nil && !endsInReturn(body) {
+		m.ReturnPaths++
+	}
+	return m
+}
+
+// endsInReturn reports whether the last statement of a block is a return.
+func endsInReturn(block *sitter.Node) bool {
+	for block != nil && (block.Kind() == "block" || block.Kind() == "statement_list") {
+		n := block.NamedChildCount()
+		if n == 0 {
+			return false
+		}
+		block = block.NamedChild(n - 1)
+	}
+	return block != nil && block.Kind() == "return_statement"
+}
+
+// MetricsOptions ranks symbols and files by one metric and sets limits for CI.
+type MetricsOptions struct {
+	// SortBy is the metric to rank by; "" means MetricCyclomatic.
+	SortBy string
+	// Top keeps only the first Top symbols and files; 0 keeps all.
+	Top int
+	// Limits are the highest allowed values; a zero field is not checked.
+	Limits SymbolMetrics
+}
+
+// MeasuredSymbol is a function or method with its metrics.
+type MeasuredSymbol struct {
+	Address   string `json:"address"`
+	Kind      string `json:"kind"`
+	FilePath  string `json:"file_path"`
+	LineStart int    `json:"line_start"`
+	SymbolMetrics
+}
+
+// FileMetrics aggregates the measured symbols of one file: Total sums each metric (MaxNesting
+// is the deepest), Max holds the largest value of each.
+type FileMetrics struct {
+	FilePath string        `json:"file_path"`
+	Symbols  int           `json:"symbols"`
+	Total    SymbolMetrics `json:"total"`
+	Max      SymbolMetrics `json:"max"`
+}
+
+// MetricViolation is one metric of one symbol above its limit.
+type MetricViolation struct {
+	Address string `json:"address"`
+	Metric  string `json:"metric"`
+	Value   int    `json:"value"`
+	Limit   int    `json:"limit"`
+}
+
+// MetricsReport ranks symbols and files by SortBy, highest first (ties by address or path),
+// and lists every limit violation ordered by address then metric.
+type MetricsReport struct {
+	SortBy     string            `json:"sort_by"`
+	Symbols    []MeasuredSymbol  `json:"symbols"`
+	Files      []FileMetrics     `json:"files"`
+	Violations []MetricViolation `json:"violations"`
+}
+
+// BuildMetricsReport ranks the symbols of cb that carry metrics and checks them against opt.Limits.
+func BuildMetricsReport(cb *CodeBase, opt MetricsOptions) (*MetricsReport, error) {
+	if opt.SortBy == "" {
+		opt.SortBy = MetricCyclomatic
+	}
+	if _, ok := (SymbolMetrics{}).Value(opt.SortBy); !ok {
+		return nil, fmt.Errorf("unknown metric %q", opt.SortBy)
+	}
+	rep := &MetricsReport{SortBy: opt.SortBy, Symbols: []MeasuredSymbol{}, Files: []FileMetrics{}, Violations: []MetricViolation{}}
+	files := map[string]*FileMetrics{}
+	for _, s := range cb.Symbols {
+		if s.Metrics == nil {
+			continue
+		}
+		m := *s.Metrics
+		rep.Symbols = append(rep.Symbols, MeasuredSymbol{Address: s.Address, Kind: s.Kind, FilePath: s.FilePath, LineStart: s.LineStart, SymbolMetrics: m})
+		f := files[s.FilePath]
+		if f == nil {
+			f = &FileMetrics{FilePath: s.FilePath}
+			files[s.FilePath] = f
+		}
+		f.Symbols++
+		f.Total = SymbolMetrics{
+			Cyclomatic: f.Total.Cyclomatic + m.Cyclomatic, MaxNesting: max(f.Total.MaxNesting, m.MaxNesting),
+			Statements: f.Total.Statements + m.Statements, Parameters: f.Total.Parameters + m.Parameters,
+			ReturnPaths: f.Total.ReturnPaths + m.ReturnPaths, Lines: f.Total.Lines + m.Lines,
+		}
+		f.Max = SymbolMetrics{
+			Cyclomatic: max(f.Max.Cyclomatic, m.Cyclomatic), MaxNesting: max(f.Max.MaxNesting, m.MaxNesting),
+			Statements: max(f.Max.Statements, m.Statements), Parameters: max(f.Max.Parameters, m.Parameters),
+			ReturnPaths: max(f.Max.ReturnPaths, m.ReturnPaths), Lines: max(f.Max.Lines, m.Lines),
+		}
+		for _, metric := range metricNames {
+			v, _ := m.Value(metric)
+			if limit, _ := opt.Limits.Value(metric); limit > 0 && v > limit {
+				rep.Violations = append(rep.Violations, MetricViolation{Address: s.Address, Metric: metric, Value: v, Limit: limit})
+			}
+		}
+	}
+	for _, f := range files {
+		rep.Files = append(rep.Files, *f)
+	}
+
+	sort.Slice(rep.Symbols, func(i, j int) bool {
+		a, _ := rep.Symbols[i].Value(opt.SortBy)
+		b, _ := rep.Symbols[j].Value(opt.SortBy)
+		if a != b {
+			return a > b
+		}
+		return rep.Symbols[i].Address < rep.Symbols[j].Address
+	})
+	sort.Slice(rep.Files, func(i, j int) bool {
+		a, _ := rep.Files[i].Total.Value(opt.SortBy)
+		b, _ := rep.Files[j].Total.Value(opt.SortBy)
+		if a != b {
+			return a > b
+		}
+		return rep.Files[i].FilePath < rep.Files[j].FilePath
+	})
+	sort.SliceStable(rep.Violations, func(i, j int) bool {
+		return rep.Violations[i].Address < rep.Violations[j].Address
+	})
+	if opt.Top > 0 {
+		rep.Symbols = rep.Symbols[:min(opt.Top, len(rep.Symbols))]
+		rep.Files = rep.Files[:min(opt.Top, len(rep.Files))]
+	}
+	return rep, nil
+}
@@ -0,0 +1,163 @@
+package codebase
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestBuildCodebaseForFilesMetrics checks the metrics computed for small function bodies.
+func TestBuildCodebaseForFilesMetrics(t *testing.T) {
+	root := t.TempDir()
+	writeTestModule(t, root, map[string]string{
+		"go.mod": "module example.com/x\n\ngo 1.25\n",
+		"m.go": `package m
+
+func Empty() {}
+
+func Branches(a, b int, c ...string) int {
+	if a > 0 && b > 0 {
+		return 1
+	} else if a < 0 || b < 0 {
+		return 2
+	}
+	for i := 0; i < a; i++ {
+		switch i {
+		case 1, 2:
+			a++
+		case 3:
+		default:
+			a--
+		}
+	}
+	return 0
+}
+
+func Closure() {
+	f := func() int {
+		if true {
+			return 1
+		}
+		return 0
+	}
+	f()
+}
+
+type T struct{}
+
+func (T) Select(ch chan int) {
+	select {
+	case <-ch:
+	case ch <- 1:
+	}
+	return
+}
+`,
+	})
+	cb, err := BuildCodebaseForFiles([]string{root}, BuildOptions{RepoRoot: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byAddr := map[string]CodeSymbol{}
+	for _, s := range cb.Symbols {
+		byAddr[s.Address] = s
+	}
+
+	tests := []struct {
+		address string
+		want    *SymbolMetrics
+	}{
+		{"m.go::Empty", &SymbolMetrics{Cyclomatic: 1, ReturnPaths: 1, Lines: 1}},
+		{"m.go::Branches", &SymbolMetrics{Cyclomatic: 8, MaxNesting: 2, Statements: 11, Parameters: 3, ReturnPaths: 3, Lines: 17}},
+		{"m.go::Closure", &SymbolMetrics{Cyclomatic: 2, MaxNesting: 2, Statements: 5, ReturnPaths: 1, Lines: 9}},
+		{"m.go::T.Select", &SymbolMetrics{Cyclomatic: 3, MaxNesting: 1, Statements: 3, Parameters: 1, ReturnPaths: 1, Lines: 7}},
+		{"m.go::T", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			s, ok := byAddr[tt.address]
+			if !ok {
+				t.Fatalf("no symbol %s", tt.address)
+			}
+			if !reflect.DeepEqual(s.Metrics, tt.want) {
+				t.Errorf("metrics = %+v, want %+v", s.Metrics, tt.want)
+			}
+		})
+	}
+}
+
+// TestBuildMetricsReport checks ranking, per-file totals and limit violations.
+func TestBuildMetricsReport(t *testing.T) {
+	cb := &CodeBase{Symbols: []CodeSymbol{
+		{Address: "a.go::A", Kind: "function", FilePath: "a.go", Metrics: &SymbolMetrics{Cyclomatic: 3, MaxNesting: 1, Lines: 10}},
+		{Address: "a.go::B", Kind: "function", FilePath: "a.go", Metrics: &SymbolMetrics{Cyclomatic: 12, MaxNesting: 4, Lines: 40}},
+		{Address: "b.go::C", Kind: "function", FilePath: "b.go", Metrics: &SymbolMetrics{Cyclomatic: 12, MaxNesting: 2, Lines: 60}},
+		{Address: "b.go::K", Kind: "constant", FilePath: "b.go"},
+	}}
+
+	tests := []struct {
+		name           string
+		opt            MetricsOptions
+		wantSymbols    []string
+		wantFiles      []string
+		wantViolations []MetricViolation
+		wantErr        bool
+	}{
+		{
+			name:           "default ranks by cyclomatic, ties by address",
+			wantSymbols:    []string{"a.go::B", "b.go::C", "a.go::A"},
+			wantFiles:      []string{"a.go", "b.go"},
+			wantViolations: []MetricViolation{},
+		},
+		{
+			name:           "lines with top",
+			opt:            MetricsOptions{SortBy: MetricLines, Top: 1},
+			wantSymbols:    []string{"b.go::C"},
+			wantFiles:      []string{"b.go"},
+			wantViolations: []MetricViolation{},
+		},
+		{
+			name:        "limits",
+			opt:         MetricsOptions{Limits: SymbolMetrics{Cyclomatic: 10, MaxNesting: 3}},
+			wantSymbols: []string{"a.go::B", "b.go::C", "a.go::A"},
+			wantFiles:   []string{"a.go", "b.go"},
+			wantViolations: []MetricViolation{
+				{Address: "a.go::B", Metric: MetricCyclomatic, Value: 12, Limit: 10},
+				{Address: "a.go::B", Metric: MetricNesting, Value: 4, Limit: 3},
+				{Address: "b.go::C", Metric: MetricCyclomatic, Value: 12, Limit: 10},
+			},
+		},
+		{
+			name:    "unknown metric",
+			opt:     MetricsOptions{SortBy: "size"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rep, err := BuildMetricsReport(cb, tt.opt)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var syms, files []string
+			for _, s := range rep.Symbols {
+				syms = append(syms, s.Address)
+			}
+			for _, f := range rep.Files {
+				files = append(files, f.FilePath)
+			}
+			if !reflect.DeepEqual(syms, tt.wantSymbols) || !reflect.DeepEqual(files, tt.wantFiles) {
+				t.Errorf("symbols = %v, files = %v; want %v, %v", syms, files, tt.wantSymbols, tt.wantFiles)
+			}
+			if !reflect.DeepEqual(rep.Violations, tt.wantViolations) {
+				t.Errorf("violations = %+v, want %+v", rep.Violations, tt.wantViolations)
+			}
+		})
+	}
+	rep, _ := BuildMetricsReport(cb, MetricsOptions{})
+	if got := rep.Files[0]; got.Symbols != 2 || got.Total.Cyclomatic != 15 || got.Total.MaxNesting != 4 || got.Max.Lines != 40 {
+		t.Errorf("a.go aggregate = %+v", got)
+	}
+}
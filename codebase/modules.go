@@ -0,0 +1,377 @@
+package codebase
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// moduleGraph maps import paths to source directories for one build: the main module, the
+// other modules of a go.work workspace, local replace targets, vendor/ and, when enabled,
+// required modules in the module cache.
+//
+// Files under the repo root keep repo-relative addresses so they match indexed symbols; files
+// of other modules (including vendor/ copies) are addressed as modulePath/relPath::Name.
+type moduleGraph struct {
+	repoRoot string
+	main     string
+	roots    []moduleRoot // longest path first
+	vendor   string       // repoRoot/vendor when vendor/modules.txt exists
+	cache    string       // module cache directory when BuildOptions.ModuleCache is set
+	requires map[string]string
+
+	mu    sync.Mutex
+	names map[string]string // import path -> package name, see packageName
+}
+
+// moduleRoot is a module whose sources are in dir.
+type moduleRoot struct {
+	path string
+	dir  string
+}
+
+// loadModuleGraph reads repoRoot/go.mod, an enclosing go.work (unless GOWORK=off) and
+// vendor/modules.txt. Missing or unreadable workspace and replace targets are skipped.
+func loadModuleGraph(repoRoot, modulePath string, useCache bool) *moduleGraph {
+	g := &moduleGraph{repoRoot: repoRoot, main: modulePath, requires: map[string]string{}}
+	roots := map[string]string{modulePath: repoRoot}
+	replaced := map[string]string{} // module path -> replacement "path@version" in the cache
+
+	addReplaces := func(dir string, directives [][]string) {
+		for _, d := range directives {
+			if d[0] != "replace" {
+				continue
+			}
+			old, repl, ok := parseReplace(d[1:])
+			if !ok {
+				continue
+			}
+			if isLocalModulePath(repl[0]) {
+				target := repl[0]
+				if !filepath.IsAbs(target) {
+					target = filepath.Join(dir, filepath.FromSlash(target))
+				}
+				roots[old] = target
+			} else if len(repl) == 2 {
+				replaced[old] = repl[0] + "@" + repl[1]
+			}
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(repoRoot, "go.mod")); err == nil {
+		directives := goModDirectives(data)
+		for _, d := range directives {
+			if d[0] == "require" && len(d) >= 3 {
+				g.requires[d[1]] = d[1] + "@" + d[2]
+			}
+		}
+		addReplaces(repoRoot, directives)
+	}
+	if work := findGoWork(repoRoot); work != "" {
+		if data, err := os.ReadFile(work); err == nil {
+			dir := filepath.Dir(work)
+			directives := goModDirectives(data)
+			for _, d := range directives {
+				if d[0] != "use" || len(d) < 2 {
+					continue
+				}
+				use := filepath.Join(dir, filepath.FromSlash(d[1]))
+				if mp, err := readModulePath(use); err == nil && mp != modulePath {
+					roots[mp] = use
+				}
+			}
+			addReplaces(dir, directives)
+		}
+	}
+	for mp, pv := range replaced {
+		if _, ok := g.requires[mp]; ok {
+			g.requires[mp] = pv
+		}
+	}
+	for mp, dir := range roots {
+		g.roots = append(g.roots, moduleRoot{path: mp, dir: dir})
+	}
+	sort.Slice(g.roots, func(i, j int) bool {
+		if len(g.roots[i].path) != len(g.roots[j].path) {
+			return len(g.roots[i].path) > len(g.roots[j].path)
+		}
+		return g.roots[i].path < g.roots[j].path
+	})
+	if st, err := os.Stat(filepath.Join(repoRoot, "vendor", "modules.txt")); err == nil && !st.IsDir() {
+		g.vendor = filepath.Join(repoRoot, "vendor")
+	}
+	if useCache {
+		g.cache = moduleCacheDir()
+	}
+	return g
+}
+
+// importDir returns the directory holding the package importPath, if its sources are local.
+func (g *moduleGraph) importDir(importPath string) (string, bool) {
+	if importPath == "" {
+		return "", false
+	}
+	for _, r := range g.roots {
+		if suffix, ok := modulePathSuffix(importPath, r.path); ok {
+			return existingDir(filepath.Join(r.dir, filepath.FromSlash(suffix)))
+		}
+	}
+	if g.vendor != "" {
+		if dir, ok := existingDir(filepath.Join(g.vendor, filepath.FromSlash(importPath))); ok {
+			return dir, true
+		}
+	}
+	if g.cache != "" {
+		// The longest required module path containing importPath owns it, e.g. x/y over x.
+		best := ""
+		for mp := range g.requires {
+			if _, ok := modulePathSuffix(importPath, mp); ok && len(mp) > len(best) {
+				best = mp
+			}
+		}
+		if best != "" {
+			suffix, _ := modulePathSuffix(importPath, best)
+			return existingDir(filepath.Join(g.cache, filepath.FromSlash(escapeModulePath(g.requires[best])), filepath.FromSlash(suffix)))
+		}
+	}
+	return "", false
+}
+
+// relPath is the path part of the address of a symbol defined in abs.
+func (g *moduleGraph) relPath(abs string) (string, error) {
+	if g.vendor != "" {
+		if rel, err := filepath.Rel(g.vendor, abs); err == nil && isLocalRel(rel) {
+			return filepath.ToSlash(rel), nil
+		}
+	}
+	if rel, err := filepath.Rel(g.repoRoot, abs); err == nil && isLocalRel(rel) {
+		return filepath.ToSlash(rel), nil
+	}
+	for _, r := range g.roots {
+		if rel, err := filepath.Rel(r.dir, abs); err == nil && isLocalRel(rel) {
+			return r.path + "/" + filepath.ToSlash(rel), nil
+		}
+	}
+	if g.cache != "" {
+		if rel, err := filepath.Rel(g.cache, abs); err == nil && isLocalRel(rel) {
+			return unescapeCachePath(filepath.ToSlash(rel)), nil
+		}
+	}
+	return toPosixRel(g.repoRoot, abs)
+}
+
+func isLocalRel(rel string) bool {
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+func modulePathSuffix(importPath, modulePath string) (string, bool) {
+	if importPath == modulePath {
+		return "", true
+	}
+	if rest, ok := strings.CutPrefix(importPath, modulePath+"/"); ok {
+		return rest, true
+	}
+	return "", false
+}
+
+func existingDir(dir string) (string, bool) {
+	st, err := os.Stat(dir)
+	if err != nil || !st.IsDir() {
+		return "", false
+	}
+	return dir, true
+}
+
+// goModDirectives splits a go.mod or go.work file into directives, one per line, with blocks
+// expanded: "require ( a v1 )" yields ["require", "a", "v1"]. Comments are dropped and quoted
+// paths unquoted.
+func goModDirectives(data []byte) [][]string {
+	var out [][]string
+	var block string
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		for i, f := range fields {
+			if u, err := strconv.Unquote(f); err == nil {
+				fields[i] = u
+			}
+		}
+		switch {
+		case block != "" && fields[0] == ")":
+			block = ""
+		case block != "":
+			out = append(out, append([]string{block}, fields...))
+		case len(fields) == 2 && fields[1] == "(":
+			block = fields[0]
+		default:
+			out = append(out, fields)
+		}
+	}
+	return out
+}
+
+// parseReplace splits the arguments of a replace directive, "old [v] => new [v]".
+func parseReplace(args []string) (old string, repl []string, ok bool) {
+	for i, a := range args {
+		if a == "=>" && i > 0 && i+1 < len(args) {
+			return args[0], args[i+1:], true
+		}
+	}
+	return "", nil, false
+}
+
+func isLocalModulePath(p string) bool {
+	return strings.HasPrefix(p, "./") || strings.HasPrefix(p, "../") || filepath.IsAbs(p)
+}
+
+// findGoWork returns $GOWORK, or the nearest go.work at or above dir; "" when workspaces are
+// off or there is none.
+func findGoWork(dir string) string {
+	switch gw := os.Getenv("GOWORK"); gw {
+	case "off":
+		return ""
+	case "":
+	default:
+		return gw
+	}
+	for {
+		p := filepath.Join(dir, "go.work")
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// moduleCacheDir returns $GOMODCACHE, else the first $GOPATH entry (or ~/go) plus pkg/mod.
+func moduleCacheDir() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	gopath := filepath.SplitList(os.Getenv("GOPATH"))
+	if len(gopath) > 0 && gopath[0] != "" {
+		return filepath.Join(gopath[0], "pkg", "mod")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, "go", "pkg", "mod")
+}
+
+// escapeModulePath applies the module cache's case encoding: each upper-case letter becomes
+// '!' followed by its lower-case form.
+func escapeModulePath(p string) string {
+	var b strings.Builder
+	for _, r := range p {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// unescapeCachePath turns a path inside the module cache back into modulePath/relPath,
+// dropping the @version of the module directory.
+func unescapeCachePath(rel string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range rel {
+		switch {
+		case r == '!':
+			upper = true
+			continue
+		case upper:
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	parts := strings.Split(b.String(), "/")
+	for i, part := range parts {
+		if at := strings.IndexByte(part, '@'); at >= 0 {
+			parts[i] = part[:at]
+			break
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// packageName returns the package name an unnamed import of importPath binds: the name in the
+// package clause of the located package, else the guess of importLocalName.
+func (g *moduleGraph) packageName(importPath string) string {
+	if g == nil {
+		return importLocalName(importPath)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if name, ok := g.names[importPath]; ok {
+		return name
+	}
+	name := importLocalName(importPath)
+	if dir, ok := g.importDir(importPath); ok {
+		if clause := packageClause(dir); clause != "" {
+			name = clause
+		}
+	}
+	if g.names == nil {
+		g.names = map[string]string{}
+	}
+	g.names[importPath] = name
+	return name
+}
+
+// packageClause returns the package name declared by the first non-test Go file in dir, or "".
+func packageClause(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), filepath.Join(dir, name), nil, parser.PackageClauseOnly)
+		if err == nil {
+			return f.Name.Name
+		}
+	}
+	return ""
+}
+
+var majorVersionElem = regexp.MustCompile(`^v[0-9]+$`)
+
+// importLocalName guesses the package name an unnamed import binds when its package cannot be
+// read: the last path element, skipping a /vN major-version suffix and a gopkg.in .vN suffix.
+func importLocalName(importPath string) string {
+	base := path.Base(importPath)
+	if majorVersionElem.MatchString(base) && path.Dir(importPath) != "." {
+		base = path.Base(path.Dir(importPath))
+	}
+	if strings.HasPrefix(importPath, "gopkg.in/") {
+		if i := strings.LastIndex(base, ".v"); i > 0 {
+			base = base[:i]
+		}
+	}
+	return base
+}
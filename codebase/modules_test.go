@@ -0,0 +1,165 @@
+package codebase
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestBuildCodebaseForFilesModules resolves calls into a go.work module, a local replace,
+// modules in the module cache (one whose package name differs from its path) and a vendored
+// module, each addressed by module path.
+func TestBuildCodebaseForFilesModules(t *testing.T) {
+	ws := t.TempDir()
+	writeTestModule(t, ws, map[string]string{
+		"go.work": "go 1.25\n\nuse (\n\t./app\n\t./shared\n)\n",
+		"app/go.mod": `module example.com/app
+
+go 1.25
+
+require (
+	example.com/shared v0.0.0 // indirect
+	example.com/lib v0.0.0
+	example.com/Corp/cached/v2 v2.1.0
+	example.com/go-git/v5 v5.4.0
+)
+
+replace example.com/lib => ../lib
+`,
+		"app/main.go": `package main
+
+import (
+	"example.com/Corp/cached/v2"
+	"example.com/go-git/v5"
+	"example.com/lib"
+	"example.com/shared/util"
+)
+
+func main() {
+	util.Hello()
+	lib.Do()
+	cached.Get()
+	git.PlainOpen()
+}
+`,
+		"shared/go.mod":       "module example.com/shared\n\ngo 1.25\n",
+		"shared/util/util.go": "package util\n\nfunc Hello() {}\n",
+		"lib/go.mod":          "module example.com/lib\n\ngo 1.25\n",
+		"lib/lib.go":          "package lib\n\nfunc Do() {}\n",
+		"modcache/example.com/!corp/cached/v2@v2.1.0/c.go": "package cached\n\nfunc Get() {}\n",
+		"modcache/example.com/go-git/v5@v5.4.0/repo.go":    "package git\n\nfunc PlainOpen() {}\n",
+		"vend/go.mod":                       "module example.com/vend\n\ngo 1.25\n\nrequire example.com/vdep v1.0.0\n",
+		"vend/vendor/modules.txt":           "# example.com/vdep v1.0.0\n## explicit\nexample.com/vdep\n",
+		"vend/vendor/example.com/vdep/v.go": "package vdep\n\nfunc Run() {}\n",
+		"vend/main.go":                      "package main\n\nimport \"example.com/vdep\"\n\nfunc main() { vdep.Run() }\n",
+	})
+	t.Setenv("GOWORK", "")
+	t.Setenv("GOMODCACHE", filepath.Join(ws, "modcache"))
+
+	tests := []struct {
+		name    string
+		root    string
+		opt     BuildOptions
+		callees []string
+	}{
+		{
+			name:    "workspace and replace",
+			root:    "app",
+			callees: []string{"example.com/lib/lib.go::Do", "example.com/shared/util/util.go::Hello"},
+		},
+		{
+			name: "with module cache",
+			root: "app",
+			opt:  BuildOptions{ModuleCache: true},
+			callees: []string{
+				"example.com/Corp/cached/v2/c.go::Get", "example.com/go-git/v5/repo.go::PlainOpen",
+				"example.com/lib/lib.go::Do", "example.com/shared/util/util.go::Hello",
+			},
+		},
+		{
+			name:    "vendor",
+			root:    "vend",
+			callees: []string{"example.com/vdep/v.go::Run"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := filepath.Join(ws, tt.root)
+			tt.opt.RepoRoot = root
+			cb, err := BuildCodebaseForFiles([]string{filepath.Join(root, "main.go")}, tt.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, s := range cb.Symbols {
+				if s.Address == "main.go::main" {
+					got = s.CallsTo
+				}
+			}
+			if len(got) != len(tt.callees) {
+				t.Fatalf("main calls %v, want %v", got, tt.callees)
+			}
+			for i := range got {
+				if got[i] != tt.callees[i] {
+					t.Errorf("main calls %v, want %v", got, tt.callees)
+				}
+			}
+		})
+	}
+}
+
+// TestBuildCodebaseForFilesModuleSymbols checks that symbols indexed from a replaced module
+// outside the repo are addressed by module path, like the edges that reach them.
+func TestBuildCodebaseForFilesModuleSymbols(t *testing.T) {
+	ws := t.TempDir()
+	writeTestModule(t, ws, map[string]string{
+		"app/go.mod":  "module example.com/app\n\ngo 1.25\n\nrequire example.com/b v0.0.0\n\nreplace example.com/b => ../b\n",
+		"app/main.go": "package main\n\nimport \"example.com/b\"\n\nfunc main() { b.Helper() }\n",
+		"b/go.mod":    "module example.com/b\n\ngo 1.25\n",
+		"b/b.go":      "package b\n\nfunc Helper() { helper() }\n\nfunc helper() {}\n",
+	})
+	t.Setenv("GOWORK", "")
+
+	root := filepath.Join(ws, "app")
+	cb, err := BuildCodebaseForFiles([]string{root, filepath.Join(ws, "b")}, BuildOptions{RepoRoot: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byAddr := map[string]CodeSymbol{}
+	for _, s := range cb.Symbols {
+		byAddr[s.Address] = s
+	}
+	for _, addr := range []string{"example.com/b/b.go::Helper", "example.com/b/b.go::helper"} {
+		if _, ok := byAddr[addr]; !ok {
+			t.Errorf("missing symbol %q", addr)
+		}
+	}
+	if got := byAddr["main.go::main"].CallsTo; len(got) != 1 || got[0] != "example.com/b/b.go::Helper" {
+		t.Errorf("main calls %v, want [example.com/b/b.go::Helper]", got)
+	}
+	if got := byAddr["example.com/b/b.go::Helper"].CalledBy; len(got) != 1 || got[0] != "main.go::main" {
+		t.Errorf("Helper called by %v, want [main.go::main]", got)
+	}
+	if got := byAddr["example.com/b/b.go::Helper"].CallsTo; len(got) != 1 || got[0] != "example.com/b/b.go::helper" {
+		t.Errorf("Helper calls %v, want [example.com/b/b.go::helper]", got)
+	}
+}
+
+// TestImportLocalName checks the package name assumed for unnamed imports.
+func TestImportLocalName(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{path: "example.com/lib", want: "lib"},
+		{path: "example.com/Corp/cached/v2", want: "cached"},
+		{path: "gopkg.in/yaml.v3", want: "yaml"},
+		{path: "v2", want: "v2"},
+	}
+
+	for _, tt := range tests {
+		if got := importLocalName(tt.path); got != tt.want {
+			t.Errorf("importLocalName(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package codebase
+
+import (
+	"runtime"
+	"sync"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_go "github.com/tree-sitter/tree-sitter-go/bindings/go"
+)
+
+func newGoParser() (*sitter.Parser, error) {
+	p := sitter.NewParser()
+	lang := sitter.NewLanguage(tree_sitter_go.Language())
+	if err := p.SetLanguage(lang); err != nil {
+		p.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// parserPool hands out parsers to workers. Tree-sitter parsers are not safe for concurrent use,
+// so a parser is owned by one goroutine between get and put.
+type parserPool struct {
+	mu   sync.Mutex
+	free []*sitter.Parser
+	all  []*sitter.Parser
+}
+
+func (pp *parserPool) get() (*sitter.Parser, error) {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	if n := len(pp.free); n > 0 {
+		p := pp.free[n-1]
+		pp.free = pp.free[:n-1]
+		return p, nil
+	}
+	p, err := newGoParser()
+	if err != nil {
+		return nil, err
+	}
+	pp.all = append(pp.all, p)
+	return p, nil
+}
+
+func (pp *parserPool) put(p *sitter.Parser) {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	pp.free = append(pp.free, p)
+}
+
+func (pp *parserPool) close() {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	for _, p := range pp.all {
+		p.Close()
+	}
+	pp.all, pp.free = nil, nil
+}
+
+func workerCount(concurrency int) int {
+	if concurrency <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return concurrency
+}
+
+// parallel calls fn(i, parser) for every i in [0, n) on up to workers goroutines. Each goroutine
+// holds one pooled parser for its lifetime. Callers write results into per-index slots so the
+// output never depends on scheduling; the error returned is the one with the lowest index.
+func parallel(pool *parserPool, workers, n int, fn func(i int, p *sitter.Parser) error) error {
+	if workers > n {
+		workers = n
+	}
+	errs := make([]error, n)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		p, err := pool.get()
+		if err != nil {
+			close(next)
+			wg.Wait()
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer pool.put(p)
+			for i := range next {
+				errs[i] = fn(i, p)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
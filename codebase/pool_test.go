@@ -0,0 +1,88 @@
+package codebase
+
+import (
+	"path/filepath"
+	"testing"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// TestBuildCodebaseForFilesConcurrency checks that output is identical for every worker count.
+func TestBuildCodebaseForFilesConcurrency(t *testing.T) {
+	repoRoot := findRepoRootForTest(t)
+	roots := []string{filepath.Join(repoRoot, "codebase", "testdata")}
+	serial, err := BuildCodebaseForFiles(roots, BuildOptions{RepoRoot: repoRoot, Concurrency: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := codeBaseJSON(t, serial)
+
+	tests := []struct {
+		name        string
+		concurrency int
+	}{
+		{name: "default worker count", concurrency: 0},
+		{name: "two workers", concurrency: 2},
+		{name: "more workers than files", concurrency: 64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb, err := BuildCodebaseForFiles(roots, BuildOptions{RepoRoot: repoRoot, Concurrency: tt.concurrency})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := codeBaseJSON(t, cb); got != want {
+				t.Errorf("Concurrency=%d output differs from serial build\ngot:  %s\nwant: %s", tt.concurrency, got, want)
+			}
+		})
+	}
+}
+
+// TestParallel checks result slots, error precedence and the empty case of parallel.
+func TestParallel(t *testing.T) {
+	tests := []struct {
+		name      string
+		workers   int
+		n         int
+		failAt    []int
+		wantError int // index whose error must be returned, -1 for none
+	}{
+		{name: "no items", workers: 4, n: 0, wantError: -1},
+		{name: "all succeed", workers: 3, n: 10, wantError: -1},
+		{name: "lowest failing index wins", workers: 4, n: 10, failAt: []int{7, 2, 5}, wantError: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := &parserPool{}
+			t.Cleanup(pool.close)
+			out := make([]int, tt.n)
+			errs := map[int]error{}
+			for _, i := range tt.failAt {
+				errs[i] = &indexError{i: i}
+			}
+			err := parallel(pool, tt.workers, tt.n, func(i int, _ *sitter.Parser) error {
+				out[i] = i * i
+				return errs[i]
+			})
+			if tt.wantError < 0 {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				for i, v := range out {
+					if v != i*i {
+						t.Errorf("slot %d = %d; want %d", i, v, i*i)
+					}
+				}
+				return
+			}
+			ie, ok := err.(*indexError)
+			if !ok || ie.i != tt.wantError {
+				t.Errorf("error = %v; want failure at index %d", err, tt.wantError)
+			}
+		})
+	}
+}
+
+type indexError struct{ i int }
+
+func (e *indexError) Error() string { return "failed" }
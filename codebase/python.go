@@ -0,0 +1,327 @@
+package codebase
+
+import (
+	"path"
+	"strings"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_python "github.com/tree-sitter/tree-sitter-python/bindings/go"
+)
+
+type pythonFrontend struct {
+	lang *sitter.Language
+}
+
+// NewPythonFrontend returns the frontend for .py files. It emits module-level functions and
+// ALL_CAPS constants, classes, and methods defined directly in a class body. Calls resolve to
+// the same module, names brought in by import / from-import of modules inside the repo, and
+// self./cls. calls to methods of the enclosing class.
+func NewPythonFrontend() Frontend {
+	return &pythonFrontend{lang: sitter.NewLanguage(tree_sitter_python.Language())}
+}
+
+func (*pythonFrontend) Name() string                       { return "python" }
+func (*pythonFrontend) Extensions() []string               { return []string{".py"} }
+func (fe *pythonFrontend) Grammar(string) *sitter.Language { return fe.lang }
+
+func (fe *pythonFrontend) Index(repoRoot string, files []*SourceFile) ([]CodeSymbol, []CallEdge, error) {
+	modules := map[string]struct{}{}
+	for _, sf := range files {
+		modules[sf.Rel] = struct{}{}
+	}
+	var scripts []*scriptFile
+	var syms []CodeSymbol
+	for _, sf := range files {
+		f, err := pythonFile(repoRoot, sf, modules)
+		if err != nil {
+			return nil, nil, err
+		}
+		scripts = append(scripts, f)
+		syms = append(syms, f.symbols()...)
+	}
+	return syms, resolveScriptCalls(scripts), nil
+}
+
+func pythonFile(repoRoot string, sf *SourceFile, modules map[string]struct{}) (*scriptFile, error) {
+	f := newScriptFile(sf.Rel)
+	root := sf.Tree.RootNode()
+	for i := uint(0); i < root.NamedChildCount(); i++ {
+		st := root.NamedChild(i)
+		def, anchor := pythonDefinition(st)
+		switch def.Kind() {
+		case "function_definition":
+			if err := pythonFunction(f, repoRoot, sf, def, anchor, ""); err != nil {
+				return nil, err
+			}
+		case "class_definition":
+			if err := pythonClass(f, repoRoot, sf, def, anchor); err != nil {
+				return nil, err
+			}
+		case "expression_statement":
+			if err := pythonConstant(f, repoRoot, sf, def); err != nil {
+				return nil, err
+			}
+		case "import_statement", "import_from_statement":
+			pythonImports(f, sf, def, modules)
+		}
+	}
+	return f, nil
+}
+
+// pythonDefinition unwraps a decorated_definition; anchor is the node comments sit above.
+func pythonDefinition(st *sitter.Node) (def, anchor *sitter.Node) {
+	if st.Kind() == "decorated_definition" {
+		if d := st.ChildByFieldName("definition"); d != nil {
+			return d, st
+		}
+	}
+	return st, st
+}
+
+func pythonFunction(f *scriptFile, repoRoot string, sf *SourceFile, def, anchor *sitter.Node, class string) error {
+	name := nodeText(sf.Src, def.ChildByFieldName("name"))
+	if name == "" {
+		return nil
+	}
+	qual, kind := name, "function"
+	var recv *string
+	if class != "" {
+		qual, kind = class+"."+name, "method"
+		rt := class
+		recv = &rt
+	}
+	addr, err := SymbolAddress(repoRoot, sf.Abs, qual)
+	if err != nil {
+		return err
+	}
+	f.addSymbol(CodeSymbol{
+		Name: name, Kind: kind,
+		LineStart: lineStart1(anchor), LineEnd: lineEnd1(anchor),
+		LineCode: lineSnippet(sf.Src, def), FilePath: sf.Rel, Address: addr,
+		Docstring:      pythonDocstring(def.ChildByFieldName("body"), sf.Src),
+		LeadingComment: pythonComments(anchor, sf.Src),
+		ReceiverType:   recv,
+	})
+	pythonCalls(f, sf.Src, def.ChildByFieldName("body"), addr, class)
+	return nil
+}
+
+func pythonClass(f *scriptFile, repoRoot string, sf *SourceFile, def, anchor *sitter.Node) error {
+	name := nodeText(sf.Src, def.ChildByFieldName("name"))
+	if name == "" {
+		return nil
+	}
+	addr, err := SymbolAddress(repoRoot, sf.Abs, name)
+	if err != nil {
+		return err
+	}
+	body := def.ChildByFieldName("body")
+	f.addSymbol(CodeSymbol{
+		Name: name, Kind: "class",
+		LineStart: lineStart1(anchor), LineEnd: lineEnd1(anchor),
+		LineCode: lineSnippet(sf.Src, def), FilePath: sf.Rel, Address: addr,
+		Docstring:      pythonDocstring(body, sf.Src),
+		LeadingComment: pythonComments(anchor, sf.Src),
+		MethodSet:      []string{}, PointerMethodSet: []string{},
+	})
+	if body == nil {
+		return nil
+	}
+	for i := uint(0); i < body.NamedChildCount(); i++ {
+		m, manchor := pythonDefinition(body.NamedChild(i))
+		if m.Kind() != "function_definition" {
+			continue
+		}
+		if err := pythonFunction(f, repoRoot, sf, m, manchor, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pythonConstant records NAME = value at module level when NAME is ALL_CAPS.
+func pythonConstant(f *scriptFile, repoRoot string, sf *SourceFile, st *sitter.Node) error {
+	as := st.NamedChild(0)
+	if as == nil || as.Kind() != "assignment" {
+		return nil
+	}
+	left := as.ChildByFieldName("left")
+	if left == nil || left.Kind() != "identifier" {
+		return nil
+	}
+	name := nodeText(sf.Src, left)
+	if !constNameGo.MatchString(name) {
+		return nil
+	}
+	addr, err := SymbolAddress(repoRoot, sf.Abs, name)
+	if err != nil {
+		return err
+	}
+	val := truncatedValue(sf.Src, as.ChildByFieldName("right"))
+	f.addSymbol(CodeSymbol{
+		Name: name, Kind: "constant",
+		LineStart: lineStart1(st), LineEnd: lineEnd1(st),
+		LineCode: lineSnippet(sf.Src, st), FilePath: sf.Rel, Address: addr,
+		ConstantValue:  &val,
+		LeadingComment: pythonComments(st, sf.Src),
+	})
+	return nil
+}
+
+// pythonDocstring returns the string literal opening a def or class body.
+func pythonDocstring(body *sitter.Node, src []byte) *string {
+	if body == nil || body.NamedChildCount() == 0 {
+		return nil
+	}
+	st := body.NamedChild(0)
+	if st.Kind() != "expression_statement" || st.NamedChildCount() == 0 || st.NamedChild(0).Kind() != "string" {
+		return nil
+	}
+	var b strings.Builder
+	s := st.NamedChild(0)
+	for i := uint(0); i < s.NamedChildCount(); i++ {
+		if ch := s.NamedChild(i); ch.Kind() == "string_content" {
+			b.WriteString(nodeText(src, ch))
+		}
+	}
+	doc := strings.TrimSpace(b.String())
+	if doc == "" {
+		return nil
+	}
+	return &doc
+}
+
+func pythonComments(anchor *sitter.Node, src []byte) *string {
+	return joinComments(src, commentsAbove(anchor), func(c string) []string {
+		return []string{strings.TrimSpace(strings.TrimPrefix(c, "#"))}
+	})
+}
+
+// pythonCalls records the calls in a def body, including nested functions and lambdas, which
+// run on behalf of the enclosing symbol. Nested classes are skipped.
+func pythonCalls(f *scriptFile, src []byte, body *sitter.Node, caller, class string) {
+	var walk func(n *sitter.Node)
+	walk = func(n *sitter.Node) {
+		if n == nil || n.Kind() == "class_definition" {
+			return
+		}
+		if n.Kind() == "call" {
+			if c, ok := pythonCall(src, n.ChildByFieldName("function")); ok {
+				c.caller, c.class, c.line = caller, class, lineStart1(n)
+				c.self = c.self && class != ""
+				f.calls = append(f.calls, c)
+			}
+		}
+		for i := uint(0); i < n.NamedChildCount(); i++ {
+			walk(n.NamedChild(i))
+		}
+	}
+	walk(body)
+}
+
+func pythonCall(src []byte, fn *sitter.Node) (scriptCall, bool) {
+	if fn == nil {
+		return scriptCall{}, false
+	}
+	switch fn.Kind() {
+	case "identifier":
+		return scriptCall{name: nodeText(src, fn)}, true
+	case "attribute":
+		obj, attr := fn.ChildByFieldName("object"), fn.ChildByFieldName("attribute")
+		if obj == nil || attr == nil || obj.Kind() != "identifier" {
+			return scriptCall{}, false
+		}
+		o := nodeText(src, obj)
+		return scriptCall{object: o, self: o == "self" || o == "cls", name: nodeText(src, attr)}, true
+	}
+	return scriptCall{}, false
+}
+
+// pythonImports binds the local names of an import statement to modules in the repo.
+func pythonImports(f *scriptFile, sf *SourceFile, st *sitter.Node, modules map[string]struct{}) {
+	for i := uint(0); i < st.ChildCount(); i++ {
+		if st.FieldNameForChild(uint32(i)) != "name" {
+			continue
+		}
+		n := st.Child(i)
+		dotted, local := n, ""
+		if n.Kind() == "aliased_import" {
+			dotted = n.ChildByFieldName("name")
+			local = nodeText(sf.Src, n.ChildByFieldName("alias"))
+		}
+		name := nodeText(sf.Src, dotted)
+		if st.Kind() == "import_statement" {
+			if local == "" {
+				// import a.b binds a; only the top package can be called through.
+				name, _, _ = strings.Cut(name, ".")
+				local = name
+			}
+			if file, ok := pythonModuleFile(sf.Rel, name, modules); ok {
+				f.imports[local] = scriptImport{file: file}
+			}
+			continue
+		}
+		if local == "" {
+			local = name
+		}
+		base := pythonFromModule(sf.Src, st.ChildByFieldName("module_name"))
+		if file, ok := pythonModuleFile(sf.Rel, joinModule(base, name), modules); ok {
+			f.imports[local] = scriptImport{file: file}
+		} else if file, ok := pythonModuleFile(sf.Rel, base, modules); ok {
+			f.imports[local] = scriptImport{file: file, name: name}
+		}
+	}
+}
+
+// pythonFromModule returns the module of a from-import as written: "pkg.mod", or with leading
+// dots for relative imports ("..pkg").
+func pythonFromModule(src []byte, n *sitter.Node) string {
+	return strings.Join(strings.Fields(nodeText(src, n)), "")
+}
+
+func joinModule(base, name string) string {
+	if base == "" || strings.HasSuffix(base, ".") {
+		return base + name
+	}
+	return base + "." + name
+}
+
+// pythonModuleFile finds the indexed file for a module name. Relative names start from the
+// importing file's package; absolute names are tried from the repo root and then from every
+// directory between it and the importing file, since sources often live under src/ or a
+// service directory rather than the root.
+func pythonModuleFile(fromRel, module string, modules map[string]struct{}) (string, bool) {
+	if module == "" {
+		return "", false
+	}
+	dir := path.Dir(fromRel)
+	var bases []string
+	if strings.HasPrefix(module, ".") {
+		rest := strings.TrimLeft(module, ".")
+		for up := len(module) - len(rest) - 1; up > 0; up-- {
+			dir = path.Dir(dir)
+		}
+		if rest == "" {
+			cand := path.Join(dir, "__init__.py")
+			_, ok := modules[cand]
+			return cand, ok
+		}
+		bases, module = []string{dir}, rest
+	} else {
+		for d := dir; ; d = path.Dir(d) {
+			bases = append([]string{d}, bases...)
+			if d == "." || d == "/" {
+				break
+			}
+		}
+	}
+	p := strings.ReplaceAll(module, ".", "/")
+	for _, b := range bases {
+		for _, cand := range []string{path.Join(b, p+".py"), path.Join(b, p, "__init__.py")} {
+			if _, ok := modules[cand]; ok {
+				return cand, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,131 @@
+package codebase
+
+import (
+	"testing"
+)
+
+// frontendEdge reports whether cb has a call edge from caller to callee.
+func frontendEdge(cb *CodeBase, caller, callee string) bool {
+	for _, e := range cb.Calls {
+		if e.CallerAddress == caller && e.CalleeAddress == callee {
+			return true
+		}
+	}
+	return false
+}
+
+// TestPythonFrontend indexes a small mixed Go/Python repo and checks Python symbols, import
+// resolution, self. calls, and that .py files are ignored without frontends.
+func TestPythonFrontend(t *testing.T) {
+	root := t.TempDir()
+	writeTestModule(t, root, map[string]string{
+		"go.mod":          "module example.com/p\n\ngo 1.25\n",
+		"main.go":         "package main\n\nfunc main() {}\n",
+		"svc/__init__.py": "",
+		"svc/util.py": `MAX_RETRIES = 3
+
+
+def helper(x):
+    """Return x doubled."""
+    return x * 2
+`,
+		"svc/app.py": `import os
+from . import util
+from .util import helper as h
+
+
+# Service handles requests.
+class Service:
+    """A service."""
+
+    def run(self):
+        self.step()
+        return h(1)
+
+    @staticmethod
+    def step():
+        util.helper(2)
+        os.getcwd()
+
+
+def main():
+    Service().run()
+`,
+		"node_modules/skip.py": "def skipped():\n    pass\n",
+	})
+
+	goOnly, err := BuildCodebaseForFiles([]string{root}, BuildOptions{RepoRoot: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(goOnly.Symbols) != 1 {
+		t.Errorf("without frontends got %d symbols, want only main.go::main", len(goOnly.Symbols))
+	}
+
+	cb, err := BuildCodebaseForFiles([]string{root}, BuildOptions{RepoRoot: root, Frontends: []Frontend{NewPythonFrontend()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byAddr := map[string]CodeSymbol{}
+	for _, s := range cb.Symbols {
+		byAddr[s.Address] = s
+	}
+
+	symTests := []struct {
+		address, kind string
+	}{
+		{address: "main.go::main", kind: "function"},
+		{address: "svc/util.py::MAX_RETRIES", kind: "constant"},
+		{address: "svc/util.py::helper", kind: "function"},
+		{address: "svc/app.py::Service", kind: "class"},
+		{address: "svc/app.py::Service.run", kind: "method"},
+		{address: "svc/app.py::Service.step", kind: "method"},
+		{address: "svc/app.py::main", kind: "function"},
+	}
+	for _, tt := range symTests {
+		t.Run(tt.address, func(t *testing.T) {
+			s, ok := byAddr[tt.address]
+			if !ok {
+				t.Fatalf("missing symbol %q", tt.address)
+			}
+			if s.Kind != tt.kind {
+				t.Errorf("Kind = %q; want %q", s.Kind, tt.kind)
+			}
+		})
+	}
+	if _, ok := byAddr["node_modules/skip.py::skipped"]; ok {
+		t.Error("node_modules should not be indexed")
+	}
+	if s := byAddr["svc/util.py::helper"]; s.Docstring == nil || *s.Docstring != "Return x doubled." {
+		t.Errorf("helper docstring = %v", s.Docstring)
+	}
+	svc := byAddr["svc/app.py::Service"]
+	if svc.LeadingComment == nil || *svc.LeadingComment != "Service handles requests." {
+		t.Errorf("Service comment = %v", svc.LeadingComment)
+	}
+	if want := []string{"svc/app.py::Service.run", "svc/app.py::Service.step"}; len(svc.MethodSet) != 2 || svc.MethodSet[0] != want[0] || svc.MethodSet[1] != want[1] {
+		t.Errorf("Service method set = %v; want %v", svc.MethodSet, want)
+	}
+	if s := byAddr["svc/app.py::Service.step"]; s.ReceiverAddress == nil || *s.ReceiverAddress != "svc/app.py::Service" {
+		t.Errorf("step receiver = %v", s.ReceiverAddress)
+	}
+
+	edgeTests := []struct {
+		caller, callee string
+	}{
+		{caller: "svc/app.py::Service.run", callee: "svc/app.py::Service.step"},
+		{caller: "svc/app.py::Service.run", callee: "svc/util.py::helper"},
+		{caller: "svc/app.py::Service.step", callee: "svc/util.py::helper"},
+		{caller: "svc/app.py::main", callee: "svc/app.py::Service"},
+	}
+	for _, tt := range edgeTests {
+		t.Run(tt.caller+"->"+tt.callee, func(t *testing.T) {
+			if !frontendEdge(cb, tt.caller, tt.callee) {
+				t.Errorf("missing edge; calls=%+v", cb.Calls)
+			}
+		})
+	}
+	if got := byAddr["svc/util.py::helper"].CalledBy; len(got) != 2 {
+		t.Errorf("helper CalledBy = %v; want both Service methods", got)
+	}
+}
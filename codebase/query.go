@@ -0,0 +1,271 @@
+package codebase
+
+import (
+	"sort"
+)
+
+// CallGraph indexes a CodeBase's call edges by caller and callee for repeated queries.
+// It does not copy the CodeBase; do not modify it while the graph is in use.
+type CallGraph struct {
+	cb      *CodeBase
+	symbols map[string]*CodeSymbol
+	out     map[string][]CallEdge // caller -> edges, sorted by callee then line
+	in      map[string][]CallEdge // callee -> edges, sorted by caller then line
+	nodes   []string              // every symbol and edge end, sorted
+}
+
+// Reached is one address found by a transitive query, Depth calls away from the start.
+type Reached struct {
+	Address string `json:"address"`
+	Depth   int    `json:"depth"`
+}
+
+// Ranked is one address in a fan-in or fan-out ranking. Count is the number of distinct
+// callers (fan-in) or callees (fan-out), not call sites.
+type Ranked struct {
+	Address string `json:"address"`
+	Count   int    `json:"count"`
+}
+
+// NewCallGraph indexes cb.
+func NewCallGraph(cb *CodeBase) *CallGraph {
+	g := &CallGraph{
+		cb:      cb,
+		symbols: map[string]*CodeSymbol{},
+		out:     map[string][]CallEdge{},
+		in:      map[string][]CallEdge{},
+	}
+	seen := map[string]bool{}
+	addNode := func(addr string) {
+		if !seen[addr] {
+			seen[addr] = true
+			g.nodes = append(g.nodes, addr)
+		}
+	}
+	for i := range cb.Symbols {
+		g.symbols[cb.Symbols[i].Address] = &cb.Symbols[i]
+		addNode(cb.Symbols[i].Address)
+	}
+	for _, e := range cb.Calls {
+		g.out[e.CallerAddress] = append(g.out[e.CallerAddress], e)
+		g.in[e.CalleeAddress] = append(g.in[e.CalleeAddress], e)
+		addNode(e.CallerAddress)
+		addNode(e.CalleeAddress)
+	}
+	for _, es := range g.out {
+		sortEdges(es, func(e CallEdge) string { return e.CalleeAddress })
+	}
+	for _, es := range g.in {
+		sortEdges(es, func(e CallEdge) string { return e.CallerAddress })
+	}
+	sort.Strings(g.nodes)
+	return g
+}
+
+// LoadCallGraph reads a CodeBase written by SaveToJSONFileCodeBase or SaveToSQLiteFileCodeBase
+// and indexes it.
+func LoadCallGraph(filePath string) (*CallGraph, error) {
+	cb, err := LoadCodeBase(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return NewCallGraph(cb), nil
+}
+
+func sortEdges(es []CallEdge, other func(CallEdge) string) {
+	sort.SliceStable(es, func(i, j int) bool {
+		if a, b := other(es[i]), other(es[j]); a != b {
+			return a < b
+		}
+		return es[i].CallLine < es[j].CallLine
+	})
+}
+
+// CodeBase returns the indexed CodeBase.
+func (g *CallGraph) CodeBase() *CodeBase {
+	return g.cb
+}
+
+// Symbol returns the symbol at addr, or nil.
+func (g *CallGraph) Symbol(addr string) *CodeSymbol {
+	return g.symbols[addr]
+}
+
+// Callers returns the call edges into addr, ordered by caller address and line.
+func (g *CallGraph) Callers(addr string) []CallEdge {
+	return append([]CallEdge{}, g.in[addr]...)
+}
+
+// Callees returns the call edges out of addr, ordered by callee address and line.
+func (g *CallGraph) Callees(addr string) []CallEdge {
+	return append([]CallEdge{}, g.out[addr]...)
+}
+
+// TransitiveCallers returns every address that reaches addr within depth calls (unbounded if
+// depth <= 0), in breadth-first order. addr itself is only included if it is recursive.
+func (g *CallGraph) TransitiveCallers(addr string, depth int) []Reached {
+	return g.walk(addr, depth, g.in, func(e CallEdge) string { return e.CallerAddress })
+}
+
+// TransitiveCallees returns every address addr reaches within depth calls (unbounded if
+// depth <= 0), in breadth-first order. addr itself is only included if it is recursive.
+func (g *CallGraph) TransitiveCallees(addr string, depth int) []Reached {
+	return g.walk(addr, depth, g.out, func(e CallEdge) string { return e.CalleeAddress })
+}
+
+func (g *CallGraph) walk(start string, depth int, adj map[string][]CallEdge, next func(CallEdge) string) []Reached {
+	out := []Reached{}
+	seen := map[string]bool{}
+	frontier := []string{start}
+	for d := 1; len(frontier) > 0 && (depth <= 0 || d <= depth); d++ {
+		var nextFrontier []string
+		for _, n := range frontier {
+			for _, e := range adj[n] {
+				m := next(e)
+				if seen[m] {
+					continue
+				}
+				seen[m] = true
+				out = append(out, Reached{Address: m, Depth: d})
+				nextFrontier = append(nextFrontier, m)
+			}
+		}
+		frontier = nextFrontier
+	}
+	return out
+}
+
+// ShortestPath returns the fewest call edges leading from one address to another. Ties resolve
+// to the lexically smallest callees, so the answer is stable. It returns nil when to is not
+// reachable and an empty slice when from == to.
+func (g *CallGraph) ShortestPath(from, to string) []CallEdge {
+	via := map[string]CallEdge{}
+	seen := map[string]bool{from: true}
+	queue := []string{from}
+	for len(queue) > 0 && !seen[to] {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, e := range g.out[cur] {
+			if seen[e.CalleeAddress] {
+				continue
+			}
+			seen[e.CalleeAddress] = true
+			via[e.CalleeAddress] = e
+			queue = append(queue, e.CalleeAddress)
+		}
+	}
+	if !seen[to] {
+		return nil
+	}
+	path := []CallEdge{}
+	for cur := to; cur != from; cur = via[cur].CallerAddress {
+		path = append(path, via[cur])
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// StronglyConnectedComponents partitions every address into strongly connected components
+// (Tarjan). Each component is sorted, and components are ordered by their first address.
+func (g *CallGraph) StronglyConnectedComponents() [][]string {
+	var (
+		index   = map[string]int{}
+		low     = map[string]int{}
+		onStack = map[string]bool{}
+		stack   []string
+		out     [][]string
+		counter int
+	)
+	var connect func(v string)
+	connect = func(v string) {
+		index[v], low[v] = counter, counter
+		counter++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, e := range g.out[v] {
+			w := e.CalleeAddress
+			if _, ok := index[w]; !ok {
+				connect(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+		if low[v] != index[v] {
+			return
+		}
+		var comp []string
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			comp = append(comp, w)
+			if w == v {
+				break
+			}
+		}
+		sort.Strings(comp)
+		out = append(out, comp)
+	}
+	for _, v := range g.nodes {
+		if _, ok := index[v]; !ok {
+			connect(v)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i][0] < out[j][0] })
+	return out
+}
+
+// RecursionCycles returns the strongly connected components that contain a cycle: groups of
+// mutually recursive symbols, and single symbols that call themselves.
+func (g *CallGraph) RecursionCycles() [][]string {
+	out := [][]string{}
+	for _, comp := range g.StronglyConnectedComponents() {
+		if len(comp) > 1 || g.callsSelf(comp[0]) {
+			out = append(out, comp)
+		}
+	}
+	return out
+}
+
+func (g *CallGraph) callsSelf(addr string) bool {
+	for _, e := range g.out[addr] {
+		if e.CalleeAddress == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// FanIn ranks addresses by their number of distinct callers, highest first. limit <= 0 returns all.
+func (g *CallGraph) FanIn(limit int) []Ranked {
+	return rank(g.in, func(e CallEdge) string { return e.CallerAddress }, limit)
+}
+
+// FanOut ranks addresses by their number of distinct callees, highest first. limit <= 0 returns all.
+func (g *CallGraph) FanOut(limit int) []Ranked {
+	return rank(g.out, func(e CallEdge) string { return e.CalleeAddress }, limit)
+}
+
+func rank(adj map[string][]CallEdge, other func(CallEdge) string, limit int) []Ranked {
+	out := []Ranked{}
+	for addr, es := range adj {
+		distinct := map[string]struct{}{}
+		for _, e := range es {
+			distinct[other(e)] = struct{}{}
+		}
+		out = append(out, Ranked{Address: addr, Count: len(distinct)})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Address < out[j].Address
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
@@ -0,0 +1,131 @@
+package codebase
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// queryTestCodeBase: A calls B twice and D; B and C call each other; D calls itself; E is never called.
+func queryTestCodeBase() *CodeBase {
+	syms := []CodeSymbol{}
+	for _, n := range []string{"A", "B", "C", "D", "E"} {
+		syms = append(syms, CodeSymbol{Name: n, Kind: "function", Address: "q.go::" + n})
+	}
+	edge := func(from, to string, line int) CallEdge {
+		return CallEdge{CallerAddress: "q.go::" + from, CalleeAddress: "q.go::" + to, CallLine: line}
+	}
+	return &CodeBase{
+		Symbols: syms,
+		Calls: []CallEdge{
+			edge("A", "B", 2), edge("A", "B", 3), edge("A", "D", 4),
+			edge("B", "C", 7), edge("C", "B", 9), edge("D", "D", 12),
+		},
+	}
+}
+
+// TestCallGraphTransitive checks depth-limited caller and callee walks.
+func TestCallGraphTransitive(t *testing.T) {
+	g := NewCallGraph(queryTestCodeBase())
+
+	tests := []struct {
+		name string
+		got  []Reached
+		want []Reached
+	}{
+		{
+			name: "callees depth 1",
+			got:  g.TransitiveCallees("q.go::A", 1),
+			want: []Reached{{"q.go::B", 1}, {"q.go::D", 1}},
+		},
+		{
+			name: "callees unbounded",
+			got:  g.TransitiveCallees("q.go::A", 0),
+			want: []Reached{{"q.go::B", 1}, {"q.go::D", 1}, {"q.go::C", 2}},
+		},
+		{
+			name: "callers include self through a cycle",
+			got:  g.TransitiveCallers("q.go::C", 0),
+			want: []Reached{{"q.go::B", 1}, {"q.go::A", 2}, {"q.go::C", 2}},
+		},
+		{
+			name: "no callers",
+			got:  g.TransitiveCallers("q.go::E", 3),
+			want: []Reached{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCallGraphShortestPath checks paths, the empty path and unreachable targets.
+func TestCallGraphShortestPath(t *testing.T) {
+	g := NewCallGraph(queryTestCodeBase())
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     []int // call lines along the path; nil means unreachable
+	}{
+		{name: "two hops", from: "q.go::A", to: "q.go::C", want: []int{2, 7}},
+		{name: "same address", from: "q.go::B", to: "q.go::B", want: []int{}},
+		{name: "unreachable", from: "q.go::C", to: "q.go::A", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := g.ShortestPath(tt.from, tt.to)
+
+			if (path == nil) != (tt.want == nil) {
+				t.Fatalf("got %v, want %v", path, tt.want)
+			}
+			got := []int{}
+			for _, e := range path {
+				got = append(got, e.CallLine)
+			}
+			if tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got lines %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCallGraphCyclesAndRankings checks SCCs, recursion cycles and fan-in/fan-out, and that a
+// graph loaded from JSON answers the same.
+func TestCallGraphCyclesAndRankings(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "cb.json")
+	if err := SaveToJSONFileCodeBase(queryTestCodeBase(), p); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadCallGraph(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, g := range map[string]*CallGraph{"built": NewCallGraph(queryTestCodeBase()), "loaded": loaded} {
+		t.Run(name, func(t *testing.T) {
+			wantSCC := [][]string{{"q.go::A"}, {"q.go::B", "q.go::C"}, {"q.go::D"}, {"q.go::E"}}
+			if got := g.StronglyConnectedComponents(); !reflect.DeepEqual(got, wantSCC) {
+				t.Errorf("SCCs = %v, want %v", got, wantSCC)
+			}
+			wantCycles := [][]string{{"q.go::B", "q.go::C"}, {"q.go::D"}}
+			if got := g.RecursionCycles(); !reflect.DeepEqual(got, wantCycles) {
+				t.Errorf("cycles = %v, want %v", got, wantCycles)
+			}
+			wantIn := []Ranked{{"q.go::B", 2}, {"q.go::D", 2}} // D counts itself
+			if got := g.FanIn(2); !reflect.DeepEqual(got, wantIn) {
+				t.Errorf("fan-in = %v, want %v", got, wantIn)
+			}
+			wantOut := []Ranked{{"q.go::A", 2}, {"q.go::B", 1}, {"q.go::C", 1}, {"q.go::D", 1}}
+			if got := g.FanOut(0); !reflect.DeepEqual(got, wantOut) {
+				t.Errorf("fan-out = %v, want %v", got, wantOut)
+			}
+		})
+	}
+}
@@ -1,25 +1,87 @@
-// Package codebase mirrors base/schema.py: JSON shape for symbols and call edges.
+// Package codebase mirrors base/schema.py: JSON shape for symbols and call edges. Go is indexed
+// natively; other languages plug in through Frontend (Python and TypeScript ship here). Graphs
+// are saved as JSON, or as SQLite (SaveToSQLiteFileCodeBase) when they are too big to load whole.
 package codebase
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 )
 
-// CodeSymbol is one symbol: function, type (as "class"), or module-level constant-like name.
+// CodeSymbol is one symbol: function, method, named type (as "class"), or module-level constant-like name.
+//
+// Methods carry their receiver type name, the address of that type when it is a "class" in the
+// same package, and whether the receiver is a pointer. Classes carry their method sets: MethodSet
+// for T (value receivers only) and PointerMethodSet for *T (all methods), both with the methods
+// promoted from embedded classes.
+// Generic functions, methods and classes carry TypeParameters; it is null otherwise.
 type CodeSymbol struct {
-	Name            string   `json:"name"`
-	Kind            string   `json:"kind"`
-	LineStart       int      `json:"line_start"`
-	LineEnd         int      `json:"line_end"`
-	LineCode        string   `json:"line_code"`
-	FilePath        string   `json:"file_path"`
-	Address         string   `json:"address"`
-	CallsTo         []string `json:"calls_to"`
-	CalledBy        []string `json:"called_by"`
-	ConstantValue   *string  `json:"constant_value"`
-	Docstring       *string  `json:"docstring"`
-	LeadingComment  *string  `json:"leading_comment"`
+	Name             string              `json:"name"`
+	Kind             string              `json:"kind"`
+	LineStart        int                 `json:"line_start"`
+	LineEnd          int                 `json:"line_end"`
+	LineCode         string              `json:"line_code"`
+	FilePath         string              `json:"file_path"`
+	Address          string              `json:"address"`
+	CallsTo          []string            `json:"calls_to"`
+	CalledBy         []string            `json:"called_by"`
+	ConstantValue    *string             `json:"constant_value"`
+	Docstring        *string             `json:"docstring"`
+	LeadingComment   *string             `json:"leading_comment"`
+	ReceiverType     *string             `json:"receiver_type"`
+	ReceiverAddress  *string             `json:"receiver_address"`
+	PointerReceiver  bool                `json:"pointer_receiver"`
+	MethodSet        []string            `json:"method_set"`
+	PointerMethodSet []string            `json:"pointer_method_set"`
+	TypeParameters   []TypeParameterInfo `json:"type_parameters"`
+	// Parameters and ReturnAnnotation give the signature of functions and methods, as in
+	// ListedFunction; they are null for other kinds.
+	Parameters       []ParameterInfo `json:"parameters"`
+	ReturnAnnotation *string         `json:"return_annotation"`
+	// Fields, InterfaceMethods and EmbeddedTypes describe Go "class" symbols: a struct has fields
+	// and an interface method specs; both may embed types. They are empty for other classes and
+	// null for other kinds.
+	Fields           []FieldInfo           `json:"fields"`
+	InterfaceMethods []InterfaceMethodInfo `json:"interface_methods"`
+	EmbeddedTypes    []EmbeddedTypeInfo    `json:"embedded_types"`
+	// Metrics measures Go functions and methods; it is null for other kinds and languages.
+	Metrics *SymbolMetrics `json:"metrics"`
+}
+
+// FieldInfo is one struct field. A declaration naming several fields (A, B int) yields one
+// entry per name; an embedded field is named after its type, as Go does.
+type FieldInfo struct {
+	Name     string  `json:"name"`
+	Type     string  `json:"type"` // as written, whitespace collapsed
+	Tag      string  `json:"tag"`  // tag contents without quotes, "" when absent
+	Embedded bool    `json:"embedded"`
+	Line     int     `json:"line"`
+	Comment  *string `json:"comment"` // comment lines above the field, else a line comment after it
+}
+
+// InterfaceMethodInfo is one method spec of an interface.
+type InterfaceMethodInfo struct {
+	Name      string `json:"name"`
+	Signature string `json:"signature"` // parameters and results as written, e.g. "(ctx context.Context) error"
+	Line      int    `json:"line"`
+}
+
+// EmbeddedTypeInfo is a type embedded in a struct or interface.
+type EmbeddedTypeInfo struct {
+	Type       string  `json:"type"`        // as written, e.g. "*Base" or "io.Reader"
+	Name       string  `json:"name"`        // type name without package, pointer or type arguments
+	ImportPath string  `json:"import_path"` // "" for a type of the declaring package
+	Pointer    bool    `json:"pointer"`
+	Address    *string `json:"address"` // the embedded class when it is among the indexed files
+}
+
+// TypeParameterInfo is one type parameter of a generic function or type, with its constraint
+// as written (e.g. "comparable", "~int | ~string"). Methods list the parameter names bound by
+// their receiver (C[K, V]); the constraint is copied from the receiver "class" when it is known.
+type TypeParameterInfo struct {
+	Name       string `json:"name"`
+	Constraint string `json:"constraint"`
 }
 
 // ParameterInfo describes one formal parameter.
@@ -30,7 +92,9 @@ type ParameterInfo struct {
 	DefaultValue *string `json:"default_value"`
 }
 
-// NameUsageSite is one identifier occurrence (for future RepoUsageReport parity).
+// NameUsageSite is one identifier occurrence recorded by BuildUsageReport: where it
+// appears, how it is used (one of the Usage kinds) and the repo symbol it resolves to,
+// if any.
 type NameUsageSite struct {
 	FilePath        string  `json:"file_path"`
 	Line            int     `json:"line"`
@@ -42,24 +106,26 @@ type NameUsageSite struct {
 
 // ListedFunction is a flat function listing entry.
 type ListedFunction struct {
-	Name              string          `json:"name"`
-	QualifiedName     string          `json:"qualified_name"`
-	FilePath          string          `json:"file_path"`
-	Line              int             `json:"line"`
-	Parameters        []ParameterInfo `json:"parameters"`
-	ReturnAnnotation  *string         `json:"return_annotation"`
-	Docstring         *string         `json:"docstring"`
-	LeadingComment    *string         `json:"leading_comment"`
+	Name             string              `json:"name"`
+	QualifiedName    string              `json:"qualified_name"`
+	FilePath         string              `json:"file_path"`
+	Line             int                 `json:"line"`
+	Parameters       []ParameterInfo     `json:"parameters"`
+	ReturnAnnotation *string             `json:"return_annotation"`
+	Docstring        *string             `json:"docstring"`
+	LeadingComment   *string             `json:"leading_comment"`
+	TypeParameters   []TypeParameterInfo `json:"type_parameters"`
 }
 
-// ListedClass lists a type (struct/interface) as a "class" for schema compatibility.
+// ListedClass lists a named type (struct, interface or other) as a "class" for schema compatibility.
 type ListedClass struct {
-	Name           string  `json:"name"`
-	QualifiedName  string  `json:"qualified_name"`
-	FilePath       string  `json:"file_path"`
-	Line           int     `json:"line"`
-	Docstring      *string `json:"docstring"`
-	LeadingComment *string `json:"leading_comment"`
+	Name           string              `json:"name"`
+	QualifiedName  string              `json:"qualified_name"`
+	FilePath       string              `json:"file_path"`
+	Line           int                 `json:"line"`
+	Docstring      *string             `json:"docstring"`
+	LeadingComment *string             `json:"leading_comment"`
+	TypeParameters []TypeParameterInfo `json:"type_parameters"`
 }
 
 // ListedConstant is a const (or ALL_CAPS var) at package scope.
@@ -87,16 +153,28 @@ type RepoUsageReport struct {
 }
 
 // CallEdge is a resolved call from caller to callee at a line (1-based).
+// ViaInterface is set when the call goes through an interface method and the callee is one
+// candidate implementation; it holds the interface address.
 type CallEdge struct {
-	CallerAddress string `json:"caller_address"`
-	CalleeAddress string `json:"callee_address"`
-	CallLine      int    `json:"call_line"`
+	CallerAddress string  `json:"caller_address"`
+	CalleeAddress string  `json:"callee_address"`
+	CallLine      int     `json:"call_line"`
+	ViaInterface  *string `json:"via_interface"`
 }
 
-// CodeBase is the full graph payload (symbols + calls), same as Python CodeBase.
+// ImplementsEdge says the "class" at TypeAddress structurally satisfies the interface at
+// InterfaceAddress. PointerOnly is true when only *T (not T) has the full method set.
+type ImplementsEdge struct {
+	TypeAddress      string `json:"type_address"`
+	InterfaceAddress string `json:"interface_address"`
+	PointerOnly      bool   `json:"pointer_only"`
+}
+
+// CodeBase is the full graph payload (symbols + calls + implements), same as Python CodeBase.
 type CodeBase struct {
-	Symbols []CodeSymbol `json:"symbols"`
-	Calls   []CallEdge   `json:"calls"`
+	Symbols    []CodeSymbol     `json:"symbols"`
+	Calls      []CallEdge       `json:"calls"`
+	Implements []ImplementsEdge `json:"implements"`
 }
 
 // SaveToJSONFileCodeBase writes codeBase to filePath with UTF-8 and indentation (like Python).
@@ -107,3 +185,16 @@ func SaveToJSONFileCodeBase(cb *CodeBase, filePath string) error {
 	}
 	return os.WriteFile(filePath, b, 0o644)
 }
+
+// LoadFromJSONFileCodeBase reads a CodeBase written by SaveToJSONFileCodeBase.
+func LoadFromJSONFileCodeBase(filePath string) (*CodeBase, error) {
+	b, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var cb CodeBase
+	if err := json.Unmarshal(b, &cb); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	return &cb, nil
+}
@@ -0,0 +1,482 @@
+package codebase
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteSchemaVersion is stored in the meta table; a file with another version is refused
+// rather than misread.
+const sqliteSchemaVersion = "2"
+
+// sqliteSchema normalizes a CodeBase into one row per file, symbol, call edge, implements edge
+// and usage. Edge ends are addresses rather than symbol ids because calls may leave the indexed
+// files (e.g. into the module cache). CallsTo and CalledBy are not stored; they are derived
+// from calls when symbols are read back. Nested symbol details (method sets, parameters,
+// fields...) are kept as JSON in symbols.details; metrics get columns so SQL can rank by them.
+const sqliteSchema = `
+CREATE TABLE meta (key TEXT PRIMARY KEY, value TEXT NOT NULL);
+CREATE TABLE files (id INTEGER PRIMARY KEY, path TEXT NOT NULL UNIQUE);
+CREATE TABLE symbols (
+	id                INTEGER PRIMARY KEY,
+	address           TEXT NOT NULL,
+	name              TEXT NOT NULL,
+	qualified_name    TEXT NOT NULL,
+	kind              TEXT NOT NULL,
+	file_id           INTEGER NOT NULL REFERENCES files(id),
+	line_start        INTEGER NOT NULL,
+	line_end          INTEGER NOT NULL,
+	line_code         TEXT NOT NULL,
+	constant_value    TEXT,
+	docstring         TEXT,
+	leading_comment   TEXT,
+	receiver_type     TEXT,
+	receiver_address  TEXT,
+	pointer_receiver  INTEGER NOT NULL,
+	return_annotation TEXT,
+	details           TEXT NOT NULL,
+	cyclomatic        INTEGER,
+	max_nesting       INTEGER,
+	statements        INTEGER,
+	parameter_count   INTEGER,
+	return_paths      INTEGER,
+	lines             INTEGER
+);
+CREATE INDEX symbols_address ON symbols(address);
+CREATE INDEX symbols_name ON symbols(name);
+CREATE INDEX symbols_qualified_name ON symbols(qualified_name);
+CREATE INDEX symbols_file ON symbols(file_id);
+CREATE TABLE calls (
+	id            INTEGER PRIMARY KEY,
+	caller        TEXT NOT NULL,
+	callee        TEXT NOT NULL,
+	line          INTEGER NOT NULL,
+	via_interface TEXT
+);
+CREATE INDEX calls_caller ON calls(caller, callee, line);
+CREATE INDEX calls_callee ON calls(callee, caller, line);
+CREATE TABLE implements (
+	id                INTEGER PRIMARY KEY,
+	type_address      TEXT NOT NULL,
+	interface_address TEXT NOT NULL,
+	pointer_only      INTEGER NOT NULL
+);
+CREATE INDEX implements_type ON implements(type_address);
+CREATE INDEX implements_interface ON implements(interface_address);
+CREATE TABLE usages (
+	id               INTEGER PRIMARY KEY,
+	file_id          INTEGER NOT NULL REFERENCES files(id),
+	line             INTEGER NOT NULL,
+	col              INTEGER NOT NULL,
+	name             TEXT NOT NULL,
+	kind             TEXT NOT NULL,
+	resolved_address TEXT
+);
+CREATE INDEX usages_name ON usages(name);
+CREATE INDEX usages_resolved ON usages(resolved_address);
+`
+
+// symbolDetails is the JSON in symbols.details: the CodeSymbol fields that are lists.
+type symbolDetails struct {
+	MethodSet        []string              `json:"method_set"`
+	PointerMethodSet []string              `json:"pointer_method_set"`
+	TypeParameters   []TypeParameterInfo   `json:"type_parameters"`
+	Parameters       []ParameterInfo       `json:"parameters"`
+	Fields           []FieldInfo           `json:"fields"`
+	InterfaceMethods []InterfaceMethodInfo `json:"interface_methods"`
+	EmbeddedTypes    []EmbeddedTypeInfo    `json:"embedded_types"`
+}
+
+// SaveToSQLiteFileCodeBase writes cb, and usages when non-nil (e.g. RepoUsageReport.Usages), to
+// a new SQLite database at filePath, replacing any existing file.
+func SaveToSQLiteFileCodeBase(cb *CodeBase, usages []NameUsageSite, filePath string) error {
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	db, err := sql.Open("sqlite3", filePath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := writeSQLite(tx, cb, usages); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+	return tx.Commit()
+}
+
+func writeSQLite(tx *sql.Tx, cb *CodeBase, usages []NameUsageSite) error {
+	if _, err := tx.Exec(`INSERT INTO meta (key, value) VALUES ('schema_version', ?)`, sqliteSchemaVersion); err != nil {
+		return err
+	}
+	fileStmt, err := tx.Prepare(`INSERT INTO files (path) VALUES (?)`)
+	if err != nil {
+		return err
+	}
+	defer fileStmt.Close()
+	fileIDs := map[string]int64{}
+	fileID := func(path string) (int64, error) {
+		if id, ok := fileIDs[path]; ok {
+			return id, nil
+		}
+		res, err := fileStmt.Exec(path)
+		if err != nil {
+			return 0, err
+		}
+		id, err := res.LastInsertId()
+		fileIDs[path] = id
+		return id, err
+	}
+
+	symStmt, err := tx.Prepare(`INSERT INTO symbols (address, name, qualified_name, kind, file_id, line_start, line_end,
+		line_code, constant_value, docstring, leading_comment, receiver_type, receiver_address, pointer_receiver,
+		return_annotation, details, cyclomatic, max_nesting, statements, parameter_count, return_paths, lines)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer symStmt.Close()
+	for _, s := range cb.Symbols {
+		fid, err := fileID(s.FilePath)
+		if err != nil {
+			return err
+		}
+		details, err := json.Marshal(symbolDetails{
+			MethodSet: s.MethodSet, PointerMethodSet: s.PointerMethodSet, TypeParameters: s.TypeParameters,
+			Parameters: s.Parameters, Fields: s.Fields, InterfaceMethods: s.InterfaceMethods, EmbeddedTypes: s.EmbeddedTypes,
+		})
+		if err != nil {
+			return err
+		}
+		_, qual, _ := strings.Cut(s.Address, "::")
+		metrics := make([]any, 6)
+		if m := s.Metrics; m != nil {
+			metrics = []any{m.Cyclomatic, m.MaxNesting, m.Statements, m.Parameters, m.ReturnPaths, m.Lines}
+		}
+		if _, err := symStmt.Exec(append([]any{s.Address, s.Name, qual, s.Kind, fid, s.LineStart, s.LineEnd, s.LineCode,
+			s.ConstantValue, s.Docstring, s.LeadingComment, s.ReceiverType, s.ReceiverAddress, s.PointerReceiver,
+			s.ReturnAnnotation, string(details)}, metrics...)...); err != nil {
+			return err
+		}
+	}
+
+	callStmt, err := tx.Prepare(`INSERT INTO calls (caller, callee, line, via_interface) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer callStmt.Close()
+	for _, e := range cb.Calls {
+		if _, err := callStmt.Exec(e.CallerAddress, e.CalleeAddress, e.CallLine, e.ViaInterface); err != nil {
+			return err
+		}
+	}
+
+	implStmt, err := tx.Prepare(`INSERT INTO implements (type_address, interface_address, pointer_only) VALUES (?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer implStmt.Close()
+	for _, e := range cb.Implements {
+		if _, err := implStmt.Exec(e.TypeAddress, e.InterfaceAddress, e.PointerOnly); err != nil {
+			return err
+		}
+	}
+
+	useStmt, err := tx.Prepare(`INSERT INTO usages (file_id, line, col, name, kind, resolved_address) VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer useStmt.Close()
+	for _, u := range usages {
+		fid, err := fileID(u.FilePath)
+		if err != nil {
+			return err
+		}
+		if _, err := useStmt.Exec(fid, u.Line, u.Column, u.Name, u.Kind, u.ResolvedAddress); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SQLiteCodeBase reads a database written by SaveToSQLiteFileCodeBase without loading it into
+// memory: symbols and edges are streamed or looked up through the address and name indexes.
+type SQLiteCodeBase struct {
+	db *sql.DB
+}
+
+// OpenSQLiteCodeBase opens filePath read-only.
+func OpenSQLiteCodeBase(filePath string) (*SQLiteCodeBase, error) {
+	if _, err := os.Stat(filePath); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", "file:"+filePath+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	var version string
+	if err := db.QueryRow(`SELECT value FROM meta WHERE key = 'schema_version'`).Scan(&version); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: not a code graph database: %w", filePath, err)
+	}
+	if version != sqliteSchemaVersion {
+		db.Close()
+		return nil, fmt.Errorf("%s: schema version %s, want %s", filePath, version, sqliteSchemaVersion)
+	}
+	return &SQLiteCodeBase{db: db}, nil
+}
+
+// DB returns the underlying database for ad-hoc SQL.
+func (s *SQLiteCodeBase) DB() *sql.DB {
+	return s.db
+}
+
+// Close closes the database.
+func (s *SQLiteCodeBase) Close() error {
+	return s.db.Close()
+}
+
+// symbolColumns selects a full CodeSymbol from symbols s joined with files f; CallsTo and
+// CalledBy come from calls as sorted, distinct JSON arrays.
+const symbolColumns = `s.name, s.kind, s.line_start, s.line_end, s.line_code, f.path, s.address,
+	(SELECT json_group_array(callee) FROM (SELECT DISTINCT callee FROM calls WHERE caller = s.address ORDER BY callee)),
+	(SELECT json_group_array(caller) FROM (SELECT DISTINCT caller FROM calls WHERE callee = s.address ORDER BY caller)),
+	s.constant_value, s.docstring, s.leading_comment, s.receiver_type, s.receiver_address, s.pointer_receiver,
+	s.return_annotation, s.details, s.cyclomatic, s.max_nesting, s.statements, s.parameter_count,
+	s.return_paths, s.lines
+	FROM symbols s JOIN files f ON f.id = s.file_id`
+
+func scanSymbol(rows *sql.Rows) (CodeSymbol, error) {
+	var (
+		s                 CodeSymbol
+		callsTo, calledBy string
+		details           string
+		m                 [6]*int
+	)
+	err := rows.Scan(&s.Name, &s.Kind, &s.LineStart, &s.LineEnd, &s.LineCode, &s.FilePath, &s.Address,
+		&callsTo, &calledBy, &s.ConstantValue, &s.Docstring, &s.LeadingComment, &s.ReceiverType, &s.ReceiverAddress,
+		&s.PointerReceiver, &s.ReturnAnnotation, &details, &m[0], &m[1], &m[2], &m[3], &m[4], &m[5])
+	if err != nil {
+		return s, err
+	}
+	if m[0] != nil {
+		s.Metrics = &SymbolMetrics{Cyclomatic: *m[0], MaxNesting: *m[1], Statements: *m[2], Parameters: *m[3], ReturnPaths: *m[4], Lines: *m[5]}
+	}
+	if err := json.Unmarshal([]byte(callsTo), &s.CallsTo); err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal([]byte(calledBy), &s.CalledBy); err != nil {
+		return s, err
+	}
+	var d symbolDetails
+	if err := json.Unmarshal([]byte(details), &d); err != nil {
+		return s, err
+	}
+	s.MethodSet, s.PointerMethodSet, s.TypeParameters = d.MethodSet, d.PointerMethodSet, d.TypeParameters
+	s.Parameters, s.Fields, s.InterfaceMethods, s.EmbeddedTypes = d.Parameters, d.Fields, d.InterfaceMethods, d.EmbeddedTypes
+	return s, nil
+}
+
+func (s *SQLiteCodeBase) symbols(where string, args []any, fn func(CodeSymbol) error) error {
+	rows, err := s.db.Query(`SELECT `+symbolColumns+` `+where+` ORDER BY s.id`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		sym, err := scanSymbol(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(sym); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// EachSymbol calls fn with every symbol in index order, stopping at the first error.
+func (s *SQLiteCodeBase) EachSymbol(fn func(CodeSymbol) error) error {
+	return s.symbols("", nil, fn)
+}
+
+// Symbol returns the symbol at addr, or nil.
+func (s *SQLiteCodeBase) Symbol(addr string) (*CodeSymbol, error) {
+	var found *CodeSymbol
+	err := s.symbols("WHERE s.address = ?", []any{addr}, func(sym CodeSymbol) error {
+		if found == nil {
+			found = &sym
+		}
+		return nil
+	})
+	return found, err
+}
+
+// FindSymbols returns the symbols whose name, qualified name or address is name.
+func (s *SQLiteCodeBase) FindSymbols(name string) ([]CodeSymbol, error) {
+	out := []CodeSymbol{}
+	err := s.symbols("WHERE s.name = ?1 OR s.qualified_name = ?1 OR s.address = ?1", []any{name}, func(sym CodeSymbol) error {
+		out = append(out, sym)
+		return nil
+	})
+	return out, err
+}
+
+func (s *SQLiteCodeBase) calls(query string, args []any, fn func(CallEdge) error) error {
+	rows, err := s.db.Query(`SELECT caller, callee, line, via_interface FROM calls `+query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e CallEdge
+		if err := rows.Scan(&e.CallerAddress, &e.CalleeAddress, &e.CallLine, &e.ViaInterface); err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// EachCall calls fn with every call edge in index order, stopping at the first error.
+func (s *SQLiteCodeBase) EachCall(fn func(CallEdge) error) error {
+	return s.calls("ORDER BY id", nil, fn)
+}
+
+// Callers returns the call edges into addr, ordered like CallGraph.Callers.
+func (s *SQLiteCodeBase) Callers(addr string) ([]CallEdge, error) {
+	out := []CallEdge{}
+	err := s.calls("WHERE callee = ? ORDER BY caller, line, id", []any{addr}, func(e CallEdge) error {
+		out = append(out, e)
+		return nil
+	})
+	return out, err
+}
+
+// Callees returns the call edges out of addr, ordered like CallGraph.Callees.
+func (s *SQLiteCodeBase) Callees(addr string) ([]CallEdge, error) {
+	out := []CallEdge{}
+	err := s.calls("WHERE caller = ? ORDER BY callee, line, id", []any{addr}, func(e CallEdge) error {
+		out = append(out, e)
+		return nil
+	})
+	return out, err
+}
+
+// EachImplements calls fn with every implements edge in index order, stopping at the first error.
+func (s *SQLiteCodeBase) EachImplements(fn func(ImplementsEdge) error) error {
+	rows, err := s.db.Query(`SELECT type_address, interface_address, pointer_only FROM implements ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e ImplementsEdge
+		if err := rows.Scan(&e.TypeAddress, &e.InterfaceAddress, &e.PointerOnly); err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// EachUsage calls fn with every recorded usage in index order, stopping at the first error.
+func (s *SQLiteCodeBase) EachUsage(fn func(NameUsageSite) error) error {
+	rows, err := s.db.Query(`SELECT f.path, u.line, u.col, u.name, u.kind, u.resolved_address
+		FROM usages u JOIN files f ON f.id = u.file_id ORDER BY u.id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var u NameUsageSite
+		if err := rows.Scan(&u.FilePath, &u.Line, &u.Column, &u.Name, &u.Kind, &u.ResolvedAddress); err != nil {
+			return err
+		}
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// CodeBase reads the whole graph into memory, as LoadFromJSONFileCodeBase would.
+func (s *SQLiteCodeBase) CodeBase() (*CodeBase, error) {
+	cb := &CodeBase{}
+	err := s.EachSymbol(func(sym CodeSymbol) error {
+		cb.Symbols = append(cb.Symbols, sym)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.EachCall(func(e CallEdge) error {
+		cb.Calls = append(cb.Calls, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.EachImplements(func(e ImplementsEdge) error {
+		cb.Implements = append(cb.Implements, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cb, nil
+}
+
+// LoadFromSQLiteFileCodeBase reads a CodeBase written by SaveToSQLiteFileCodeBase.
+func LoadFromSQLiteFileCodeBase(filePath string) (*CodeBase, error) {
+	s, err := OpenSQLiteCodeBase(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	return s.CodeBase()
+}
+
+// sqliteMagic starts every SQLite 3 database file.
+var sqliteMagic = []byte("SQLite format 3\x00")
+
+// IsSQLiteFile reports whether filePath is a SQLite database rather than JSON.
+func IsSQLiteFile(filePath string) bool {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, len(sqliteMagic))
+	if _, err := io.ReadFull(f, head); err != nil {
+		return false
+	}
+	return bytes.Equal(head, sqliteMagic)
+}
+
+// LoadCodeBase reads a CodeBase from either a JSON file or a SQLite database.
+func LoadCodeBase(filePath string) (*CodeBase, error) {
+	if IsSQLiteFile(filePath) {
+		return LoadFromSQLiteFileCodeBase(filePath)
+	}
+	return LoadFromJSONFileCodeBase(filePath)
+}
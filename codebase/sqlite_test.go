@@ -0,0 +1,127 @@
+package codebase
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestSQLiteCodeBase checks that a graph stored as SQLite reads back like its JSON copy, and
+// that point queries match CallGraph.
+func TestSQLiteCodeBase(t *testing.T) {
+	repoRoot := findRepoRootForTest(t)
+	dirs := []string{filepath.Join(repoRoot, "codebase", "testdata", "iface"), filepath.Join(repoRoot, "codebase", "testdata", "generics")}
+	cb, err := BuildCodebaseForFiles(dirs, BuildOptions{RepoRoot: repoRoot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep, err := BuildUsageReport(dirs, BuildOptions{RepoRoot: repoRoot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp := t.TempDir()
+	jsonPath, dbPath := filepath.Join(tmp, "cb.json"), filepath.Join(tmp, "cb.db")
+	if err := SaveToJSONFileCodeBase(cb, jsonPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveToSQLiteFileCodeBase(cb, rep.Usages, dbPath); err != nil {
+		t.Fatal(err)
+	}
+	// Saving again replaces the file instead of failing on the existing tables.
+	if err := SaveToSQLiteFileCodeBase(cb, rep.Usages, dbPath); err != nil {
+		t.Fatal(err)
+	}
+
+	if IsSQLiteFile(jsonPath) || !IsSQLiteFile(dbPath) {
+		t.Fatalf("IsSQLiteFile(json) = %v, IsSQLiteFile(db) = %v", IsSQLiteFile(jsonPath), IsSQLiteFile(dbPath))
+	}
+	fromJSON, err := LoadCodeBase(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromDB, err := LoadCodeBase(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := codeBaseJSON(t, fromDB), codeBaseJSON(t, fromJSON); got != want {
+		t.Fatalf("SQLite load differs from JSON load\nsqlite: %s\njson:   %s", got, want)
+	}
+	if _, err := OpenSQLiteCodeBase(jsonPath); err == nil {
+		t.Error("OpenSQLiteCodeBase accepted a JSON file")
+	}
+
+	s, err := OpenSQLiteCodeBase(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	g := NewCallGraph(cb)
+	const get = "codebase/testdata/iface/mem.go::MemStore.Get"
+
+	tests := []struct {
+		name string
+		got  func() (any, error)
+		want any
+	}{
+		{
+			name: "callers",
+			got:  func() (any, error) { return s.Callers(get) },
+			want: g.Callers(get),
+		},
+		{
+			name: "callees",
+			got:  func() (any, error) { return s.Callees("codebase/testdata/iface/store.go::Lookup") },
+			want: g.Callees("codebase/testdata/iface/store.go::Lookup"),
+		},
+		{
+			name: "symbol",
+			got:  func() (any, error) { return s.Symbol(get) },
+			want: g.Symbol(get),
+		},
+		{
+			name: "missing symbol",
+			got:  func() (any, error) { return s.Symbol("nope.go::X") },
+			want: (*CodeSymbol)(nil),
+		},
+		{
+			name: "find by qualified name",
+			got: func() (any, error) {
+				syms, err := s.FindSymbols("MemStore.Get")
+				return len(syms), err
+			},
+			want: 1,
+		},
+		{
+			name: "usages",
+			got: func() (any, error) {
+				var us []NameUsageSite
+				err := s.EachUsage(func(u NameUsageSite) error {
+					us = append(us, u)
+					return nil
+				})
+				return us, err
+			},
+			want: rep.Usages,
+		},
+		{
+			name: "ad-hoc SQL over the name index",
+			got: func() (any, error) {
+				var n int
+				err := s.DB().QueryRow(`SELECT count(*) FROM symbols s JOIN files f ON f.id = s.file_id WHERE s.name = 'Get' AND f.path LIKE '%/mem.go'`).Scan(&n)
+				return n, err
+			},
+			want: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.got()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package generics
+
+// Cache is a generic keyed store.
+type Cache[K comparable, V any] struct {
+	items map[K]V
+}
+
+// NewCache builds an empty Cache.
+func NewCache[K comparable, V any](size int) *Cache[K, V] {
+	return &Cache[K, V]{items: make(map[K]V, size)}
+}
+
+// Get looks up k.
+func (c *Cache[K, V]) Get(k K) (V, bool) {
+	v, ok := c.items[k]
+	return v, ok
+}
+
+// Number is satisfied by the built-in numeric types Sum accepts.
+type Number interface {
+	~int | ~float64
+}
+
+// Sum adds xs.
+func Sum[N Number](xs ...N) N {
+	var total N
+	for _, x := range xs {
+		total += x
+	}
+	return total
+}
+
+// Map applies fn to every element.
+func Map[T, R any](xs []T, fn func(T) R) []R {
+	out := make([]R, 0, len(xs))
+	for _, x := range xs {
+		out = append(out, fn(x))
+	}
+	return out
+}
+
+// Warm calls each generic with explicit type arguments.
+func Warm() {
+	c := NewCache[string, int](4)
+	c.Get("a")
+	_ = Sum[int](1)
+	_ = Map[int, string](nil, nil)
+}
@@ -0,0 +1,9 @@
+package use
+
+import "github.com/tqhuy-dev/xgen/codebase/testdata/generics"
+
+// Use instantiates generics from another package.
+func Use() float64 {
+	generics.NewCache[string, int](1)
+	return generics.Sum[float64](2)
+}
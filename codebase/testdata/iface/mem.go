@@ -0,0 +1,30 @@
+package iface
+
+// MemStore keeps values in a map.
+type MemStore struct {
+	data map[string]string
+}
+
+// Get returns the value stored under key.
+func (m MemStore) Get(key string) (string, error) {
+	return m.data[key], nil
+}
+
+// Put stores value under key.
+func (m *MemStore) Put(key, value string) error {
+	m.data[key] = value
+	return nil
+}
+
+// ReadOnly only implements Getter, with a mismatched Put signature.
+type ReadOnly struct{}
+
+// Get always returns an empty value.
+func (ReadOnly) Get(key string) (string, error) {
+	return "", nil
+}
+
+// Put has a different signature from Store.Put.
+func (ReadOnly) Put(key string) error {
+	return nil
+}
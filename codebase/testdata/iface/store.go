@@ -0,0 +1,23 @@
+package iface
+
+// Getter reads values by key.
+type Getter interface {
+	Get(key string) (string, error)
+}
+
+// Store reads and writes values by key.
+type Store interface {
+	Getter
+	Put(key, value string) error
+}
+
+// Number constrains numeric type parameters.
+type Number interface {
+	~int | ~float64
+}
+
+// Lookup reads key through any Getter.
+func Lookup(g Getter, key string) string {
+	v, _ := g.Get(key)
+	return v
+}
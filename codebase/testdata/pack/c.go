@@ -0,0 +1,28 @@
+package pack
+
+// LIMIT bounds how often Counter may be bumped.
+const LIMIT = 3
+
+// Counter is a small struct used by usage tests.
+type Counter struct {
+	n int
+}
+
+// Bump increments c up to LIMIT.
+func Bump(c *Counter) *Counter {
+	if c.n < LIMIT {
+		c.n++
+	}
+	helper()
+	return c
+}
+
+// Value reports the current count.
+func (c Counter) Value() int {
+	return c.n
+}
+
+// Reset sets the count back to zero.
+func (c *Counter) Reset() {
+	c.n = 0
+}
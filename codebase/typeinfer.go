@@ -0,0 +1,642 @@
+package codebase
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// typeIndex answers what local type inference asks about the indexed Go symbols: which class a
+// type name denotes, the fields and methods of a class, and the result type of a function.
+// Types declared in the requested files are known up front. A package the module graph locates
+// elsewhere, e.g. a replaced module outside the repo, is parsed on first use into foreign;
+// anything else stays uninferred.
+type typeIndex struct {
+	repoRoot string
+	mods     *moduleGraph
+	classes  map[string]map[string][][2]string // package dir -> class name -> (line, address)
+	funcs    map[string]map[string][][2]string // package dir -> function name -> (line, address)
+	syms     map[string]*CodeSymbol
+	methods  map[string]map[string]string // class address -> method name -> method address
+	ifaces   map[string]map[string]string // interface address -> flattened method name -> signature
+	imports  map[string]map[string]string // file rel -> local package name -> import path
+	pkgDirs  map[string]bool              // package dirs of the requested files
+	pool     *parserPool
+
+	mu      sync.Mutex
+	foreign *typeIndex // packages loaded by loadPackage, with the same maps
+	loaded  map[string]bool
+}
+
+func newTypeIndex(in *buildInput, syms []CodeSymbol, facts []*fileFacts, impls *implementsResult) *typeIndex {
+	ti := &typeIndex{
+		repoRoot: in.repoRoot,
+		mods:     in.modules,
+		classes:  packageIndexOfKinds(syms, in.repoRoot, "class"),
+		funcs:    packageIndexOfKinds(syms, in.repoRoot, "function"),
+		syms:     map[string]*CodeSymbol{},
+		methods:  map[string]map[string]string{},
+		ifaces:   impls.methods,
+		imports:  map[string]map[string]string{},
+	}
+	for i := range syms {
+		s := &syms[i]
+		ti.syms[s.Address] = s
+		if s.Kind == "method" && s.ReceiverAddress != nil {
+			if ti.methods[*s.ReceiverAddress] == nil {
+				ti.methods[*s.ReceiverAddress] = map[string]string{}
+			}
+			ti.methods[*s.ReceiverAddress][s.Name] = s.Address
+		}
+	}
+	ti.pkgDirs = map[string]bool{}
+	for i, abs := range in.files {
+		ti.imports[in.cache[abs].rel] = facts[i].ImportNames
+		ti.pkgDirs[filepath.Dir(abs)] = true
+	}
+	ti.pool = in.pool
+	ti.foreign = &typeIndex{
+		classes: map[string]map[string][][2]string{},
+		funcs:   map[string]map[string][][2]string{},
+		syms:    map[string]*CodeSymbol{},
+		methods: map[string]map[string]string{},
+		imports: map[string]map[string]string{},
+	}
+	ti.loaded = map[string]bool{}
+	return ti
+}
+
+// loadPackage parses the Go files of dir, a package outside the requested files, into
+// ti.foreign. Its symbols are addressed like those of requested files; nothing else is linked
+// to them.
+func (ti *typeIndex) loadPackage(dir string) {
+	if ti.pkgDirs[dir] {
+		return
+	}
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+	if ti.loaded[dir] {
+		return
+	}
+	ti.loaded[dir] = true
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	p, err := ti.pool.get()
+	if err != nil {
+		return
+	}
+	defer ti.pool.put(p)
+	var syms []CodeSymbol
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") || strings.HasSuffix(e.Name(), "_test.go") {
+			continue
+		}
+		abs := filepath.Join(dir, e.Name())
+		src, err := os.ReadFile(abs)
+		if err != nil {
+			continue
+		}
+		rel, err := toPosixRel(ti.repoRoot, abs)
+		if err != nil {
+			continue
+		}
+		addrRel, err := ti.mods.relPath(abs)
+		if err != nil {
+			continue
+		}
+		tree := p.Parse(src, nil)
+		if tree == nil {
+			continue
+		}
+		var bodies []funcBody
+		if collectPackageLevel(tree, src, rel, addrRel, ti.mods, &syms, &bodies) == nil {
+			imports := map[string]string{}
+			for _, row := range collectImports(tree.RootNode(), src, ti.mods) {
+				if row.local != "." {
+					imports[row.local] = row.path
+				}
+			}
+			ti.foreign.imports[rel] = imports
+		}
+		tree.Close()
+	}
+	linkMethods(syms, ti.repoRoot)
+	linkEmbeddedTypes(syms, ti.repoRoot, ti.mods)
+	promoteMethods(syms)
+
+	ti.foreign.classes[dir] = packageIndexOfKinds(syms, ti.repoRoot, "class")[dir]
+	ti.foreign.funcs[dir] = packageIndexOfKinds(syms, ti.repoRoot, "function")[dir]
+	for i := range syms {
+		s := &syms[i]
+		ti.foreign.syms[s.Address] = s
+		if s.Kind == "method" && s.ReceiverAddress != nil {
+			if ti.foreign.methods[*s.ReceiverAddress] == nil {
+				ti.foreign.methods[*s.ReceiverAddress] = map[string]string{}
+			}
+			ti.foreign.methods[*s.ReceiverAddress][s.Name] = s.Address
+		}
+	}
+}
+
+// The accessors below look in the requested files first, then in the loaded packages.
+
+func (ti *typeIndex) classesIn(dir string) map[string][][2]string {
+	if m, ok := ti.classes[dir]; ok {
+		return m
+	}
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+	return ti.foreign.classes[dir]
+}
+
+func (ti *typeIndex) funcsIn(dir string) map[string][][2]string {
+	if m, ok := ti.funcs[dir]; ok {
+		return m
+	}
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+	return ti.foreign.funcs[dir]
+}
+
+func (ti *typeIndex) sym(addr string) *CodeSymbol {
+	if s, ok := ti.syms[addr]; ok {
+		return s
+	}
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+	return ti.foreign.syms[addr]
+}
+
+func (ti *typeIndex) methodOf(class, name string) string {
+	if a := ti.methods[class][name]; a != "" {
+		return a
+	}
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+	return ti.foreign.methods[class][name]
+}
+
+func (ti *typeIndex) importsOf(rel string) map[string]string {
+	if m, ok := ti.imports[rel]; ok {
+		return m
+	}
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+	return ti.foreign.imports[rel]
+}
+
+// externalClass stands for a type of a package the module graph cannot locate, e.g. sync.Mutex
+// or the result of reflect.ValueOf. Its methods are known not to be any indexed symbol.
+const externalClass = "<external>"
+
+// typeScope is a typeIndex seen from one file. It records the package directories whose
+// classes and functions inference consulted, so cached edges can be invalidated when they change.
+type typeScope struct {
+	ti   *typeIndex
+	rel  string
+	dirs map[string]struct{}
+}
+
+func (ti *typeIndex) scope(rel string) *typeScope {
+	if ti == nil {
+		return nil
+	}
+	return &typeScope{ti: ti, rel: rel, dirs: map[string]struct{}{}}
+}
+
+// packageDir returns the directory of the package that pkg names in file rel: the file's own
+// package when pkg is "", else the imported package, loaded first if it is not requested.
+func (sc *typeScope) packageDir(rel, pkg string) (string, bool) {
+	dir := packageDirOf(sc.ti.repoRoot, rel)
+	if pkg != "" {
+		ip, ok := sc.ti.importsOf(rel)[pkg]
+		if !ok {
+			return "", false
+		}
+		if dir, ok = sc.ti.mods.importDir(ip); !ok {
+			return "", false
+		}
+		dir = filepath.Clean(dir)
+	}
+	sc.ti.loadPackage(dir)
+	sc.dirs[dir] = struct{}{}
+	return dir, true
+}
+
+// classOf returns the address of the class a type expression written in file rel names,
+// through pointers and type arguments; externalClass for a type of a package the module graph
+// cannot locate, and "" for other types.
+func (sc *typeScope) classOf(rel, typ string) string {
+	pkg, name, ok := namedType(typ)
+	if !ok {
+		return ""
+	}
+	dir, ok := sc.packageDir(rel, pkg)
+	if !ok {
+		return sc.externalOr(rel, pkg)
+	}
+	return packageTopLevelAddress(sc.ti.classesIn(dir), name)
+}
+
+// externalOr returns externalClass when pkg is a package imported by file rel, which packageDir
+// could not locate, and "" otherwise.
+func (sc *typeScope) externalOr(rel, pkg string) string {
+	if pkg == "" {
+		return ""
+	}
+	if _, imported := sc.ti.importsOf(rel)[pkg]; !imported {
+		return ""
+	}
+	return externalClass
+}
+
+// resultClass returns the class of the first result of the function or method at addr.
+func (sc *typeScope) resultClass(addr string) string {
+	s := sc.ti.sym(addr)
+	if s == nil || s.ReturnAnnotation == nil {
+		return ""
+	}
+	return sc.classOf(s.FilePath, firstResultType(*s.ReturnAnnotation))
+}
+
+// funcResultClass is resultClass for the package-level function name in package pkg of file rel.
+func (sc *typeScope) funcResultClass(rel, pkg, name string) string {
+	dir, ok := sc.packageDir(rel, pkg)
+	if !ok {
+		return sc.externalOr(rel, pkg)
+	}
+	if addr := packageTopLevelAddress(sc.ti.funcsIn(dir), name); addr != "" {
+		return sc.resultClass(addr)
+	}
+	// A conversion T(x) has type T.
+	return packageTopLevelAddress(sc.ti.classesIn(dir), name)
+}
+
+// fieldClass returns the class of field name of class, including fields promoted from
+// embedded types.
+func (sc *typeScope) fieldClass(class, name string, depth int) string {
+	s := sc.ti.sym(class)
+	if s == nil || depth > 4 {
+		return ""
+	}
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return sc.classOf(s.FilePath, f.Type)
+		}
+	}
+	for _, e := range s.EmbeddedTypes {
+		if e.Address != nil {
+			if c := sc.fieldClass(*e.Address, name, depth+1); c != "" {
+				return c
+			}
+		}
+	}
+	return ""
+}
+
+// method resolves method name on class: a declared or promoted concrete method yields its
+// address; a method of an interface (class itself or an embedded one) yields that interface,
+// to be dispatched to its implementations.
+func (sc *typeScope) method(class, name string, depth int) (addr, iface string) {
+	if depth > 4 {
+		return "", ""
+	}
+	if a := sc.ti.methodOf(class, name); a != "" {
+		return a, ""
+	}
+	if _, ok := sc.ti.ifaces[class][name]; ok {
+		return "", class
+	}
+	if s := sc.ti.sym(class); s != nil {
+		for _, e := range s.EmbeddedTypes {
+			if e.Address == nil {
+				continue
+			}
+			if a, i := sc.method(*e.Address, name, depth+1); a != "" || i != "" {
+				return a, i
+			}
+		}
+	}
+	return "", ""
+}
+
+// localVar is a variable whose type was inferred, valid from line on.
+type localVar struct {
+	line  int
+	class string
+}
+
+// typeEnv holds the inferred classes of the receiver, parameters and local variables of one
+// function body. Assignments after the declaration are not tracked: a variable keeps the type
+// it was declared with, which Go guarantees for everything but interface-typed variables.
+type typeEnv struct {
+	sc      *typeScope
+	src     []byte
+	imports []importRow
+	vars    map[string][]localVar
+}
+
+// funcEnv infers the variables of fn, a function_declaration or method_declaration.
+func (sc *typeScope) funcEnv(fn *sitter.Node, src []byte, imports []importRow) *typeEnv {
+	if sc == nil {
+		return nil
+	}
+	env := &typeEnv{sc: sc, src: src, imports: imports, vars: map[string][]localVar{}}
+	env.bindParameters(fn.ChildByFieldName("receiver"))
+	env.bindParameters(fn.ChildByFieldName("parameters"))
+	if body := fn.ChildByFieldName("body"); body != nil {
+		env.bindDeclarations(body)
+	}
+	return env
+}
+
+// bind declares name at line. class may be "": the variable is then known to shadow any
+// package or function of that name, but has no inferred type.
+func (env *typeEnv) bind(name string, line int, class string) {
+	if name == "_" {
+		return
+	}
+	env.vars[name] = append(env.vars[name], localVar{line: line, class: class})
+}
+
+// lookup returns the class of the latest declaration of name at or before line.
+func (env *typeEnv) lookup(name string, line int) (string, bool) {
+	vs, ok := env.vars[name]
+	if !ok {
+		return "", false
+	}
+	class, found := "", false
+	for _, v := range vs {
+		if v.line <= line {
+			class, found = v.class, true
+		}
+	}
+	return class, found
+}
+
+func (env *typeEnv) bindParameters(list *sitter.Node) {
+	if list == nil {
+		return
+	}
+	for i := uint(0); i < list.NamedChildCount(); i++ {
+		pd := list.NamedChild(i)
+		if pd.Kind() != "parameter_declaration" {
+			continue
+		}
+		typ := pd.ChildByFieldName("type")
+		if typ == nil {
+			continue
+		}
+		class := env.sc.classOf(env.sc.rel, nodeText(env.src, typ))
+		for j := uint(0); j < pd.ChildCount(); j++ {
+			if pd.FieldNameForChild(uint32(j)) == "name" {
+				env.bind(nodeText(env.src, pd.Child(j)), lineStart1(pd), class)
+			}
+		}
+	}
+}
+
+// bindDeclarations walks a body for var declarations and short variable declarations. Function
+// literals are walked too, binding their parameters, since their calls belong to the enclosing
+// function; like other block scopes, theirs is not tracked.
+func (env *typeEnv) bindDeclarations(n *sitter.Node) {
+	switch n.Kind() {
+	case "func_literal":
+		env.bindParameters(n.ChildByFieldName("parameters"))
+	case "var_spec":
+		env.bindVarSpec(n)
+	case "short_var_declaration":
+		env.bindAssignment(n.ChildByFieldName("left"), n.ChildByFieldName("right"), lineStart1(n))
+	}
+	for i := uint(0); i < n.NamedChildCount(); i++ {
+		env.bindDeclarations(n.NamedChild(i))
+	}
+}
+
+func (env *typeEnv) bindVarSpec(spec *sitter.Node) {
+	line := lineStart1(spec)
+	if typ := spec.ChildByFieldName("type"); typ != nil {
+		class := env.sc.classOf(env.sc.rel, nodeText(env.src, typ))
+		for j := uint(0); j < spec.ChildCount(); j++ {
+			if spec.FieldNameForChild(uint32(j)) == "name" {
+				env.bind(nodeText(env.src, spec.Child(j)), line, class)
+			}
+		}
+		return
+	}
+	var names []*sitter.Node
+	for j := uint(0); j < spec.ChildCount(); j++ {
+		if spec.FieldNameForChild(uint32(j)) == "name" {
+			names = append(names, spec.Child(j))
+		}
+	}
+	env.bindValues(names, spec.ChildByFieldName("value"), line)
+}
+
+func (env *typeEnv) bindAssignment(left, right *sitter.Node, line int) {
+	if left == nil {
+		return
+	}
+	var names []*sitter.Node
+	for i := uint(0); i < left.NamedChildCount(); i++ {
+		names = append(names, left.NamedChild(i))
+	}
+	env.bindValues(names, right, line)
+}
+
+// bindValues pairs names with the expressions of values; a single call assigned to several
+// names types only the first, from the call's first result.
+func (env *typeEnv) bindValues(names []*sitter.Node, values *sitter.Node, line int) {
+	if len(names) == 0 {
+		return
+	}
+	classes := make([]string, len(names))
+	switch {
+	case values == nil:
+	case int(values.NamedChildCount()) == len(names):
+		for i := range names {
+			classes[i] = env.exprClass(values.NamedChild(uint(i)), line)
+		}
+	case values.NamedChildCount() == 1 && unwrapPrimary(values.NamedChild(0)).Kind() == "call_expression":
+		classes[0] = env.exprClass(values.NamedChild(0), line)
+	}
+	for i, nm := range names {
+		if nm.Kind() == "identifier" {
+			env.bind(nodeText(env.src, nm), line, classes[i])
+		}
+	}
+}
+
+// exprClass infers the class of an expression: a typed variable, &T{}, T{}, new(T), x.(T), a
+// field selector, or a call to a function or method whose first result names a class. Fields and
+// method results of an externalClass value are externalClass too.
+func (env *typeEnv) exprClass(n *sitter.Node, line int) string {
+	n = unwrapPrimary(n)
+	if n == nil {
+		return ""
+	}
+	sc := env.sc
+	switch n.Kind() {
+	case "identifier":
+		c, _ := env.lookup(nodeText(env.src, n), line)
+		return c
+	case "unary_expression":
+		if op := n.ChildByFieldName("operator"); op != nil && nodeText(env.src, op) == "&" {
+			return env.exprClass(n.ChildByFieldName("operand"), line)
+		}
+	case "composite_literal", "type_assertion_expression":
+		if typ := n.ChildByFieldName("type"); typ != nil {
+			return sc.classOf(sc.rel, nodeText(env.src, typ))
+		}
+	case "selector_expression":
+		op, fd := n.ChildByFieldName("operand"), n.ChildByFieldName("field")
+		if op == nil || fd == nil || env.isPackage(op, line) {
+			return ""
+		}
+		if c := env.exprClass(op, line); c == externalClass {
+			return c
+		} else if c != "" {
+			return sc.fieldClass(c, nodeText(env.src, fd), 0)
+		}
+	case "call_expression":
+		fn := unwrapPrimary(n.ChildByFieldName("function"))
+		if fn != nil && fn.Kind() == "index_expression" {
+			fn = unwrapPrimary(fn.ChildByFieldName("operand"))
+		}
+		if fn == nil {
+			return ""
+		}
+		switch fn.Kind() {
+		case "identifier":
+			name := nodeText(env.src, fn)
+			if name == "new" {
+				if args := n.ChildByFieldName("arguments"); args != nil && args.NamedChildCount() > 0 {
+					return sc.classOf(sc.rel, nodeText(env.src, args.NamedChild(0)))
+				}
+				return ""
+			}
+			if _, local := env.lookup(name, line); local {
+				return ""
+			}
+			return sc.funcResultClass(sc.rel, "", name)
+		case "selector_expression":
+			op, fd := fn.ChildByFieldName("operand"), fn.ChildByFieldName("field")
+			if op == nil || fd == nil {
+				return ""
+			}
+			if env.isPackage(op, line) {
+				return sc.funcResultClass(sc.rel, nodeText(env.src, op), nodeText(env.src, fd))
+			}
+			if c := env.exprClass(op, line); c == externalClass {
+				return c
+			} else if c != "" {
+				if addr, _ := sc.method(c, nodeText(env.src, fd), 0); addr != "" {
+					return sc.resultClass(addr)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// isPackage reports whether n is an imported package name not shadowed by a local variable.
+func (env *typeEnv) isPackage(n *sitter.Node, line int) bool {
+	if n.Kind() != "identifier" {
+		return false
+	}
+	name := nodeText(env.src, n)
+	if _, local := env.lookup(name, line); local {
+		return false
+	}
+	return isImportAlias(env.imports, name)
+}
+
+// methodCall resolves the method a selector call invokes from the inferred class of its
+// operand. ok is false when the operand's class is unknown or has no method of that name,
+// leaving the call to name-based resolution. A call on an externalClass operand is resolved
+// to nothing: ok is true with addr and iface both "".
+func (env *typeEnv) methodCall(call *sitter.Node, name string, line int) (addr, iface string, ok bool) {
+	if env == nil || call == nil || call.Kind() != "call_expression" {
+		return "", "", false
+	}
+	fn := unwrapPrimary(call.ChildByFieldName("function"))
+	if fn != nil && fn.Kind() == "index_expression" {
+		fn = unwrapPrimary(fn.ChildByFieldName("operand"))
+	}
+	if fn == nil || fn.Kind() != "selector_expression" {
+		return "", "", false
+	}
+	op := fn.ChildByFieldName("operand")
+	if op == nil || env.isPackage(op, line) {
+		return "", "", false
+	}
+	class := env.exprClass(op, line)
+	switch class {
+	case "":
+		return "", "", false
+	case externalClass:
+		return "", "", true
+	}
+	addr, iface = env.sc.method(class, name, 0)
+	return addr, iface, addr != "" || iface != ""
+}
+
+// usedDirs lists the package directories the scope consulted, relative to the repo root; a
+// loaded package outside the repo starts with "..".
+func (sc *typeScope) usedDirs() []string {
+	out := []string{}
+	for dir := range sc.dirs {
+		if rel, err := filepath.Rel(sc.ti.repoRoot, dir); err == nil {
+			out = append(out, filepath.ToSlash(rel))
+		}
+	}
+	return out
+}
+
+var namedTypeRe = regexp.MustCompile(`^\*?\s*(?:([A-Za-z_]\w*)\.)?([A-Za-z_]\w*)\s*(?:\[.*\])?$`)
+
+// namedType splits a type expression naming a defined type, "T", "*pkg.T" or "T[int]", into
+// its package qualifier and name. Slices, maps, channels and function types do not match.
+func namedType(typ string) (pkg, name string, ok bool) {
+	m := namedTypeRe.FindStringSubmatch(strings.TrimSpace(typ))
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// firstResultType returns the type of the first result in a result list: "T", "(T, error)"
+// or "(v T, err error)".
+func firstResultType(ret string) string {
+	ret = strings.TrimSpace(ret)
+	if !strings.HasPrefix(ret, "(") || !strings.HasSuffix(ret, ")") {
+		return ret
+	}
+	ret = ret[1 : len(ret)-1]
+	depth := 0
+	for i, r := range ret {
+		switch r {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		}
+		if r == ',' && depth == 0 {
+			ret = ret[:i]
+			break
+		}
+	}
+	// A named result is "name Type"; "chan T" is the only unnamed type with a space before it.
+	fields := strings.Fields(ret)
+	if len(fields) >= 2 && fields[0] != "chan" && !strings.ContainsAny(fields[0], ".*[]()") {
+		return strings.Join(fields[1:], " ")
+	}
+	return strings.TrimSpace(ret)
+}
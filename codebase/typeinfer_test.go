@@ -0,0 +1,247 @@
+package codebase
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestBuildCodebaseForFilesTypeInference checks that method calls resolve through the inferred
+// types of receivers, parameters, locals and fields rather than by method name alone.
+func TestBuildCodebaseForFilesTypeInference(t *testing.T) {
+	root := t.TempDir()
+	writeTestModule(t, root, map[string]string{
+		"go.mod": "module example.com/t\n\ngo 1.25\n",
+		"work/work.go": `package work
+
+type Tree struct{}
+
+func (t *Tree) Pull() error { return nil }
+
+type Other struct{}
+
+func (o *Other) Pull() error { return nil }
+
+type Repo struct {
+	workTree *Tree
+}
+
+func (r *Repo) Sync() error {
+	return r.workTree.Pull()
+}
+
+func NewRepo() (*Repo, error) { return &Repo{}, nil }
+`,
+		"app/app.go": `package app
+
+import (
+	"path/filepath"
+	"reflect"
+	"sync"
+
+	"example.com/t/work"
+)
+
+type Store interface {
+	Get() string
+	Put()
+}
+
+type Getter interface {
+	Get() string
+}
+
+type B struct{}
+
+func (B) Get() string { return "b" }
+
+func (B) Put() {}
+
+type A struct{}
+
+func (A) Get() string { return "a" }
+
+type Holder struct {
+	*work.Repo
+}
+
+func Literal() {
+	x := &work.Tree{}
+	x.Pull()
+}
+
+func Var() {
+	var x work.Other
+	x.Pull()
+}
+
+func Ctor() error {
+	r, err := work.NewRepo()
+	if err != nil {
+		return err
+	}
+	return r.Sync()
+}
+
+func Iface(s Store) {
+	s.Get()
+}
+
+func Promoted(h Holder) {
+	h.Sync()
+}
+
+func Assert(v any) {
+	v.(*B).Put()
+}
+
+func Shadowed() {
+	work := A{}
+	work.Get()
+}
+
+type Locker interface {
+	Lock()
+}
+
+type FileLock struct{}
+
+func (FileLock) Lock() {}
+
+type Indexer interface {
+	Index(i int) int
+}
+
+type Ints []int
+
+func (s Ints) Index(i int) int { return s[i] }
+
+type Guarded struct {
+	mu sync.Mutex
+}
+
+func (g *Guarded) Locked() {
+	g.mu.Lock()
+}
+
+func Reflect(x any) {
+	v := reflect.ValueOf(x)
+	v.Index(0)
+}
+`,
+	})
+	cb, err := BuildCodebaseForFiles([]string{root}, BuildOptions{RepoRoot: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := map[string][]string{}
+	for _, e := range cb.Calls {
+		c := e.CalleeAddress
+		if e.ViaInterface != nil {
+			c += " via " + *e.ViaInterface
+		}
+		calls[e.CallerAddress] = append(calls[e.CallerAddress], c)
+	}
+
+	tests := []struct {
+		caller string
+		want   []string
+	}{
+		{"work/work.go::Repo.Sync", []string{"work/work.go::Tree.Pull"}},
+		{"app/app.go::Literal", []string{"work/work.go::Tree.Pull"}},
+		{"app/app.go::Var", []string{"work/work.go::Other.Pull"}},
+		{"app/app.go::Ctor", []string{"work/work.go::NewRepo", "work/work.go::Repo.Sync"}},
+		{"app/app.go::Iface", []string{"app/app.go::B.Get via app/app.go::Store"}},
+		{"app/app.go::Promoted", []string{"work/work.go::Repo.Sync"}},
+		{"app/app.go::Assert", []string{"app/app.go::B.Put"}},
+		{"app/app.go::Shadowed", []string{"app/app.go::A.Get"}},
+		{"app/app.go::Guarded.Locked", nil},
+		{"app/app.go::Reflect", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.caller, func(t *testing.T) {
+			got := calls[tt.caller]
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("calls = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBuildCodebaseForFilesTypeInferenceModules checks that method calls resolve through types
+// of a replaced module outside the repo, whether or not its files are indexed.
+func TestBuildCodebaseForFilesTypeInferenceModules(t *testing.T) {
+	ws := t.TempDir()
+	writeTestModule(t, ws, map[string]string{
+		"app/go.mod": "module example.com/app\n\ngo 1.25\n\nrequire example.com/b v0.0.0\n\nreplace example.com/b => ../b\n",
+		"app/main.go": `package main
+
+import "example.com/b"
+
+func main() {
+	c := b.New()
+	c.Do()
+	var v b.T
+	v.Do()
+}
+`,
+		"b/go.mod": "module example.com/b\n\ngo 1.25\n",
+		"b/b.go": `package b
+
+type T struct{}
+
+func New() *T { return &T{} }
+
+func (t *T) Do() {}
+`,
+	})
+	t.Setenv("GOWORK", "")
+	root := filepath.Join(ws, "app")
+
+	tests := []struct {
+		name  string
+		paths []string
+	}{
+		{name: "module indexed", paths: []string{root, filepath.Join(ws, "b")}},
+		{name: "module not indexed", paths: []string{root}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb, err := BuildCodebaseForFiles(tt.paths, BuildOptions{RepoRoot: root})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range cb.Calls {
+				if e.CallerAddress == "main.go::main" {
+					got = append(got, e.CalleeAddress)
+				}
+			}
+			want := []string{"example.com/b/b.go::New", "example.com/b/b.go::T.Do", "example.com/b/b.go::T.Do"}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("main calls %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// TestFirstResultType checks extraction of the first result type from result annotations.
+func TestFirstResultType(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"*Repo", "*Repo"},
+		{"(*Repo, error)", "*Repo"},
+		{"(r *work.Repo, err error)", "*work.Repo"},
+		{"(map[string]int, error)", "map[string]int"},
+		{"(Pair[int, string], error)", "Pair[int, string]"},
+		{"chan T", "chan T"},
+	}
+	for _, tt := range tests {
+		if got := firstResultType(tt.in); got != tt.want {
+			t.Errorf("firstResultType(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
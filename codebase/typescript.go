@@ -0,0 +1,398 @@
+package codebase
+
+import (
+	"path"
+	"strings"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_typescript "github.com/tree-sitter/tree-sitter-typescript/bindings/go"
+)
+
+type typescriptFrontend struct {
+	ts, tsx *sitter.Language
+}
+
+// NewTypeScriptFrontend returns the frontend for .ts and .tsx files. It emits top-level
+// functions (declarations and const arrow functions), ALL_CAPS constants, classes and
+// interfaces, and class methods (including arrow-function fields). Calls resolve to the same
+// module, names imported by relative specifiers (named, default and namespace imports), and
+// this. calls to methods of the enclosing class; new X() resolves to the class.
+func NewTypeScriptFrontend() Frontend {
+	return &typescriptFrontend{
+		ts:  sitter.NewLanguage(tree_sitter_typescript.LanguageTypescript()),
+		tsx: sitter.NewLanguage(tree_sitter_typescript.LanguageTSX()),
+	}
+}
+
+func (*typescriptFrontend) Name() string         { return "typescript" }
+func (*typescriptFrontend) Extensions() []string { return []string{".ts", ".tsx"} }
+
+func (fe *typescriptFrontend) Grammar(ext string) *sitter.Language {
+	if ext == ".tsx" {
+		return fe.tsx
+	}
+	return fe.ts
+}
+
+func (fe *typescriptFrontend) Index(repoRoot string, files []*SourceFile) ([]CodeSymbol, []CallEdge, error) {
+	modules := map[string]struct{}{}
+	for _, sf := range files {
+		modules[sf.Rel] = struct{}{}
+	}
+	var scripts []*scriptFile
+	var syms []CodeSymbol
+	for _, sf := range files {
+		f, err := typescriptFile(repoRoot, sf, modules)
+		if err != nil {
+			return nil, nil, err
+		}
+		scripts = append(scripts, f)
+		syms = append(syms, f.symbols()...)
+	}
+	return syms, resolveScriptCalls(scripts), nil
+}
+
+func typescriptFile(repoRoot string, sf *SourceFile, modules map[string]struct{}) (*scriptFile, error) {
+	f := newScriptFile(sf.Rel)
+	root := sf.Tree.RootNode()
+	var defaultName string
+	for i := uint(0); i < root.NamedChildCount(); i++ {
+		st := root.NamedChild(i)
+		decl, anchor, isDefault := st, st, false
+		if st.Kind() == "export_statement" {
+			isDefault = hasChildKind(st, "default")
+			decl = st.ChildByFieldName("declaration")
+			if decl == nil {
+				// export default name;
+				if v := st.ChildByFieldName("value"); isDefault && v != nil && v.Kind() == "identifier" {
+					defaultName = nodeText(sf.Src, v)
+				}
+				continue
+			}
+		}
+		name, err := typescriptDeclaration(f, repoRoot, sf, decl, anchor)
+		if err != nil {
+			return nil, err
+		}
+		if isDefault && name != "" {
+			defaultName = name
+		}
+		if st.Kind() == "import_statement" {
+			typescriptImports(f, sf, st, modules)
+		}
+	}
+	if addr, ok := f.topLevel[defaultName]; ok {
+		f.topLevel["default"] = addr
+	}
+	return f, nil
+}
+
+// typescriptDeclaration records a top-level declaration and returns the name it declares, or
+// "" when it declares nothing indexed.
+func typescriptDeclaration(f *scriptFile, repoRoot string, sf *SourceFile, decl, anchor *sitter.Node) (string, error) {
+	switch decl.Kind() {
+	case "function_declaration", "generator_function_declaration":
+		name := nodeText(sf.Src, decl.ChildByFieldName("name"))
+		return name, typescriptFunction(f, repoRoot, sf, name, decl, decl, anchor, "")
+	case "class_declaration", "abstract_class_declaration", "interface_declaration":
+		return nodeText(sf.Src, decl.ChildByFieldName("name")), typescriptClass(f, repoRoot, sf, decl, anchor)
+	case "lexical_declaration", "variable_declaration":
+		var last string
+		for i := uint(0); i < decl.NamedChildCount(); i++ {
+			vd := decl.NamedChild(i)
+			if vd.Kind() != "variable_declarator" {
+				continue
+			}
+			name, err := typescriptVariable(f, repoRoot, sf, vd, anchor)
+			if err != nil {
+				return "", err
+			}
+			if name != "" {
+				last = name
+			}
+		}
+		return last, nil
+	}
+	return "", nil
+}
+
+// typescriptVariable records const f = () => ... as a function and NAME = value as a constant.
+func typescriptVariable(f *scriptFile, repoRoot string, sf *SourceFile, vd, anchor *sitter.Node) (string, error) {
+	nameNode, val := vd.ChildByFieldName("name"), vd.ChildByFieldName("value")
+	if nameNode == nil || nameNode.Kind() != "identifier" || val == nil {
+		return "", nil
+	}
+	name := nodeText(sf.Src, nameNode)
+	if isTypescriptFunction(val) {
+		return name, typescriptFunction(f, repoRoot, sf, name, vd, val, anchor, "")
+	}
+	if !constNameGo.MatchString(name) {
+		return "", nil
+	}
+	addr, err := SymbolAddress(repoRoot, sf.Abs, name)
+	if err != nil {
+		return "", err
+	}
+	doc, lead := typescriptComments(anchor, sf.Src)
+	value := truncatedValue(sf.Src, val)
+	f.addSymbol(CodeSymbol{
+		Name: name, Kind: "constant",
+		LineStart: lineStart1(anchor), LineEnd: lineEnd1(anchor),
+		LineCode: lineSnippet(sf.Src, vd), FilePath: sf.Rel, Address: addr,
+		ConstantValue: &value,
+		Docstring:     doc, LeadingComment: lead,
+	})
+	return name, nil
+}
+
+func isTypescriptFunction(n *sitter.Node) bool {
+	switch n.Kind() {
+	case "arrow_function", "function_expression", "function", "generator_function":
+		return true
+	}
+	return false
+}
+
+// typescriptFunction records a function or method. def is the node the snippet is taken from,
+// fn the node holding parameters, type parameters and body, and anchor the node comments sit
+// above.
+func typescriptFunction(f *scriptFile, repoRoot string, sf *SourceFile, name string, def, fn, anchor *sitter.Node, class string) error {
+	if name == "" {
+		return nil
+	}
+	qual, kind := name, "function"
+	var recv *string
+	if class != "" {
+		qual, kind = class+"."+name, "method"
+		rt := class
+		recv = &rt
+	}
+	addr, err := SymbolAddress(repoRoot, sf.Abs, qual)
+	if err != nil {
+		return err
+	}
+	doc, lead := typescriptComments(anchor, sf.Src)
+	f.addSymbol(CodeSymbol{
+		Name: name, Kind: kind,
+		LineStart: lineStart1(anchor), LineEnd: lineEnd1(anchor),
+		LineCode: lineSnippet(sf.Src, def), FilePath: sf.Rel, Address: addr,
+		Docstring: doc, LeadingComment: lead,
+		ReceiverType:   recv,
+		TypeParameters: typescriptTypeParameters(fn, sf.Src),
+	})
+	typescriptCalls(f, sf.Src, fn.ChildByFieldName("body"), addr, class)
+	return nil
+}
+
+func typescriptClass(f *scriptFile, repoRoot string, sf *SourceFile, decl, anchor *sitter.Node) error {
+	name := nodeText(sf.Src, decl.ChildByFieldName("name"))
+	if name == "" {
+		return nil
+	}
+	addr, err := SymbolAddress(repoRoot, sf.Abs, name)
+	if err != nil {
+		return err
+	}
+	doc, lead := typescriptComments(anchor, sf.Src)
+	f.addSymbol(CodeSymbol{
+		Name: name, Kind: "class",
+		LineStart: lineStart1(anchor), LineEnd: lineEnd1(anchor),
+		LineCode: lineSnippet(sf.Src, decl), FilePath: sf.Rel, Address: addr,
+		Docstring: doc, LeadingComment: lead,
+		MethodSet: []string{}, PointerMethodSet: []string{},
+		TypeParameters: typescriptTypeParameters(decl, sf.Src),
+	})
+	body := decl.ChildByFieldName("body")
+	if body == nil || decl.Kind() == "interface_declaration" {
+		return nil
+	}
+	for i := uint(0); i < body.NamedChildCount(); i++ {
+		m := body.NamedChild(i)
+		switch m.Kind() {
+		case "method_definition":
+			err = typescriptFunction(f, repoRoot, sf, nodeText(sf.Src, m.ChildByFieldName("name")), m, m, m, name)
+		case "public_field_definition":
+			// handler = () => { ... } is a method bound to the instance.
+			if v := m.ChildByFieldName("value"); v != nil && isTypescriptFunction(v) {
+				err = typescriptFunction(f, repoRoot, sf, nodeText(sf.Src, m.ChildByFieldName("name")), m, v, m, name)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// typescriptTypeParameters returns the <T extends C> list of a function, class or interface.
+func typescriptTypeParameters(n *sitter.Node, src []byte) []TypeParameterInfo {
+	out := []TypeParameterInfo{}
+	tps := n.ChildByFieldName("type_parameters")
+	if tps == nil {
+		return out
+	}
+	for i := uint(0); i < tps.NamedChildCount(); i++ {
+		tp := tps.NamedChild(i)
+		if tp.Kind() != "type_parameter" {
+			continue
+		}
+		var constraint string
+		if c := tp.ChildByFieldName("constraint"); c != nil {
+			constraint = strings.TrimSpace(strings.TrimPrefix(strings.Join(strings.Fields(nodeText(src, c)), " "), "extends"))
+		}
+		out = append(out, TypeParameterInfo{Name: nodeText(src, tp.ChildByFieldName("name")), Constraint: constraint})
+	}
+	return out
+}
+
+// typescriptComments splits the comments above anchor into the JSDoc block (/** ... */),
+// which becomes the docstring, and the remaining line or block comments.
+func typescriptComments(anchor *sitter.Node, src []byte) (doc, lead *string) {
+	var jsdoc, other []*sitter.Node
+	for _, c := range commentsAbove(anchor) {
+		if strings.HasPrefix(nodeText(src, c), "/**") {
+			jsdoc = append(jsdoc, c)
+		} else {
+			other = append(other, c)
+		}
+	}
+	strip := func(c string) []string {
+		if rest, ok := strings.CutPrefix(c, "//"); ok {
+			return []string{strings.TrimSpace(rest)}
+		}
+		c = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(c, "/*"), "*"), "*/")
+		var lines []string
+		for _, l := range strings.Split(c, "\n") {
+			lines = append(lines, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(l), "*")))
+		}
+		return lines
+	}
+	return joinComments(src, jsdoc, strip), joinComments(src, other, strip)
+}
+
+// typescriptCalls records the calls and constructions in a function body, including nested
+// functions and arrow functions. Nested class declarations and expressions are skipped.
+func typescriptCalls(f *scriptFile, src []byte, body *sitter.Node, caller, class string) {
+	var walk func(n *sitter.Node)
+	walk = func(n *sitter.Node) {
+		if n == nil || n.Kind() == "class_declaration" || n.Kind() == "class" {
+			return
+		}
+		var target *sitter.Node
+		switch n.Kind() {
+		case "call_expression":
+			target = n.ChildByFieldName("function")
+		case "new_expression":
+			target = n.ChildByFieldName("constructor")
+		}
+		if c, ok := typescriptCall(src, target); ok {
+			c.caller, c.class, c.line = caller, class, lineStart1(n)
+			c.self = c.self && class != ""
+			f.calls = append(f.calls, c)
+		}
+		for i := uint(0); i < n.NamedChildCount(); i++ {
+			walk(n.NamedChild(i))
+		}
+	}
+	walk(body)
+}
+
+func typescriptCall(src []byte, fn *sitter.Node) (scriptCall, bool) {
+	if fn == nil {
+		return scriptCall{}, false
+	}
+	switch fn.Kind() {
+	case "identifier":
+		return scriptCall{name: nodeText(src, fn)}, true
+	case "member_expression":
+		obj, prop := fn.ChildByFieldName("object"), fn.ChildByFieldName("property")
+		if obj == nil || prop == nil {
+			return scriptCall{}, false
+		}
+		switch obj.Kind() {
+		case "this":
+			return scriptCall{object: "this", self: true, name: nodeText(src, prop)}, true
+		case "identifier":
+			return scriptCall{object: nodeText(src, obj), name: nodeText(src, prop)}, true
+		}
+	}
+	return scriptCall{}, false
+}
+
+// typescriptImports binds the local names of an import declaration with a relative specifier
+// to the module file it names. Default imports bind to the module's "default" export.
+func typescriptImports(f *scriptFile, sf *SourceFile, st *sitter.Node, modules map[string]struct{}) {
+	spec := st.ChildByFieldName("source")
+	if spec == nil {
+		return
+	}
+	file, ok := typescriptModuleFile(sf.Rel, strings.Trim(nodeText(sf.Src, spec), "\"'`"), modules)
+	if !ok {
+		return
+	}
+	for i := uint(0); i < st.NamedChildCount(); i++ {
+		clause := st.NamedChild(i)
+		if clause.Kind() != "import_clause" {
+			continue
+		}
+		for j := uint(0); j < clause.NamedChildCount(); j++ {
+			n := clause.NamedChild(j)
+			switch n.Kind() {
+			case "identifier":
+				f.imports[nodeText(sf.Src, n)] = scriptImport{file: file, name: "default"}
+			case "namespace_import":
+				if id := firstChildOfKind(n, "identifier"); id != nil {
+					f.imports[nodeText(sf.Src, id)] = scriptImport{file: file}
+				}
+			case "named_imports":
+				for k := uint(0); k < n.NamedChildCount(); k++ {
+					is := n.NamedChild(k)
+					if is.Kind() != "import_specifier" {
+						continue
+					}
+					name := nodeText(sf.Src, is.ChildByFieldName("name"))
+					local := name
+					if a := is.ChildByFieldName("alias"); a != nil {
+						local = nodeText(sf.Src, a)
+					}
+					f.imports[local] = scriptImport{file: file, name: name}
+				}
+			}
+		}
+	}
+}
+
+// typescriptModuleFile finds the indexed file for a relative import specifier, trying the
+// extensions and index files the TypeScript resolver would. Bare specifiers name packages
+// outside the repo and are not resolved.
+func typescriptModuleFile(fromRel, spec string, modules map[string]struct{}) (string, bool) {
+	if !strings.HasPrefix(spec, "./") && !strings.HasPrefix(spec, "../") {
+		return "", false
+	}
+	p := path.Join(path.Dir(fromRel), spec)
+	for _, ext := range []string{".js", ".jsx", ".ts", ".tsx"} {
+		if trimmed, ok := strings.CutSuffix(p, ext); ok {
+			p = trimmed
+			break
+		}
+	}
+	for _, cand := range []string{p + ".ts", p + ".tsx", p + "/index.ts", p + "/index.tsx"} {
+		if _, ok := modules[cand]; ok {
+			return cand, true
+		}
+	}
+	return "", false
+}
+
+func hasChildKind(n *sitter.Node, kind string) bool {
+	return firstChildOfKind(n, kind) != nil
+}
+
+func firstChildOfKind(n *sitter.Node, kind string) *sitter.Node {
+	for i := uint(0); i < n.ChildCount(); i++ {
+		if ch := n.Child(i); ch.Kind() == kind {
+			return ch
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package codebase
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestTypeScriptFrontend checks TypeScript and TSX symbols, JSDoc, type parameters, and calls
+// through named, default and namespace imports, this. and new.
+func TestTypeScriptFrontend(t *testing.T) {
+	root := t.TempDir()
+	writeTestModule(t, root, map[string]string{
+		"web/lib/math.ts": `/** Largest batch. */
+export const MAX_BATCH = 50;
+
+export function add(a: number, b: number): number {
+  return a + b;
+}
+
+export default function scale(x: number): number {
+  return add(x, x);
+}
+`,
+		"web/lib/index.ts": "export const twice = (x: number) => x * 2;\n",
+		"web/store.ts": `import scale, { add as plus } from "./lib/math.js";
+import * as lib from "./lib";
+import { useState } from "react";
+
+export interface Repo<T> {
+  get(id: string): T;
+}
+
+/**
+ * Store keeps items.
+ */
+export class Store<T extends object> {
+  load(): void {
+    this.reset();
+    plus(1, 2);
+  }
+  reset = () => {
+    lib.twice(scale(3));
+  };
+}
+
+export function open(): Store<object> {
+  useState();
+  return new Store();
+}
+`,
+		"web/view.tsx": `import { open } from "./store";
+
+export const View = () => {
+  const s = open();
+  return <div>{s.load()}</div>;
+};
+`,
+	})
+
+	cb, err := BuildCodebaseForFiles([]string{root}, BuildOptions{RepoRoot: root, Frontends: DefaultFrontends()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byAddr := map[string]CodeSymbol{}
+	for _, s := range cb.Symbols {
+		byAddr[s.Address] = s
+	}
+
+	symTests := []struct {
+		address, kind string
+		typeParams    []TypeParameterInfo
+	}{
+		{address: "web/lib/math.ts::MAX_BATCH", kind: "constant"},
+		{address: "web/lib/math.ts::add", kind: "function"},
+		{address: "web/lib/math.ts::scale", kind: "function"},
+		{address: "web/lib/index.ts::twice", kind: "function"},
+		{address: "web/store.ts::Repo", kind: "class", typeParams: []TypeParameterInfo{{Name: "T"}}},
+		{address: "web/store.ts::Store", kind: "class", typeParams: []TypeParameterInfo{{Name: "T", Constraint: "object"}}},
+		{address: "web/store.ts::Store.load", kind: "method"},
+		{address: "web/store.ts::Store.reset", kind: "method"},
+		{address: "web/store.ts::open", kind: "function"},
+		{address: "web/view.tsx::View", kind: "function"},
+	}
+	for _, tt := range symTests {
+		t.Run(tt.address, func(t *testing.T) {
+			s, ok := byAddr[tt.address]
+			if !ok {
+				t.Fatalf("missing symbol %q", tt.address)
+			}
+			if s.Kind != tt.kind {
+				t.Errorf("Kind = %q; want %q", s.Kind, tt.kind)
+			}
+			if len(s.TypeParameters)+len(tt.typeParams) > 0 && !slices.Equal(s.TypeParameters, tt.typeParams) {
+				t.Errorf("TypeParameters = %v; want %v", s.TypeParameters, tt.typeParams)
+			}
+		})
+	}
+	if s := byAddr["web/lib/math.ts::MAX_BATCH"]; s.Docstring == nil || *s.Docstring != "Largest batch." {
+		t.Errorf("MAX_BATCH docstring = %v", s.Docstring)
+	}
+	if s := byAddr["web/store.ts::Store"]; s.Docstring == nil || *s.Docstring != "Store keeps items." {
+		t.Errorf("Store docstring = %v", s.Docstring)
+	}
+	if got := byAddr["web/store.ts::Store"].MethodSet; !slices.Equal(got, []string{"web/store.ts::Store.load", "web/store.ts::Store.reset"}) {
+		t.Errorf("Store method set = %v", got)
+	}
+
+	edgeTests := []struct {
+		caller, callee string
+	}{
+		{caller: "web/lib/math.ts::scale", callee: "web/lib/math.ts::add"},
+		{caller: "web/store.ts::Store.load", callee: "web/store.ts::Store.reset"},
+		{caller: "web/store.ts::Store.load", callee: "web/lib/math.ts::add"},
+		{caller: "web/store.ts::Store.reset", callee: "web/lib/math.ts::scale"},
+		{caller: "web/store.ts::Store.reset", callee: "web/lib/index.ts::twice"},
+		{caller: "web/store.ts::open", callee: "web/store.ts::Store"},
+		{caller: "web/view.tsx::View", callee: "web/store.ts::open"},
+	}
+	for _, tt := range edgeTests {
+		t.Run(tt.caller+"->"+tt.callee, func(t *testing.T) {
+			if !frontendEdge(cb, tt.caller, tt.callee) {
+				t.Errorf("missing edge; calls=%+v", cb.Calls)
+			}
+		})
+	}
+	if n := len(byAddr["web/store.ts::open"].CallsTo); n != 1 {
+		t.Errorf("open CallsTo = %v; want only Store (react is outside the repo)", byAddr["web/store.ts::open"].CallsTo)
+	}
+}
@@ -0,0 +1,414 @@
+package codebase
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Usage kinds recorded in NameUsageSite.Kind.
+const (
+	UsageRead    = "read"
+	UsageWrite   = "write"
+	UsageTypeRef = "type-ref"
+	UsageCall    = "call"
+	UsageImport  = "import"
+)
+
+// BuildUsageReport parses the same inputs as BuildCodebaseForFiles and returns the symbol listing
+// plus every identifier reference (reads, writes, type references, calls and imports).
+// Declarations themselves (function, type, parameter, var and const names) are not usages.
+// ResolvedAddress is set when the name resolves to a package-level symbol of the indexed files
+// or of a package under the current module; locals, fields and methods stay unresolved.
+func BuildUsageReport(paths []string, opt BuildOptions) (*RepoUsageReport, error) {
+	if opt.Revision != "" {
+		dir, revPaths, revOpt, err := exportRevision(paths, opt)
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+		paths, opt = revPaths, revOpt
+	}
+	in, err := loadBuildInput(paths, opt)
+	if err != nil {
+		return nil, err
+	}
+	if in == nil {
+		return &RepoUsageReport{}, nil
+	}
+	defer in.close()
+
+	syms, err := collectSymbols(in)
+	if err != nil {
+		return nil, err
+	}
+	listing := symbolListing(syms)
+	report := &RepoUsageReport{
+		Functions: listing.Functions,
+		Classes:   listing.Classes,
+		Constants: listing.Constants,
+		Usages:    []NameUsageSite{},
+	}
+
+	ur := &usageResolver{
+		in:     in,
+		pkgIdx: packageIndexOfKinds(syms, in.repoRoot, "function", "class", "constant"),
+		kinds:  map[string]string{},
+	}
+	for _, s := range syms {
+		ur.kinds[s.Address] = s.Kind
+	}
+	perFile := make([][]NameUsageSite, len(in.files))
+	err = in.eachFile(func(i int, p *sitter.Parser) error {
+		pf, err := in.parsed(in.files[i], p)
+		if err != nil {
+			return err
+		}
+		perFile[i] = ur.fileUsages(pf, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, sites := range perFile {
+		report.Usages = append(report.Usages, sites...)
+	}
+	return report, nil
+}
+
+// symbolListing flattens collected symbols into the RepoSymbolListing shape.
+func symbolListing(syms []CodeSymbol) RepoSymbolListing {
+	out := RepoSymbolListing{
+		Functions: []ListedFunction{},
+		Classes:   []ListedClass{},
+		Constants: []ListedConstant{},
+	}
+	for _, s := range syms {
+		_, qual, _ := strings.Cut(s.Address, "::")
+		switch s.Kind {
+		case "function", "method":
+			lf := ListedFunction{
+				Name: s.Name, QualifiedName: qual, FilePath: s.FilePath, Line: s.LineStart,
+				Parameters: s.Parameters, ReturnAnnotation: s.ReturnAnnotation,
+				Docstring: s.Docstring, LeadingComment: s.LeadingComment,
+				TypeParameters: s.TypeParameters,
+			}
+			out.Functions = append(out.Functions, lf)
+		case "class":
+			out.Classes = append(out.Classes, ListedClass{
+				Name: s.Name, QualifiedName: qual, FilePath: s.FilePath, Line: s.LineStart,
+				Docstring: s.Docstring, LeadingComment: s.LeadingComment,
+				TypeParameters: s.TypeParameters,
+			})
+		case "constant":
+			val := ""
+			if s.ConstantValue != nil {
+				val = *s.ConstantValue
+			}
+			out.Constants = append(out.Constants, ListedConstant{
+				Name: s.Name, QualifiedName: qual, FilePath: s.FilePath, Line: s.LineStart, Value: val,
+			})
+		}
+	}
+	return out
+}
+
+type usageResolver struct {
+	in     *buildInput
+	pkgIdx map[string]map[string][][2]string
+	kinds  map[string]string // address -> symbol kind
+}
+
+// fileUsages walks one parsed file and returns its usage sites in source order.
+func (ur *usageResolver) fileUsages(pf *parsedFile, p *sitter.Parser) []NameUsageSite {
+	root := pf.tr.RootNode()
+	if root == nil {
+		return nil
+	}
+	imports := collectImports(root, pf.src, ur.in.modules)
+	aliases := map[string]importRow{}
+	for _, row := range imports {
+		aliases[row.local] = row
+	}
+
+	var out []NameUsageSite
+	add := func(n *sitter.Node, name, kind string, addr string) {
+		site := NameUsageSite{
+			FilePath: pf.rel,
+			Line:     lineStart1(n),
+			Column:   int(n.StartPosition().Column) + 1,
+			Name:     name,
+			Kind:     kind,
+		}
+		if addr != "" {
+			site.ResolvedAddress = &addr
+		}
+		out = append(out, site)
+	}
+
+	var walk func(n *sitter.Node, locals map[string]struct{})
+	walk = func(n *sitter.Node, locals map[string]struct{}) {
+		if n == nil {
+			return
+		}
+		switch n.Kind() {
+		case "package_clause":
+			return
+		case "import_spec":
+			if ip := n.ChildByFieldName("path"); ip != nil {
+				add(ip, importPathString(ip, pf.src), UsageImport, "")
+			}
+			return
+		case "function_declaration", "method_declaration":
+			if locals == nil {
+				locals = localNames(n, pf.src)
+			}
+		case "identifier":
+			if isDeclarationName(n) {
+				return
+			}
+			name := nodeText(pf.src, n)
+			_, isLocal := locals[name]
+			if _, isPkg := aliases[name]; isPkg && !isLocal && isSelectorOperand(n) {
+				// Package qualifier: the selected field carries the usage.
+				return
+			}
+			kind := usageKindOf(n)
+			addr := ""
+			if !isLocal {
+				addr = ur.resolve(pf.abs, name, "", aliases, p)
+			}
+			if addr == "" && isKeyedFieldName(n) {
+				kind = UsageWrite
+			}
+			add(n, name, kind, addr)
+			return
+		case "type_identifier":
+			if isDeclarationName(n) {
+				return
+			}
+			name := nodeText(pf.src, n)
+			addr := ""
+			if _, isLocal := locals[name]; !isLocal {
+				pkgAlias := ""
+				if qt := n.Parent(); qt != nil && qt.Kind() == "qualified_type" {
+					if pk := qt.ChildByFieldName("package"); pk != nil {
+						pkgAlias = nodeText(pf.src, pk)
+					}
+				}
+				addr = ur.resolve(pf.abs, name, pkgAlias, aliases, p)
+			}
+			kind := UsageTypeRef
+			if ur.kinds[addr] == "function" && isGenericConversion(n) {
+				// NewThing[T](x) parses as a conversion to an instantiated type.
+				kind = UsageCall
+			}
+			add(n, name, kind, addr)
+			return
+		case "field_identifier":
+			sel := n.Parent()
+			if sel == nil || sel.Kind() != "selector_expression" {
+				// Method, struct field and interface method names are declarations.
+				return
+			}
+			name := nodeText(pf.src, n)
+			addr := ""
+			if op := sel.ChildByFieldName("operand"); op != nil && op.Kind() == "identifier" {
+				alias := nodeText(pf.src, op)
+				if _, isLocal := locals[alias]; !isLocal {
+					if _, isPkg := aliases[alias]; isPkg {
+						addr = ur.resolve(pf.abs, name, alias, aliases, p)
+					}
+				}
+			}
+			add(n, name, usageKindOf(sel), addr)
+			return
+		}
+		for i := uint(0); i < n.ChildCount(); i++ {
+			walk(n.Child(i), locals)
+		}
+	}
+	walk(root, nil)
+	return out
+}
+
+// resolve maps name (optionally qualified by a package alias) to a symbol address, or "".
+func (ur *usageResolver) resolve(importerAbs, name, pkgAlias string, aliases map[string]importRow, p *sitter.Parser) string {
+	if pkgAlias == "" {
+		if _, ok := builtinsGo[name]; ok {
+			return ""
+		}
+		if addr := packageTopLevelAddress(ur.pkgIdx[filepath.Dir(importerAbs)], name); addr != "" {
+			return addr
+		}
+		if row, ok := aliases["."]; ok {
+			return ur.resolveInImport(row, name, p)
+		}
+		return ""
+	}
+	row, ok := aliases[pkgAlias]
+	if !ok {
+		return ""
+	}
+	return ur.resolveInImport(row, name, p)
+}
+
+func (ur *usageResolver) resolveInImport(row importRow, name string, p *sitter.Parser) string {
+	dir, ok := ur.in.modules.importDir(row.path)
+	if !ok {
+		return ""
+	}
+	if addr := packageTopLevelAddress(ur.pkgIdx[dir], name); addr != "" {
+		return addr
+	}
+	abs, qual, ok := findDefInPackageDir(dir, name, ur.in.defs, p)
+	if !ok {
+		return ""
+	}
+	rel, err := ur.in.modules.relPath(abs)
+	if err != nil {
+		return ""
+	}
+	return rel + "::" + qual
+}
+
+// usageKindOf classifies the expression ref (an identifier or selector) by its syntactic position.
+func usageKindOf(ref *sitter.Node) string {
+	outer := ref
+	p := outer.Parent()
+	for p != nil && p.Kind() == "parenthesized_expression" {
+		outer = p
+		p = p.Parent()
+	}
+	if p == nil {
+		return UsageRead
+	}
+	switch p.Kind() {
+	case "call_expression":
+		if fn := p.ChildByFieldName("function"); fn != nil && fn.Id() == outer.Id() {
+			return UsageCall
+		}
+	case "inc_statement", "dec_statement":
+		return UsageWrite
+	case "expression_list":
+		gp := p.Parent()
+		if gp == nil {
+			break
+		}
+		switch gp.Kind() {
+		case "assignment_statement", "short_var_declaration", "range_clause", "receive_statement":
+			if left := gp.ChildByFieldName("left"); left != nil && left.Id() == p.Id() {
+				return UsageWrite
+			}
+		}
+	}
+	return UsageRead
+}
+
+// isDeclarationName reports whether n is the name being declared by its parent.
+func isDeclarationName(n *sitter.Node) bool {
+	p := n.Parent()
+	if p == nil {
+		return false
+	}
+	switch p.Kind() {
+	case "function_declaration", "type_spec", "type_alias", "parameter_declaration",
+		"variadic_parameter_declaration", "type_parameter_declaration", "const_spec", "var_spec":
+		for i := uint(0); i < p.ChildCount(); i++ {
+			if p.FieldNameForChild(uint32(i)) == "name" {
+				if ch := p.Child(i); ch != nil && ch.Id() == n.Id() {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// isGenericConversion reports whether the type name n heads the type of a conversion expression,
+// e.g. Foo in Foo[int](x).
+func isGenericConversion(n *sitter.Node) bool {
+	g := n.Parent()
+	if g != nil && g.Kind() == "qualified_type" {
+		g = g.Parent()
+	}
+	if g == nil || g.Kind() != "generic_type" {
+		return false
+	}
+	conv := g.Parent()
+	if conv == nil || conv.Kind() != "type_conversion_expression" {
+		return false
+	}
+	typ := conv.ChildByFieldName("type")
+	return typ != nil && typ.Id() == g.Id()
+}
+
+func isSelectorOperand(n *sitter.Node) bool {
+	p := n.Parent()
+	if p == nil || p.Kind() != "selector_expression" {
+		return false
+	}
+	op := p.ChildByFieldName("operand")
+	return op != nil && op.Id() == n.Id()
+}
+
+// isKeyedFieldName reports whether n is the bare key of a keyed composite literal element,
+// which for struct literals names the field being initialised.
+func isKeyedFieldName(n *sitter.Node) bool {
+	le := n.Parent()
+	if le == nil || le.Kind() != "literal_element" || le.NamedChildCount() != 1 {
+		return false
+	}
+	ke := le.Parent()
+	if ke == nil || ke.Kind() != "keyed_element" {
+		return false
+	}
+	key := ke.ChildByFieldName("key")
+	return key != nil && key.Id() == le.Id()
+}
+
+// localNames collects every name declared inside a function or method: receiver, type parameters,
+// parameters, results and body-level declarations. It is flow-insensitive on purpose: a local
+// anywhere in the function shadows the package-level name everywhere in it.
+func localNames(fn *sitter.Node, src []byte) map[string]struct{} {
+	out := map[string]struct{}{}
+	addList := func(list *sitter.Node) {
+		if list == nil {
+			return
+		}
+		for i := uint(0); i < list.NamedChildCount(); i++ {
+			if ch := list.NamedChild(i); ch != nil && ch.Kind() == "identifier" {
+				out[nodeText(src, ch)] = struct{}{}
+			}
+		}
+	}
+	var walk func(*sitter.Node)
+	walk = func(n *sitter.Node) {
+		if n == nil {
+			return
+		}
+		switch n.Kind() {
+		case "parameter_declaration", "variadic_parameter_declaration", "type_parameter_declaration",
+			"const_spec", "var_spec":
+			for i := uint(0); i < n.ChildCount(); i++ {
+				if n.FieldNameForChild(uint32(i)) != "name" {
+					continue
+				}
+				if ch := n.Child(i); ch != nil && ch.Kind() == "identifier" {
+					out[nodeText(src, ch)] = struct{}{}
+				}
+			}
+		case "short_var_declaration", "range_clause":
+			addList(n.ChildByFieldName("left"))
+		case "type_switch_statement":
+			addList(n.ChildByFieldName("alias"))
+		}
+		for i := uint(0); i < n.ChildCount(); i++ {
+			walk(n.Child(i))
+		}
+	}
+	walk(fn)
+	return out
+}
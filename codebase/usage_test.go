@@ -0,0 +1,108 @@
+package codebase
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestBuildUsageReport checks usage kinds and resolution for identifiers in testdata/pack.
+func TestBuildUsageReport(t *testing.T) {
+	repoRoot := findRepoRootForTest(t)
+	packDir := filepath.Join(repoRoot, "codebase", "testdata", "pack")
+
+	report, err := BuildUsageReport([]string{packDir}, BuildOptions{RepoRoot: repoRoot})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		ident    string
+		kind     string
+		wantAddr string // "" means the site must stay unresolved
+	}{
+		{
+			name:     "call to same-file function",
+			file:     "codebase/testdata/pack/a.go",
+			ident:    "helper",
+			kind:     UsageCall,
+			wantAddr: "codebase/testdata/pack/a.go::helper",
+		},
+		{
+			name:     "call to function in sibling file",
+			file:     "codebase/testdata/pack/c.go",
+			ident:    "helper",
+			kind:     UsageCall,
+			wantAddr: "codebase/testdata/pack/a.go::helper",
+		},
+		{
+			name:     "constant read",
+			file:     "codebase/testdata/pack/c.go",
+			ident:    "LIMIT",
+			kind:     UsageRead,
+			wantAddr: "codebase/testdata/pack/c.go::LIMIT",
+		},
+		{
+			name:     "type reference in signature",
+			file:     "codebase/testdata/pack/c.go",
+			ident:    "Counter",
+			kind:     UsageTypeRef,
+			wantAddr: "codebase/testdata/pack/c.go::Counter",
+		},
+		{
+			name:  "field write through increment",
+			file:  "codebase/testdata/pack/c.go",
+			ident: "n",
+			kind:  UsageWrite,
+		},
+		{
+			name:  "parameter read stays unresolved",
+			file:  "codebase/testdata/pack/c.go",
+			ident: "c",
+			kind:  UsageRead,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var found bool
+			for _, u := range report.Usages {
+				if u.FilePath != tt.file || u.Name != tt.ident || u.Kind != tt.kind {
+					continue
+				}
+				got := ""
+				if u.ResolvedAddress != nil {
+					got = *u.ResolvedAddress
+				}
+				if got == tt.wantAddr {
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Errorf("no %s usage of %q in %s resolving to %q; usages=%+v", tt.kind, tt.ident, tt.file, tt.wantAddr, report.Usages)
+			}
+		})
+	}
+
+	t.Run("declarations are not usages", func(t *testing.T) {
+		for _, u := range report.Usages {
+			if u.Name == "Bump" {
+				t.Errorf("declaration of Bump reported as usage: %+v", u)
+			}
+		}
+	})
+
+	t.Run("listing is populated", func(t *testing.T) {
+		if len(report.Functions) == 0 || len(report.Classes) == 0 || len(report.Constants) == 0 {
+			t.Fatalf("listing incomplete: functions=%d classes=%d constants=%d",
+				len(report.Functions), len(report.Classes), len(report.Constants))
+		}
+		for _, f := range report.Functions {
+			if f.Name == "Bump" && (len(f.Parameters) != 1 || f.ReturnAnnotation == nil) {
+				t.Errorf("Bump listing = %+v; want one parameter and a return annotation", f)
+			}
+		}
+	})
+}